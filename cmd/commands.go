@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+func runCommand(name string, args []string) error {
+	switch name {
+	case "create-admin":
+		return createAdmin(args)
+	}
+	return fmt.Errorf("unknown command %q (available: create-admin)", name)
+}
+
+// createAdmin bootstraps the first admin account, since registration now
+// needs an invite from an admin. The password is read from ADMIN_PASSWORD
+// or, if that is unset, from the first line of stdin.
+func createAdmin(args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "email address to sign in with (required)")
+	name := fs.String("name", "Administrator", "display name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("create-admin: -email is required")
+	}
+
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		fmt.Print("Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("create-admin: reading password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	user, err := services.CreateUser(services.NewUser{
+		Name:     *name,
+		Email:    *email,
+		Password: password,
+		Role:     models.RoleAdmin,
+	})
+	if err != nil {
+		return fmt.Errorf("create-admin: %w", err)
+	}
+	fmt.Printf("✅ Created admin %s (id %d)\n", user.Email, user.ID)
+	return nil
+}
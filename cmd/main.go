@@ -2,6 +2,7 @@ package main
 
 import (
     "fmt"
+    "os"
     "time"
 
     "github.com/gin-contrib/cors"
@@ -9,7 +10,9 @@ import (
 
     "github.com/Sathwik-145/hospital-portal/config"
     "github.com/Sathwik-145/hospital-portal/models"
+    "github.com/Sathwik-145/hospital-portal/repository"
     "github.com/Sathwik-145/hospital-portal/routes"
+    "github.com/Sathwik-145/hospital-portal/services"
 )
 
 func main() {
@@ -18,11 +21,90 @@ func main() {
 
     // Run database migration - Add this after connecting to database
     fmt.Println("🔄 Running database migrations...")
-    if err := config.DB.AutoMigrate(&models.Patient{}, &models.MedicalHistory{}, &models.User{}); err != nil {
+    // Checked before AutoMigrate adds the column
+    introducingPrimaryDoctors := !config.DB.Migrator().HasColumn(&models.Patient{}, "primary_doctor_id")
+    if err := config.DB.AutoMigrate(&models.Patient{}, &models.MedicalHistory{}, &models.User{}, &models.Appointment{}, &models.DoctorAvailability{}, &models.DoctorBreak{}, &models.DoctorLeave{}, &models.AuditEvent{}, &models.Household{}, &models.HouseholdMember{}, &models.Medication{}, &models.Prescription{}, &models.PatientAllergy{}, &models.InteractionRule{}, &models.Vitals{}, &models.VitalReferenceRange{}, &models.RolePermission{}, &models.AppliedPermissionChange{}, &models.UserInvite{}, &models.Session{}, &models.RefreshToken{}, &models.UserMFA{}, &models.RecoveryCode{}, &models.MFAChallenge{}, &models.RoleMFAPolicy{}, &models.PasswordHistory{}, &models.PasswordResetToken{}, &models.LoginAttempt{}, &models.CareTeamMember{}, &models.BreakGlassAccess{}, &models.RolePatientScope{}); err != nil {
         fmt.Println("❌ Migration failed:", err)
         return
     }
+    if err := repository.ProtectAuditTable(); err != nil {
+        fmt.Println("❌ Failed to protect audit table:", err)
+        return
+    }
+    if err := repository.EnsureSearchIndexes(); err != nil {
+        fmt.Println("❌ Failed to create search indexes:", err)
+        return
+    }
+    if err := repository.MigratePhoneHouseholds(); err != nil {
+        fmt.Println("❌ Failed to migrate phone-number families to households:", err)
+        return
+    }
+    if err := repository.MigrateDateOfBirth(); err != nil {
+        fmt.Println("❌ Failed to estimate dates of birth from ages:", err)
+        return
+    }
+    if err := repository.MigrateClinicalSnapshots(); err != nil {
+        fmt.Println("❌ Failed to record current diagnoses as encounters:", err)
+        return
+    }
+    if introducingPrimaryDoctors {
+        if err := repository.AssignPrimaryDoctorsFromVisits(); err != nil {
+            fmt.Println("❌ Failed to assign primary doctors from past visits:", err)
+            return
+        }
+    }
+    if err := services.SeedInteractionRules(); err != nil {
+        fmt.Println("❌ Failed to load bundled interaction rules:", err)
+        return
+    }
+    if err := services.SeedVitalRanges(); err != nil {
+        fmt.Println("❌ Failed to load default vital reference ranges:", err)
+        return
+    }
+    if err := services.SeedRolePermissions(); err != nil {
+        fmt.Println("❌ Failed to install default role permissions:", err)
+        return
+    }
+    if err := services.SeedRoleMFAPolicies(); err != nil {
+        fmt.Println("❌ Failed to install default MFA requirements:", err)
+        return
+    }
+    if err := services.SeedRolePatientScopes(); err != nil {
+        fmt.Println("❌ Failed to install default patient access scopes:", err)
+        return
+    }
+    if err := services.PruneSessions(); err != nil {
+        fmt.Println("❌ Failed to prune ended sessions:", err)
+        return
+    }
+    if err := services.PruneMFAChallenges(); err != nil {
+        fmt.Println("❌ Failed to prune expired MFA challenges:", err)
+        return
+    }
+    if err := services.PrunePasswordResetTokens(); err != nil {
+        fmt.Println("❌ Failed to prune expired password reset links:", err)
+        return
+    }
     fmt.Println("✅ Database migration completed")
+
+    if err := services.LoadTokenKeys(); err != nil {
+        fmt.Println("❌ Failed to load JWT signing keys:", err)
+        return
+    }
+    if err := services.ConfigureLoginAttemptStore(); err != nil {
+        fmt.Println("❌ Failed to set up login throttling:", err)
+        return
+    }
+    services.ConfigureNotifier()
+
+    // Maintenance commands, e.g. `go run ./cmd create-admin -email ...`
+    if len(os.Args) > 1 {
+        if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+            fmt.Println("❌", err)
+            os.Exit(1)
+        }
+        return
+    }
 	
 
     router := gin.Default()
@@ -33,9 +115,9 @@ func main() {
     // Enable CORS (Frontend @ 3000)
     router.Use(cors.New(cors.Config{
         AllowOrigins:     []string{"http://localhost:3000"},
-        AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-        AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-        ExposeHeaders:    []string{"Content-Length"},
+        AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+        AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match"},
+        ExposeHeaders:    []string{"Content-Length", "ETag"},
         AllowCredentials: true,
         MaxAge:           12 * time.Hour,
     }))
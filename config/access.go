@@ -0,0 +1,9 @@
+package config
+
+import "time"
+
+// BreakGlassTTL is how long emergency access to an unassigned patient lasts,
+// set via BREAK_GLASS_MINUTES
+func BreakGlassTTL() time.Duration {
+	return time.Duration(envInt("BREAK_GLASS_MINUTES", 60)) * time.Minute
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// Clinic is the branding printed on generated documents
+type Clinic struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Phone   string `json:"phone"`
+	Email   string `json:"email"`
+}
+
+// ClinicBranding reads CLINIC_NAME, CLINIC_ADDRESS, CLINIC_PHONE and CLINIC_EMAIL
+func ClinicBranding() Clinic {
+	name := os.Getenv("CLINIC_NAME")
+	if name == "" {
+		name = "Hospital Portal"
+	}
+	return Clinic{
+		Name:    name,
+		Address: os.Getenv("CLINIC_ADDRESS"),
+		Phone:   os.Getenv("CLINIC_PHONE"),
+		Email:   os.Getenv("CLINIC_EMAIL"),
+	}
+}
+
+// PublicBaseURL is where the API is reachable from outside, used for links
+// printed on documents. Set via PUBLIC_BASE_URL.
+func PublicBaseURL() string {
+	if v := strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/"); v != "" {
+		return v
+	}
+	return "http://localhost:8080"
+}
+
+// AppBaseURL is where staff open the web app, used for links in emails.
+// Set via APP_BASE_URL.
+func AppBaseURL() string {
+	if v := strings.TrimRight(os.Getenv("APP_BASE_URL"), "/"); v != "" {
+		return v
+	}
+	return "http://localhost:3000"
+}
+
+// DocumentSigningKey is the HMAC key for document verification codes, set via
+// DOCUMENT_SIGNING_KEY. Empty means documents can't be issued.
+func DocumentSigningKey() []byte {
+	return []byte(os.Getenv("DOCUMENT_SIGNING_KEY"))
+}
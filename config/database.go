@@ -20,7 +20,8 @@ func ConnectDatabase() {
 
 	dsn := os.Getenv("DB_URL")
 	// ✅ Define the db variable here
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError turns constraint violations into gorm.ErrDuplicatedKey etc.
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// JWTKeysFile points to a JSON key set with asymmetric keys and rotation,
+// set via JWT_KEYS_FILE. When empty, tokens are signed with JWT_SECRET.
+func JWTKeysFile() string {
+	return os.Getenv("JWT_KEYS_FILE")
+}
+
+// JWTSecret is the HS256 key used when no key set is configured
+func JWTSecret() []byte {
+	return []byte(os.Getenv("JWT_SECRET"))
+}
+
+// JWTKeyID is the kid for the JWT_SECRET key, set via JWT_KEY_ID
+func JWTKeyID() string {
+	if v := strings.TrimSpace(os.Getenv("JWT_KEY_ID")); v != "" {
+		return v
+	}
+	return "default"
+}
+
+// JWTIssuer goes in the iss claim and is checked on verification, set via
+// JWT_ISSUER. Defaults to PUBLIC_BASE_URL.
+func JWTIssuer() string {
+	if v := strings.TrimSpace(os.Getenv("JWT_ISSUER")); v != "" {
+		return v
+	}
+	return PublicBaseURL()
+}
@@ -0,0 +1,10 @@
+package config
+
+import "os"
+
+// DefaultCountryCode is prefixed to local phone numbers entered without one,
+// set via DEFAULT_COUNTRY_CODE (e.g. "+91"). Empty means numbers must already
+// be in E.164 form.
+func DefaultCountryCode() string {
+	return os.Getenv("DEFAULT_COUNTRY_CODE")
+}
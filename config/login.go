@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoginThrottle limits password guessing; see services/login_throttle.go
+type LoginThrottle struct {
+	// Failures before an account is locked, LOGIN_MAX_FAILURES
+	MaxAccountFailures int
+	// Failures from one IP, across all accounts, before it is locked,
+	// LOGIN_MAX_FAILURES_PER_IP
+	MaxIPFailures int
+	// Failures older than this are forgotten, LOGIN_FAILURE_WINDOW_MINUTES
+	Window time.Duration
+	// How long a lock lasts unless an admin lifts it, LOGIN_LOCKOUT_MINUTES
+	Lockout time.Duration
+}
+
+func LoginThrottleSettings() LoginThrottle {
+	return LoginThrottle{
+		MaxAccountFailures: envInt("LOGIN_MAX_FAILURES", 5),
+		MaxIPFailures:      envInt("LOGIN_MAX_FAILURES_PER_IP", 20),
+		Window:             time.Duration(envInt("LOGIN_FAILURE_WINDOW_MINUTES", 15)) * time.Minute,
+		Lockout:            time.Duration(envInt("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+	}
+}
+
+// LoginAttemptStore is where failed sign-ins are counted, set via
+// LOGIN_ATTEMPT_STORE: "memory" (default) for a single instance, or
+// "database" when several instances must share the counts
+func LoginAttemptStore() string {
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv("LOGIN_ATTEMPT_STORE"))); v != "" {
+		return v
+	}
+	return "memory"
+}
+
+// envInt reads a positive integer, falling back to def
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,25 @@
+package config
+
+import "os"
+
+// SMTP is the mail server for account emails such as password resets. With
+// no host set, messages are written to the server log instead.
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPSettings reads SMTP_HOST, SMTP_PORT (default 587), SMTP_USERNAME,
+// SMTP_PASSWORD and SMTP_FROM
+func SMTPSettings() SMTP {
+	return SMTP{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     envInt("SMTP_PORT", 587),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+}
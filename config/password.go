@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PasswordPolicy applies whenever a password is set
+type PasswordPolicy struct {
+	// PASSWORD_MIN_LENGTH, default 8
+	MinLength int
+	// How many recent passwords can't be reused, counting the current one,
+	// PASSWORD_HISTORY. 0 turns the check off.
+	History int
+	// Reject passwords found in breach lists, PASSWORD_BREACH_CHECK (default true)
+	BreachCheck bool
+	// Optional extra list, one password per line, PASSWORD_BREACH_LIST_FILE.
+	// The bundled list only has the most common passwords.
+	BreachListFile string
+}
+
+func PasswordPolicySettings() PasswordPolicy {
+	history := 5
+	if v, err := strconv.Atoi(os.Getenv("PASSWORD_HISTORY")); err == nil && v >= 0 {
+		history = v
+	}
+	breachCheck := true
+	if v, err := strconv.ParseBool(os.Getenv("PASSWORD_BREACH_CHECK")); err == nil {
+		breachCheck = v
+	}
+	return PasswordPolicy{
+		MinLength:      envInt("PASSWORD_MIN_LENGTH", 8),
+		History:        history,
+		BreachCheck:    breachCheck,
+		BreachListFile: strings.TrimSpace(os.Getenv("PASSWORD_BREACH_LIST_FILE")),
+	}
+}
+
+// PasswordResetTTL is how long an emailed reset link works, set via
+// PASSWORD_RESET_TTL_MINUTES
+func PasswordResetTTL() time.Duration {
+	return time.Duration(envInt("PASSWORD_RESET_TTL_MINUTES", 60)) * time.Minute
+}
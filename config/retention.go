@@ -0,0 +1,20 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Seven years, a common minimum for adult medical records
+const defaultRetentionDays = 2555
+
+// RecordRetention is how long archived patients must be kept before they can
+// be purged, set via RECORD_RETENTION_DAYS.
+func RecordRetention() time.Duration {
+	days := defaultRetentionDays
+	if v, err := strconv.Atoi(os.Getenv("RECORD_RETENTION_DAYS")); err == nil && v >= 0 {
+		days = v
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAccessTokenMinutes = 15
+	defaultRefreshTokenDays   = 30
+)
+
+// AccessTokenTTL is the lifetime of a JWT, set via ACCESS_TOKEN_TTL_MINUTES
+func AccessTokenTTL() time.Duration {
+	minutes := defaultAccessTokenMinutes
+	if v, err := strconv.Atoi(os.Getenv("ACCESS_TOKEN_TTL_MINUTES")); err == nil && v > 0 {
+		minutes = v
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// SessionTTL is how long a sign-in can be kept alive with refresh tokens
+// before the user must log in again, set via REFRESH_TOKEN_TTL_DAYS
+func SessionTTL() time.Duration {
+	days := defaultRefreshTokenDays
+	if v, err := strconv.Atoi(os.Getenv("REFRESH_TOKEN_TTL_DAYS")); err == nil && v > 0 {
+		days = v
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
@@ -0,0 +1,18 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const defaultInviteTTLHours = 72
+
+// InviteTTL is how long a staff invite can be redeemed, set via INVITE_TTL_HOURS
+func InviteTTL() time.Duration {
+	hours := defaultInviteTTLHours
+	if v, err := strconv.Atoi(os.Getenv("INVITE_TTL_HOURS")); err == nil && v > 0 {
+		hours = v
+	}
+	return time.Duration(hours) * time.Hour
+}
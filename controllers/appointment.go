@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+type AppointmentInput struct {
+	PatientID uint      `json:"patient_id" binding:"required"`
+	DoctorID  uint      `json:"doctor_id" binding:"required"`
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time"`
+	// Used when end_time is omitted
+	DurationMinutes int    `json:"duration_minutes"`
+	Reason          string `json:"reason"`
+}
+
+type AppointmentStatusInput struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+const defaultAppointmentMinutes = 15
+
+// CreateAppointment - Only receptionists can book appointments
+func CreateAppointment(c *gin.Context) {
+	var input AppointmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if input.EndTime.IsZero() {
+		minutes := input.DurationMinutes
+		if minutes <= 0 {
+			minutes = defaultAppointmentMinutes
+		}
+		input.EndTime = input.StartTime.Add(time.Duration(minutes) * time.Minute)
+	}
+	if !input.EndTime.After(input.StartTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_time must be after start_time"})
+		return
+	}
+	if input.StartTime.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot book an appointment in the past"})
+		return
+	}
+
+	if _, err := repository.GetPatientByID(int(input.PatientID)); err != nil {
+		respondError(c, err, "Patient not found", "Failed to fetch patient")
+		return
+	}
+	doctor, err := repository.GetDoctorByID(input.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "doctor_id must refer to a doctor"})
+		return
+	}
+
+	available, err := services.IsDoctorAvailable(doctor, input.StartTime, input.EndTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check doctor availability"})
+		return
+	}
+	if !available {
+		c.JSON(http.StatusConflict, gin.H{"error": "Doctor is not available at this time"})
+		return
+	}
+
+	bookedBy, _ := currentUserID(c)
+	appointment := models.Appointment{
+		PatientID:  input.PatientID,
+		DoctorID:   input.DoctorID,
+		StartTime:  input.StartTime,
+		EndTime:    input.EndTime,
+		Reason:     input.Reason,
+		BookedByID: bookedBy,
+	}
+
+	if err := repository.CreateAppointment(&appointment); err != nil {
+		respondError(c, err, "Patient or doctor not found", "Failed to book appointment")
+		return
+	}
+
+	created, err := repository.GetAppointmentByID(int(appointment.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Appointment booked but failed to fetch data"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, projectFields(c, created))
+}
+
+// ListAppointments - Filter by doctor_id, patient_id, status and date (YYYY-MM-DD)
+func ListAppointments(c *gin.Context) {
+	var filter repository.AppointmentFilter
+	if v := c.Query("doctor_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor_id"})
+			return
+		}
+		filter.DoctorID = uint(id)
+	}
+	if v := c.Query("patient_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient_id"})
+			return
+		}
+		filter.PatientID = uint(id)
+	}
+	if v := c.Query("status"); v != "" {
+		if !models.ValidAppointmentStatus(v) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+		filter.Status = v
+	}
+	if v := c.Query("date"); v != "" {
+		day, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date, expected YYYY-MM-DD"})
+			return
+		}
+		filter.From = day
+		filter.To = day.AddDate(0, 0, 1)
+	}
+
+	// Roles limited to assigned patients only see those patients' appointments
+	scope, ok := patientScope(c)
+	if !ok {
+		return
+	}
+	filter.AssignedTo = scope
+
+	appointments, err := repository.ListAppointments(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointments"})
+		return
+	}
+	if !recordAppointmentAccess(c, appointments...) {
+		return
+	}
+
+	c.JSON(http.StatusOK, projectFields(c, appointments))
+}
+
+// GetMyAppointments - A doctor's own day list (defaults to today)
+func GetMyAppointments(c *gin.Context) {
+	if c.GetString("role") != models.RoleDoctor {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: only doctors have a day list"})
+		return
+	}
+	doctorID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	day := time.Now()
+	if v := c.Query("date"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date, expected YYYY-MM-DD"})
+			return
+		}
+		day = parsed
+	}
+
+	appointments, err := repository.GetDoctorDayAppointments(doctorID, day)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointments"})
+		return
+	}
+	if !recordAppointmentAccess(c, appointments...) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":         day.Format("2006-01-02"),
+		"appointments": projectFields(c, appointments),
+	})
+}
+
+// GetAppointment - Get single appointment by ID
+func GetAppointment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	appointment, err := repository.GetAppointmentByID(id)
+	if err != nil {
+		respondError(c, err, "Appointment not found", "Failed to fetch appointment")
+		return
+	}
+	if !requirePatientAccess(c, appointment.PatientID) || !recordAppointmentAccess(c, appointment) {
+		return
+	}
+
+	c.JSON(http.StatusOK, projectFields(c, appointment))
+}
+
+// recordAppointmentAccess audits a read of each patient the appointments
+// show, once per patient
+func recordAppointmentAccess(c *gin.Context, appointments ...models.Appointment) bool {
+	seen := map[uint]bool{}
+	var ids []uint
+	for _, a := range appointments {
+		if !seen[a.PatientID] {
+			seen[a.PatientID] = true
+			ids = append(ids, a.PatientID)
+		}
+	}
+	if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientAppointmentsRead, ids...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
+		return false
+	}
+	return true
+}
+
+// CancelAppointment - Only receptionists can cancel appointments
+func CancelAppointment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	// Reason is optional
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&input)
+
+	respondAppointmentStatus(c, id, models.AppointmentCancelled, input.Reason)
+}
+
+// UpdateAppointmentStatus - Receptionists check patients in or mark no-shows,
+// doctors complete their own appointments
+func UpdateAppointmentStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	var input AppointmentStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	switch {
+	case hasPermission(c, models.PermAppointmentManage) && (input.Status == models.AppointmentCheckedIn || input.Status == models.AppointmentNoShow):
+	case hasPermission(c, models.PermAppointmentComplete) && (input.Status == models.AppointmentCompleted || input.Status == models.AppointmentNoShow):
+		existing, err := repository.GetAppointmentByID(id)
+		if err != nil {
+			respondError(c, err, "Appointment not found", "Failed to fetch appointment")
+			return
+		}
+		if userID, _ := currentUserID(c); existing.DoctorID != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: not your appointment"})
+			return
+		}
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: you cannot set this status"})
+		return
+	}
+
+	respondAppointmentStatus(c, id, input.Status, input.Reason)
+}
+
+func respondAppointmentStatus(c *gin.Context, id int, status, reason string) {
+	appointment, err := repository.UpdateAppointmentStatus(id, status, reason)
+	if err != nil {
+		respondError(c, err, "Appointment not found", "Failed to update appointment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Appointment updated successfully",
+		"appointment": projectFields(c, appointment),
+	})
+}
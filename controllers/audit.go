@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// Actions whose snapshots are clinical records as a whole; callers without
+// patient.clinical.read get these events without before, after and diff
+var clinicalAuditActions = map[string]bool{
+	models.AuditPatientEncounter:        true,
+	models.AuditPrescriptionCreate:      true,
+	models.AuditPrescriptionDiscontinue: true,
+	models.AuditPrescriptionOverride:    true,
+	models.AuditAllergyCreate:           true,
+	models.AuditAllergyDelete:           true,
+	models.AuditVitalsCreate:            true,
+}
+
+// Clinical patient fields, left out of other snapshots for those callers
+var clinicalPatientFields = models.FieldNames(models.Patient{}, models.AccessClinical)
+
+// GetAuditEvents - Admin-only audit query.
+// Filters: actor_id, patient_id, action, from/to (RFC3339 or YYYY-MM-DD), limit, offset
+func GetAuditEvents(c *gin.Context) {
+	filter := repository.AuditFilter{
+		Action: c.Query("action"),
+		Limit:  defaultAuditLimit,
+	}
+
+	for param, dst := range map[string]*uint{"actor_id": &filter.ActorID, "patient_id": &filter.PatientID} {
+		if v := c.Query(param); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil || id < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+				return
+			}
+			*dst = uint(id)
+		}
+	}
+
+	var err error
+	if filter.From, err = parseTimeQuery(c.Query("from"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, expected RFC3339 or YYYY-MM-DD"})
+		return
+	}
+	if filter.To, err = parseTimeQuery(c.Query("to"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, expected RFC3339 or YYYY-MM-DD"})
+		return
+	}
+
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		filter.Limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+			return
+		}
+		filter.Offset = n
+	}
+
+	events, total, err := repository.ListAuditEvents(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit events"})
+		return
+	}
+	// Snapshots would otherwise show what the role-aware projection hides
+	if !hasPermission(c, models.PermPatientClinicalRead) {
+		if err := redactClinicalSnapshots(events); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit events"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// redactClinicalSnapshots removes clinical data from the events' snapshots
+// and diffs
+func redactClinicalSnapshots(events []models.AuditEvent) error {
+	for i := range events {
+		e := &events[i]
+		if clinicalAuditActions[e.Action] {
+			e.Before, e.After, e.Diff = "", "", ""
+			continue
+		}
+		for _, text := range []*models.JSONText{&e.Before, &e.After, &e.Diff} {
+			if *text == "" || *text == "null" {
+				continue
+			}
+			fields := map[string]json.RawMessage{}
+			if err := json.Unmarshal([]byte(*text), &fields); err != nil {
+				return err
+			}
+			for _, name := range clinicalPatientFields {
+				delete(fields, name)
+			}
+			raw, err := json.Marshal(fields)
+			if err != nil {
+				return err
+			}
+			*text = models.JSONText(raw)
+		}
+	}
+	return nil
+}
+
+// parseTimeQuery accepts RFC3339 or a plain date. A plain "to" date is
+// inclusive, so it is moved to the start of the following day.
+func parseTimeQuery(v string, endOfDay bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return t, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -1,45 +1,53 @@
 package controllers
 import (
+    "errors"
+    "log"
+    "math"
     "net/http"
+    "strconv"
+    "strings"
 
     "github.com/gin-gonic/gin"
-    "golang.org/x/crypto/bcrypt"
+    "gorm.io/gorm"
     "github.com/Sathwik-145/hospital-portal/models"
-    "github.com/Sathwik-145/hospital-portal/config"
+    "github.com/Sathwik-145/hospital-portal/repository"
 	"github.com/Sathwik-145/hospital-portal/services"
-	"github.com/Sathwik-145/hospital-portal/utils"
 )
 
+// RegisterInput redeems an invite; the email and role come from the invite
 type RegisterInput struct {
-	Name     string `json:"name" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
-	Role     string `json:"role" binding:"required"`
+	InviteToken string `json:"invite_token" binding:"required"`
+	Name        string `json:"name" binding:"required,max=100"`
+	Password    string `json:"password" binding:"required"`
+	// Doctors only, when the invite did not set it
+	RegistrationNumber string `json:"registration_number" binding:"max=50"`
 }
 
+// RegisterUser - Staff register through an invite created by an admin
 func RegisterUser(c *gin.Context) {
 	var input RegisterInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Registration requires an invite from an administrator"})
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	user, err := services.RedeemInvite(input.InviteToken, input.Name, input.Password, input.RegistrationNumber)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password hashing failed"})
+		switch {
+		case errors.Is(err, repository.ErrInviteInvalid):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrPasswordPolicy):
+			respondFieldErrors(c, FieldErrors{"password": err.Error()})
+		case errors.Is(err, repository.ErrEmailTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Registration failed"})
+		}
 		return
 	}
 
-	user := models.User{
-		Name:     input.Name,
-		Email:    input.Email,
-		Password: string(hashedPassword),
-		Role:     input.Role,
-	}
-
-	if err := config.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	if err := services.RecordChange(services.AuditActor{UserID: user.ID, Role: user.Role, ClientIP: c.ClientIP()}, models.AuditUserRegister, nil, user); err != nil {
+		log.Println("audit: failed to record registration:", err)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User registered successfully!"})
@@ -53,31 +61,197 @@ func LoginUser(c *gin.Context) {
 		return
 	}
 
-	user, err := services.AuthenticateUser(credentials.Email, credentials.Password)
+	user, err := services.AuthenticateUser(credentials.Email, credentials.Password, c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		respondLoginError(c, err)
 		return
 	}
 
-	token, err := utils.GenerateJWT(*user)
+	// With MFA the tokens are only issued by VerifyMFA, in exchange for the
+	// challenge token and a code
+	challenge, err := services.BeginLoginChallenge(*user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":        true,
+			"enrollment_required": challenge.Enroll,
+			"challenge_token":     challenge.Token,
+			"expires_at":          challenge.ExpiresAt,
+		})
+		return
+	}
+
+	tokens, err := services.StartSession(*user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	// ✅ FIXED: Return user object that frontend expects
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
+	c.JSON(http.StatusOK, sessionResponse(tokens, *user))
+}
+
+// respondLoginError answers a failed password check. Wrong passwords and
+// unknown emails get the same message.
+func respondLoginError(c *gin.Context, err error) {
+	var throttled *services.LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": throttled.Error(), "locked": throttled.Locked})
+	case errors.Is(err, services.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	case errors.Is(err, services.ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+	default:
+		log.Println("auth: login failed:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Login failed"})
+	}
+}
+
+// sessionResponse is the body of every response that signs the user in
+func sessionResponse(tokens services.TokenPair, user models.User) gin.H {
+	return gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
 		"user": gin.H{
 			"id":    user.ID,
 			"name":  user.Name,
 			"email": user.Email,
 			"role":  user.Role,
+			// The app sends the user to the change-password screen first
+			"must_change_password": user.MustChangePassword,
 		},
+	}
+}
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken - Swaps a refresh token for a new access token and refresh
+// token. Each refresh token works once; reusing one ends the session.
+func RefreshToken(c *gin.Context) {
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	tokens, user, err := services.RefreshSession(input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRefreshTokenInvalid), errors.Is(err, services.ErrAccountDisabled):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has ended, please log in again"})
+		case errors.Is(err, repository.ErrRefreshTokenReused):
+			log.Println("auth: refresh token reuse detected, session revoked")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, sessionResponse(tokens, user))
+}
+
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Logout - Ends the current session. Send the refresh token, or the access
+// token as a Bearer header if the refresh token is not at hand.
+func Logout(c *gin.Context) {
+	var input LogoutInput
+	_ = c.ShouldBindJSON(&input)
+
+	if input.RefreshToken != "" {
+		if _, err := services.EndSessionByRefreshToken(input.RefreshToken); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+		return
+	}
+
+	fields := strings.Fields(c.GetHeader("Authorization"))
+	if len(fields) != 2 || strings.ToLower(fields[0]) != "bearer" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Send refresh_token or an Authorization header"})
+		return
+	}
+	claims, err := services.ParseAccessToken(fields[1])
+	if err != nil {
+		// Already expired; nothing more to revoke through it
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+		return
+	}
+	if err := repository.RevokeSession(claims.SessionID, claims.UserID, models.SessionLogout); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// GetMySessions - The caller's signed-in devices
+func GetMySessions(c *gin.Context) {
+	userID, _ := currentUserID(c)
+	sessions, err := repository.GetUserSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+	current, _ := c.Get("session_id")
+	c.JSON(http.StatusOK, gin.H{
+		"sessions":        sessions,
+		"current_session": current,
 	})
 }
 
+// RevokeMySession - Signs one of the caller's devices out
+func RevokeMySession(c *gin.Context) {
+	userID, _ := currentUserID(c)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	if err := repository.RevokeSession(uint(id), userID, models.SessionLogout); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
 
+// LogoutAll - Signs the caller out everywhere, e.g. after losing a device
+func LogoutAll(c *gin.Context) {
+	userID, _ := currentUserID(c)
+	if err := repository.RevokeUserSessions(userID, models.SessionLogoutAll); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out sessions"})
+		return
+	}
 
+	if err := services.RecordChange(auditActor(c), models.AuditUserSessionsRevoke, nil, gin.H{"user_id": userID}); err != nil {
+		log.Println("audit: failed to record logout-all:", err)
+	}
 
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions logged out"})
+}
 
+// GetJWKS - Public keys other services use to verify our access tokens
+func GetJWKS(c *gin.Context) {
+	keys, err := services.PublicJWKS()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Signing keys are not loaded"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
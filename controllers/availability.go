@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+type ScheduleInput struct {
+	Hours  []models.DoctorAvailability `json:"hours"`
+	Breaks []models.DoctorBreak        `json:"breaks"`
+}
+
+type LeaveInput struct {
+	StartDate string `json:"start_date" binding:"required"`
+	EndDate   string `json:"end_date"`
+	Reason    string `json:"reason"`
+}
+
+const (
+	defaultSlotCount = 5
+	maxSlotCount     = 50
+)
+
+// GetDoctors - List doctors for booking
+func GetDoctors(c *gin.Context) {
+	doctors, err := repository.GetDoctors()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, doctors)
+}
+
+// GetDoctorAvailability - Weekly template plus upcoming leave
+func GetDoctorAvailability(c *gin.Context) {
+	doctor, ok := doctorFromParam(c)
+	if !ok {
+		return
+	}
+
+	hours, breaks, err := repository.GetDoctorSchedule(doctor.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch availability"})
+		return
+	}
+	leaves, err := repository.GetDoctorLeaves(doctor.ID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leave"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"doctor_id": doctor.ID,
+		"hours":     hours,
+		"breaks":    breaks,
+		"leaves":    leaves,
+	})
+}
+
+// SetDoctorAvailability - Doctors edit their own template, receptionists any doctor's
+func SetDoctorAvailability(c *gin.Context) {
+	doctor, ok := doctorFromParam(c)
+	if !ok || !canManageSchedule(c, doctor.ID) {
+		return
+	}
+
+	var input ScheduleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if err := services.ValidateSchedule(input.Hours, input.Breaks); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repository.ReplaceDoctorSchedule(doctor.ID, input.Hours, input.Breaks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save availability"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Availability updated successfully",
+		"hours":   input.Hours,
+		"breaks":  input.Breaks,
+	})
+}
+
+// AddDoctorLeave - Block whole days for a doctor
+func AddDoctorLeave(c *gin.Context) {
+	doctor, ok := doctorFromParam(c)
+	if !ok || !canManageSchedule(c, doctor.ID) {
+		return
+	}
+
+	var input LeaveInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if input.EndDate == "" {
+		input.EndDate = input.StartDate
+	}
+	start, err := time.Parse("2006-01-02", input.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date, expected YYYY-MM-DD"})
+		return
+	}
+	end, err := time.Parse("2006-01-02", input.EndDate)
+	if err != nil || end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date, expected YYYY-MM-DD on or after start_date"})
+		return
+	}
+
+	leave := models.DoctorLeave{
+		DoctorID:  doctor.ID,
+		StartDate: start,
+		EndDate:   end,
+		Reason:    input.Reason,
+	}
+	if err := repository.CreateDoctorLeave(&leave); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save leave"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, leave)
+}
+
+// DeleteDoctorLeave - Remove a leave entry
+func DeleteDoctorLeave(c *gin.Context) {
+	doctor, ok := doctorFromParam(c)
+	if !ok || !canManageSchedule(c, doctor.ID) {
+		return
+	}
+
+	leaveID, err := strconv.Atoi(c.Param("leaveId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid leave ID"})
+		return
+	}
+
+	if err := repository.DeleteDoctorLeave(doctor.ID, leaveID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Leave not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete leave"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Leave deleted successfully"})
+}
+
+// FindSlots - Next free slots for doctor_id, or for any doctor when omitted.
+// Optional: from (RFC3339), count, duration_minutes
+func FindSlots(c *gin.Context) {
+	var doctorID uint
+	if v := c.Query("doctor_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor_id"})
+			return
+		}
+		doctorID = uint(id)
+	}
+
+	from := time.Now()
+	if v := c.Query("from"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, expected RFC3339"})
+			return
+		}
+		if parsed.After(from) {
+			from = parsed.In(time.Local)
+		}
+	}
+
+	count := defaultSlotCount
+	if v := c.Query("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSlotCount {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and 50"})
+			return
+		}
+		count = n
+	}
+
+	var duration time.Duration
+	if v := c.Query("duration_minutes"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration_minutes"})
+			return
+		}
+		duration = time.Duration(n) * time.Minute
+	}
+
+	slots, err := services.FindFreeSlots(doctorID, from, count, duration)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, repository.ErrNotADoctor) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search slots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
+
+// doctorFromParam loads the doctor in the :id path param, writing the error response itself
+func doctorFromParam(c *gin.Context) (models.User, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return models.User{}, false
+	}
+	doctor, err := repository.GetDoctorByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
+		return models.User{}, false
+	}
+	return doctor, true
+}
+
+func canManageSchedule(c *gin.Context, doctorID uint) bool {
+	userID, _ := currentUserID(c)
+	if hasPermission(c, models.PermScheduleManage) || (hasPermission(c, models.PermScheduleManageOwn) && userID == doctorID) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: you cannot change this doctor's schedule"})
+	return false
+}
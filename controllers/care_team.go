@@ -0,0 +1,333 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+// Break-glass reasons must say something about the emergency
+const minBreakGlassReason = 10
+
+type PrimaryDoctorInput struct {
+	// null unassigns the patient
+	DoctorID *uint `json:"doctor_id"`
+}
+
+type CareTeamMemberInput struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Note   string `json:"note" binding:"max=200"`
+}
+
+type BreakGlassInput struct {
+	Reason string `json:"reason" binding:"required,max=1000"`
+}
+
+type RolePatientScopeInput struct {
+	AssignedOnly *bool `json:"assigned_only" binding:"required"`
+}
+
+// GetCareTeam - The patient's primary doctor and care team
+func GetCareTeam(c *gin.Context) {
+	patient, ok := careTeamPatient(c)
+	if !ok {
+		return
+	}
+	team, err := services.GetCareTeam(patient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch care team"})
+		return
+	}
+	c.JSON(http.StatusOK, team)
+}
+
+// SetPrimaryDoctor - Assigns the patient to a doctor; {"doctor_id": null} unassigns
+func SetPrimaryDoctor(c *gin.Context) {
+	patient, ok := careTeamPatient(c)
+	if !ok {
+		return
+	}
+	var input PrimaryDoctorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	before, err := services.GetCareTeam(patient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch care team"})
+		return
+	}
+	err = saveCareTeamChange(c, patient, before, input.DoctorID, func(uow repository.UnitOfWork) error {
+		return services.SetPrimaryDoctor(uow, int(patient.ID), input.DoctorID)
+	})
+	if err != nil {
+		respondCareTeamError(c, err, "doctor_id", "Failed to assign primary doctor")
+		return
+	}
+	patient.PrimaryDoctorID = input.DoctorID
+	respondCareTeam(c, patient)
+}
+
+// AddCareTeamMember - Gives another staff member access to the patient
+func AddCareTeamMember(c *gin.Context) {
+	patient, ok := careTeamPatient(c)
+	if !ok {
+		return
+	}
+	var input CareTeamMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+	userID, _ := currentUserID(c)
+
+	before, err := services.GetCareTeam(patient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch care team"})
+		return
+	}
+	member := models.CareTeamMember{
+		PatientID: patient.ID,
+		UserID:    input.UserID,
+		Note:      strings.TrimSpace(input.Note),
+		AddedByID: userID,
+	}
+	err = saveCareTeamChange(c, patient, before, patient.PrimaryDoctorID, func(uow repository.UnitOfWork) error {
+		return services.AddCareTeamMember(uow, &member)
+	})
+	if err != nil {
+		respondCareTeamError(c, err, "user_id", "Failed to add care team member")
+		return
+	}
+	respondCareTeam(c, patient)
+}
+
+// RemoveCareTeamMember - Takes a staff member off the patient's care team
+func RemoveCareTeamMember(c *gin.Context) {
+	patient, ok := careTeamPatient(c)
+	if !ok {
+		return
+	}
+	memberID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	before, err := services.GetCareTeam(patient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch care team"})
+		return
+	}
+	err = saveCareTeamChange(c, patient, before, patient.PrimaryDoctorID, func(uow repository.UnitOfWork) error {
+		return services.RemoveCareTeamMember(uow, int(patient.ID), uint(memberID))
+	})
+	if err != nil {
+		respondError(c, err, "User is not on this patient's care team", "Failed to remove care team member")
+		return
+	}
+	respondCareTeam(c, patient)
+}
+
+// BreakGlass - Emergency access to a patient outside the caller's care team.
+// Needs a reason; the grant expires after BREAK_GLASS_MINUTES and every
+// request made with it is audited.
+func BreakGlass(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+	var input BreakGlassInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required for emergency access"})
+		return
+	}
+	reason := strings.TrimSpace(input.Reason)
+	if len([]rune(reason)) < minBreakGlassReason {
+		respondFieldErrors(c, FieldErrors{"reason": "describe the emergency in at least 10 characters"})
+		return
+	}
+	if _, err := repository.GetPatientByID(id); err != nil {
+		respondError(c, err, "Patient not found", "Failed to fetch patient")
+		return
+	}
+
+	grant, err := services.BreakGlass(auditActor(c), id, reason)
+	if err != nil {
+		if errors.Is(err, services.ErrBreakGlassNotNeeded) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant emergency access"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Emergency access granted. This access is audited and will be reviewed.",
+		"access":  grant,
+	})
+}
+
+// ListBreakGlassAccess - Break-glass grants for review, newest first.
+// Query: user_id, patient_id, from, to (YYYY-MM-DD), active=true, limit
+func ListBreakGlassAccess(c *gin.Context) {
+	q := repository.BreakGlassQuery{Limit: defaultAuditLimit}
+	for param, dst := range map[string]*uint{"user_id": &q.UserID, "patient_id": &q.PatientID} {
+		if v := c.Query(param); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+				return
+			}
+			*dst = uint(n)
+		}
+	}
+	for param, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if v := c.Query(param); v != "" {
+			day, err := time.ParseInLocation("2006-01-02", v, time.Local)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + ", expected YYYY-MM-DD"})
+				return
+			}
+			*dst = day
+		}
+	}
+	if !q.To.IsZero() {
+		q.To = q.To.AddDate(0, 0, 1)
+	}
+	q.ActiveOnly = c.Query("active") == "true"
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		q.Limit = n
+	}
+
+	grants, err := repository.ListBreakGlassAccess(q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch break-glass access"})
+		return
+	}
+	c.JSON(http.StatusOK, grants)
+}
+
+// UpdateRolePatientScope - Limits a role to the patients its members are
+// assigned to, or lets it see every patient
+func UpdateRolePatientScope(c *gin.Context) {
+	role := c.Param("role")
+	if !models.ValidRole(role) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown role", "allowed": models.Roles})
+		return
+	}
+	var input RolePatientScopeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "assigned_only must be true or false"})
+		return
+	}
+
+	scope, err := services.SetRoleAssignedOnly(auditActor(c), role, *input.AssignedOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save patient scope"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scope)
+}
+
+// patientScope returns the user to limit patient lists to, or 0 when the
+// caller sees every patient. It responds itself when the check fails.
+func patientScope(c *gin.Context) (uint, bool) {
+	userID, _ := currentUserID(c)
+	scope, err := services.PatientScope(userID, c.GetString("role"))
+	if err != nil {
+		log.Println("patient access: failed to load patient scope:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check patient access"})
+		return 0, false
+	}
+	return scope, true
+}
+
+// requirePatientAccess is middleware.RequirePatientAccess for routes that
+// reach a patient through another record, e.g. a prescription
+func requirePatientAccess(c *gin.Context, patientID uint) bool {
+	err := services.AuthorizePatientAccess(auditActor(c), int(patientID), c.Request.Method, c.Request.URL.Path)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrPatientNotAssigned):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "patient_not_assigned"})
+	default:
+		log.Println("patient access:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check patient access"})
+	}
+	return false
+}
+
+func careTeamPatient(c *gin.Context) (models.Patient, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return models.Patient{}, false
+	}
+	patient, err := repository.GetPatientByID(id)
+	if err != nil {
+		respondError(c, err, "Patient not found", "Failed to fetch patient")
+		return models.Patient{}, false
+	}
+	return patient, true
+}
+
+// saveCareTeamChange runs change and audits it in one transaction; primary
+// is the patient's primary doctor once the change is made
+func saveCareTeamChange(c *gin.Context, patient models.Patient, before services.CareTeam, primary *uint, change func(uow repository.UnitOfWork) error) error {
+	return repository.Atomically(func(uow repository.UnitOfWork) error {
+		if err := change(uow); err != nil {
+			return err
+		}
+		members, err := uow.GetCareTeam(int(patient.ID))
+		if err != nil {
+			return err
+		}
+		return services.RecordPatientChangeIn(uow, auditActor(c), models.AuditPatientCareTeamUpdate, patient.ID,
+			careTeamSnapshot(patient.PrimaryDoctorID, before.Members), careTeamSnapshot(primary, members))
+	})
+}
+
+// respondCareTeam answers with the team as it is after a change
+func respondCareTeam(c *gin.Context, patient models.Patient) {
+	after, err := services.GetCareTeam(patient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Care team updated but fetch failed"})
+		return
+	}
+	c.JSON(http.StatusOK, after)
+}
+
+func careTeamSnapshot(primary *uint, team []models.CareTeamMember) gin.H {
+	members := make([]uint, len(team))
+	for i, m := range team {
+		members[i] = m.UserID
+	}
+	return gin.H{"primary_doctor_id": primary, "member_ids": members}
+}
+
+func respondCareTeamError(c *gin.Context, err error, field, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrNotADoctor), errors.Is(err, services.ErrInactiveStaff):
+		respondFieldErrors(c, FieldErrors{field: err.Error()})
+	default:
+		respondError(c, err, "Patient not found", fallback)
+	}
+}
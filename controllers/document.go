@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+// GetVisitPDF - Printable PDF for one visit. ?type=summary (default) for the
+// visit summary or ?type=prescription for the prescription slip.
+func GetVisitPDF(c *gin.Context) {
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+	visitID, err := strconv.Atoi(c.Param("visitId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid visit ID"})
+		return
+	}
+
+	var docType string
+	switch c.DefaultQuery("type", "summary") {
+	case "summary":
+		docType = services.DocumentVisitSummary
+	case "prescription":
+		docType = services.DocumentPrescription
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be summary or prescription"})
+		return
+	}
+
+	doc, err := services.LoadVisitDocument(docType, patientID, visitID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Visit not found for this patient"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load visit"})
+		return
+	}
+	if docType == services.DocumentPrescription && len(doc.Prescriptions) == 0 && doc.Visit.Prescriptions == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No prescriptions were recorded at this visit"})
+		return
+	}
+
+	sig, err := services.SignDocument(doc, time.Now())
+	if err != nil {
+		if errors.Is(err, services.ErrSigningKeyMissing) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Document signing is not configured"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign document"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := services.RenderVisitPDF(&buf, doc, sig); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render PDF"})
+		return
+	}
+
+	if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientDocumentPrint, doc.Visit.PatientID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%d-visit-%d.pdf", docType, doc.Visit.PatientID, doc.Visit.ID)
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// VerifyDocument - Public check of the QR code printed on a visit document.
+// Only returns enough to confirm the document, never clinical content.
+func VerifyDocument(c *gin.Context) {
+	visitID, err := strconv.ParseUint(c.Query("v"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification code"})
+		return
+	}
+	issued, err := strconv.ParseInt(c.Query("i"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification code"})
+		return
+	}
+
+	result, err := services.VerifyDocument(c.Query("t"), uint(visitID), issued, c.Query("h"), c.Query("s"))
+	if err != nil {
+		if errors.Is(err, services.ErrSigningKeyMissing) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Document verification is not configured"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify document"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+// EncounterInput is the payload for POST /api/patients/:id/encounters
+type EncounterInput struct {
+	ChiefComplaint string `json:"chief_complaint" binding:"max=1000"`
+	Findings       string `json:"findings" binding:"max=10000"`
+	Diagnosis      string `json:"diagnosis" binding:"max=1000"`
+	Plan           string `json:"plan" binding:"max=10000"`
+	MedicalNotes   string `json:"medical_notes" binding:"max=10000"`
+	// Free text; structured prescriptions go to /prescriptions with visit_id
+	Prescriptions string `json:"prescriptions" binding:"max=5000"`
+	// Required to go ahead when new prescriptions trigger a blocking allergy
+	// or interaction warning
+	OverrideReason string `json:"override_reason" binding:"max=500"`
+}
+
+func (in *EncounterInput) normalize() {
+	in.ChiefComplaint = strings.TrimSpace(in.ChiefComplaint)
+	in.Findings = strings.TrimSpace(in.Findings)
+	in.Diagnosis = strings.TrimSpace(in.Diagnosis)
+	in.Plan = strings.TrimSpace(in.Plan)
+	in.MedicalNotes = strings.TrimSpace(in.MedicalNotes)
+	in.Prescriptions = strings.TrimSpace(in.Prescriptions)
+	in.OverrideReason = strings.TrimSpace(in.OverrideReason)
+}
+
+func (in EncounterInput) validate(canPrescribe bool) FieldErrors {
+	fields := FieldErrors{}
+	if in.ChiefComplaint == "" && in.Findings == "" && in.Diagnosis == "" {
+		fields["diagnosis"] = "an encounter needs a chief complaint, findings or a diagnosis"
+	}
+	if in.Prescriptions != "" && !canPrescribe {
+		fields["prescriptions"] = "prescribing needs the " + models.PermPrescriptionWrite + " permission"
+	}
+	return fields
+}
+
+func (in EncounterInput) toModel(patientID uint) models.MedicalHistory {
+	return models.MedicalHistory{
+		PatientID:      patientID,
+		ChiefComplaint: in.ChiefComplaint,
+		Findings:       in.Findings,
+		Diagnosis:      in.Diagnosis,
+		Plan:           in.Plan,
+		MedicalNotes:   in.MedicalNotes,
+		Prescriptions:  in.Prescriptions,
+	}
+}
+
+// CreateEncounter - Records a visit. The patient's diagnosis, notes and
+// prescriptions then show this visit's values; earlier visits stay in the history.
+func CreateEncounter(c *gin.Context) {
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	var input EncounterInput
+	fields, ok := bindValidated(c, &input)
+	if !ok {
+		return
+	}
+	if fields = fields.merge(input.validate(hasPermission(c, models.PermPrescriptionWrite))); len(fields) > 0 {
+		respondFieldErrors(c, fields)
+		return
+	}
+
+	before, err := repository.GetPatientByID(patientID)
+	if err != nil {
+		respondError(c, err, "Patient not found", "Failed to fetch patient")
+		return
+	}
+
+	// New drugs in free-text prescriptions are checked against allergies
+	// and active medications
+	check := services.SafetyCheck{Warnings: []services.SafetyWarning{}}
+	if input.Prescriptions != "" && input.Prescriptions != before.Prescriptions {
+		check, err = services.CheckPrescriptionText(before.ID, input.Prescriptions, before.Prescriptions)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check allergies and interactions"})
+			return
+		}
+		if check.Blocked && input.OverrideReason == "" {
+			respondSafetyBlocked(c, check)
+			return
+		}
+	}
+
+	encounter := input.toModel(before.ID)
+	err = repository.Atomically(func(uow repository.UnitOfWork) error {
+		if err := uow.CreateEncounter(&encounter, userID); err != nil {
+			return err
+		}
+		if check.Blocked {
+			override := gin.H{"visit_id": encounter.ID, "override_reason": input.OverrideReason, "warnings": check.Warnings}
+			if err := services.RecordPatientChangeIn(uow, auditActor(c), models.AuditPrescriptionOverride, before.ID, nil, override); err != nil {
+				return err
+			}
+		}
+		return services.RecordPatientChangeIn(uow, auditActor(c), models.AuditPatientEncounter, before.ID, nil, encounter)
+	})
+	if err != nil {
+		respondError(c, err, "Patient not found", "Failed to record encounter")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"encounter": projectFields(c, encounter),
+		"warnings":  check.Warnings,
+	})
+}
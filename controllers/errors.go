@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+// respondError answers with the status for the kind of repository error:
+// 404 (with notFound unless the error says what is missing), 409, 400 or a
+// field error. Anything else is a server fault, logged and answered with
+// fallback so database details do not reach clients.
+func respondError(c *gin.Context, err error, notFound, fallback string) {
+	var typed *repository.Error
+	hasMessage := errors.As(err, &typed)
+
+	switch repository.KindOf(err) {
+	case repository.ErrNotFound:
+		if hasMessage {
+			notFound = typed.Message
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	case repository.ErrConflict:
+		msg := "The change conflicts with existing records"
+		if hasMessage {
+			msg = typed.Message
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+	case repository.ErrValidation:
+		switch {
+		case hasMessage && typed.Field != "":
+			respondFieldErrors(c, FieldErrors{typed.Field: typed.Message})
+		case hasMessage:
+			c.JSON(http.StatusBadRequest, gin.H{"error": typed.Message})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		}
+	default:
+		log.Printf("%s: %v", fallback, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+// currentUserID reads the user_id that AuthMiddleware stores from the JWT claims.
+// JSON numbers in jwt.MapClaims decode as float64.
+func currentUserID(c *gin.Context) (uint, bool) {
+	v, ok := c.Get("user_id")
+	if !ok {
+		return 0, false
+	}
+	switch id := v.(type) {
+	case float64:
+		return uint(id), id > 0
+	case uint:
+		return id, id > 0
+	}
+	return 0, false
+}
+
+func auditActor(c *gin.Context) services.AuditActor {
+	userID, _ := currentUserID(c)
+	role, _ := c.Get("role")
+	roleName, _ := role.(string)
+	return services.AuditActor{
+		UserID:   userID,
+		Role:     roleName,
+		ClientIP: c.ClientIP(),
+	}
+}
+
+// hasPermission is for handlers whose behaviour, not access, depends on the
+// caller's permissions; route access is checked by middleware.RequirePermission
+func hasPermission(c *gin.Context, permission string) bool {
+	ok, err := services.HasPermission(c.GetString("role"), permission)
+	if err != nil {
+		log.Println("rbac: failed to load permissions:", err)
+	}
+	return ok
+}
+
+// projectFields drops the fields of v that the caller's role may not see,
+// e.g. a patient's diagnosis without patient.clinical.read (see models.Project)
+func projectFields(c *gin.Context, v interface{}) interface{} {
+	clinical := hasPermission(c, models.PermPatientClinicalRead)
+	return models.Project(v, func(level string) bool {
+		return level == models.AccessClinical && clinical
+	})
+}
@@ -0,0 +1,375 @@
+package controllers
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+type HouseholdInput struct {
+	Name          string `json:"name"`
+	PhoneNumber   string `json:"phone_number"`
+	HeadPatientID uint   `json:"head_patient_id" binding:"required"`
+}
+
+type HouseholdMemberInput struct {
+	PatientID    uint   `json:"patient_id"`
+	Relationship string `json:"relationship" binding:"required"`
+}
+
+type HouseholdHeadInput struct {
+	PatientID uint `json:"patient_id" binding:"required"`
+}
+
+// CreateHousehold - Only receptionists can create households
+func CreateHousehold(c *gin.Context) {
+	var input HouseholdInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	head, err := repository.GetPatientByID(int(input.HeadPatientID))
+	if err != nil {
+		respondError(c, err, "Patient not found", "Failed to fetch patient")
+		return
+	}
+	if input.Name == "" {
+		input.Name = head.Name + " household"
+	}
+	if input.PhoneNumber == "" {
+		input.PhoneNumber = head.PhoneNumber
+	}
+
+	household := models.Household{Name: input.Name, PhoneNumber: input.PhoneNumber}
+	err = repository.Atomically(func(uow repository.UnitOfWork) error {
+		if err := uow.CreateHousehold(&household, head.ID); err != nil {
+			return err
+		}
+		return auditHouseholdMembers(c, uow, household.ID, nil)
+	})
+	if err != nil {
+		respondHouseholdError(c, err, "Failed to create household")
+		return
+	}
+
+	respondHousehold(c, http.StatusCreated, int(household.ID))
+}
+
+// GetHousehold - Household with its members
+func GetHousehold(c *gin.Context) {
+	id, ok := householdIDParam(c)
+	if !ok {
+		return
+	}
+	respondHousehold(c, http.StatusOK, id)
+}
+
+// GetHouseholdHistory - Medical history of every household member, with a visit summary
+func GetHouseholdHistory(c *gin.Context) {
+	id, ok := householdIDParam(c)
+	if !ok {
+		return
+	}
+	household, err := repository.GetHousehold(id)
+	if err != nil {
+		respondHouseholdError(c, err, "Failed to fetch household")
+		return
+	}
+	respondHouseholdHistory(c, household)
+}
+
+// GetPatientHousehold - Household history for the household a patient belongs to
+func GetPatientHousehold(c *gin.Context) {
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	household, err := repository.GetHouseholdByPatient(patientID)
+	if err != nil {
+		respondError(c, err, "Patient is not in a household", "Failed to fetch household")
+		return
+	}
+	respondHouseholdHistory(c, household)
+}
+
+// AddHouseholdMember - Link a patient to a household
+func AddHouseholdMember(c *gin.Context) {
+	id, ok := householdIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input HouseholdMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.PatientID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if !validMemberRelationship(c, input.Relationship) {
+		return
+	}
+
+	err := saveHouseholdChange(c, uint(id), func(uow repository.UnitOfWork) error {
+		return uow.AddHouseholdMember(uint(id), input.PatientID, input.Relationship)
+	})
+	if err != nil {
+		respondHouseholdError(c, err, "Failed to add household member")
+		return
+	}
+
+	respondHousehold(c, http.StatusOK, id)
+}
+
+// UpdateHouseholdMember - Change a member's relationship to the head
+func UpdateHouseholdMember(c *gin.Context) {
+	id, ok := householdIDParam(c)
+	if !ok {
+		return
+	}
+	patientID, err := strconv.Atoi(c.Param("patientId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var input HouseholdMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if !validMemberRelationship(c, input.Relationship) {
+		return
+	}
+
+	err = saveHouseholdChange(c, uint(id), func(uow repository.UnitOfWork) error {
+		return uow.UpdateHouseholdMember(uint(id), uint(patientID), input.Relationship)
+	})
+	if err != nil {
+		respondHouseholdError(c, err, "Failed to update household member")
+		return
+	}
+
+	respondHousehold(c, http.StatusOK, id)
+}
+
+// RemoveHouseholdMember - Unlink a patient from a household
+func RemoveHouseholdMember(c *gin.Context) {
+	id, ok := householdIDParam(c)
+	if !ok {
+		return
+	}
+	patientID, err := strconv.Atoi(c.Param("patientId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	err = saveHouseholdChange(c, uint(id), func(uow repository.UnitOfWork) error {
+		return uow.RemoveHouseholdMember(uint(id), uint(patientID))
+	})
+	if err != nil {
+		respondHouseholdError(c, err, "Failed to remove household member")
+		return
+	}
+
+	respondHousehold(c, http.StatusOK, id)
+}
+
+// SetHouseholdHead - Make an existing member the head of household
+func SetHouseholdHead(c *gin.Context) {
+	id, ok := householdIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input HouseholdHeadInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	err := saveHouseholdChange(c, uint(id), func(uow repository.UnitOfWork) error {
+		return uow.SetHouseholdHead(uint(id), input.PatientID)
+	})
+	if err != nil {
+		respondHouseholdError(c, err, "Failed to change head of household")
+		return
+	}
+
+	respondHousehold(c, http.StatusOK, id)
+}
+
+func householdIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid household ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+func validMemberRelationship(c *gin.Context, relationship string) bool {
+	if relationship == "self" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use the head endpoint to make a member the head of household"})
+		return false
+	}
+	if !models.ValidRelationship(relationship) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid relationship", "allowed": models.Relationships})
+		return false
+	}
+	return true
+}
+
+func respondHousehold(c *gin.Context, status, id int) {
+	household, err := repository.GetHousehold(id)
+	if err != nil {
+		respondHouseholdError(c, err, "Failed to fetch household")
+		return
+	}
+	if !hideUnassignedMembers(c, &household) {
+		return
+	}
+	memberIDs := make([]uint, len(household.Members))
+	for i, m := range household.Members {
+		memberIDs[i] = m.PatientID
+	}
+	if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientHouseholdRead, memberIDs...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
+		return
+	}
+	c.JSON(status, projectFields(c, household))
+}
+
+// saveHouseholdChange makes change to household id and audits it in the same
+// transaction
+func saveHouseholdChange(c *gin.Context, id uint, change func(uow repository.UnitOfWork) error) error {
+	return repository.Atomically(func(uow repository.UnitOfWork) error {
+		before, err := uow.LockHouseholdMembers(id)
+		if err != nil {
+			return err
+		}
+		if err := change(uow); err != nil {
+			return err
+		}
+		return auditHouseholdMembers(c, uow, id, before)
+	})
+}
+
+// auditHouseholdMembers records a change for every patient who joined or
+// left household id, or whose relationship changed, since before. Membership
+// also sets the patient's relationship, so it is audited as a patient write.
+func auditHouseholdMembers(c *gin.Context, uow repository.UnitOfWork, id uint, before []models.HouseholdMember) error {
+	after, err := uow.LockHouseholdMembers(id)
+	if err != nil {
+		return err
+	}
+
+	// Snapshots per patient; a patient missing from one side has none
+	was := map[uint]interface{}{}
+	now := map[uint]interface{}{}
+	var patientIDs []uint
+	for _, m := range before {
+		was[m.PatientID] = gin.H{"household_id": m.HouseholdID, "relationship": m.Relationship}
+		patientIDs = append(patientIDs, m.PatientID)
+	}
+	for _, m := range after {
+		now[m.PatientID] = gin.H{"household_id": m.HouseholdID, "relationship": m.Relationship}
+		if _, ok := was[m.PatientID]; !ok {
+			patientIDs = append(patientIDs, m.PatientID)
+		}
+	}
+
+	for _, patientID := range patientIDs {
+		if reflect.DeepEqual(was[patientID], now[patientID]) {
+			continue
+		}
+		if err := services.RecordPatientChangeIn(uow, auditActor(c), models.AuditPatientHouseholdUpdate, patientID, was[patientID], now[patientID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hideUnassignedMembers drops the members outside the caller's care team,
+// for roles that only see assigned patients
+func hideUnassignedMembers(c *gin.Context, household *models.Household) bool {
+	ids := make([]uint, len(household.Members))
+	for i, m := range household.Members {
+		ids[i] = m.PatientID
+	}
+	userID, _ := currentUserID(c)
+	allowed, err := services.FilterAssignedPatients(userID, c.GetString("role"), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check patient access"})
+		return false
+	}
+	keep := make(map[uint]bool, len(allowed))
+	for _, id := range allowed {
+		keep[id] = true
+	}
+	members := make([]models.HouseholdMember, 0, len(allowed))
+	for _, m := range household.Members {
+		if keep[m.PatientID] {
+			members = append(members, m)
+		}
+	}
+	household.Members = members
+	return true
+}
+
+func respondHouseholdHistory(c *gin.Context, household models.Household) {
+	history, err := repository.GetHouseholdHistory(household.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch family history"})
+		return
+	}
+	// Visits of members outside the caller's care team stay hidden
+	userID, _ := currentUserID(c)
+	allowed, err := services.FilterAssignedPatients(userID, c.GetString("role"), historyPatientIDs(history))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check patient access"})
+		return
+	}
+	history = visitsForPatients(history, allowed)
+	if !hideUnassignedMembers(c, &household) {
+		return
+	}
+
+	members := make([]models.Patient, 0, len(household.Members))
+	memberIDs := make([]uint, 0, len(household.Members))
+	for _, m := range household.Members {
+		if m.Patient != nil {
+			members = append(members, *m.Patient)
+			memberIDs = append(memberIDs, m.PatientID)
+		}
+	}
+
+	summary, err := repository.GetHouseholdVisitSummary(household.ID, memberIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get family summary"})
+		return
+	}
+	if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientFamilyRead, memberIDs...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, projectFields(c, gin.H{
+		"household":       household,
+		"family_summary":  summary,
+		"medical_history": history,
+		"family_members":  members,
+	}))
+}
+
+func respondHouseholdError(c *gin.Context, err error, fallback string) {
+	respondError(c, err, "Household or patient not found", fallback)
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+type AllergyInput struct {
+	Substance string `json:"substance" binding:"required,max=200"`
+	Reaction  string `json:"reaction" binding:"max=500"`
+	Severity  string `json:"severity" binding:"omitempty,oneof=mild moderate severe unknown"`
+}
+
+func (in *AllergyInput) normalize() {
+	in.Substance = strings.TrimSpace(in.Substance)
+	in.Reaction = strings.TrimSpace(in.Reaction)
+	in.Severity = strings.ToLower(strings.TrimSpace(in.Severity))
+	if in.Severity == "" {
+		in.Severity = "unknown"
+	}
+}
+
+// GetPatientAllergies - Documented allergies for a patient
+func GetPatientAllergies(c *gin.Context) {
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+	if _, err := repository.GetPatientByID(patientID); err != nil {
+		respondError(c, err, "Patient not found", "Failed to fetch patient")
+		return
+	}
+
+	allergies, err := repository.GetPatientAllergies(patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allergies"})
+		return
+	}
+
+	if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientAllergiesRead, uint(patientID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, allergies)
+}
+
+// AddPatientAllergy - Doctors document an allergy
+func AddPatientAllergy(c *gin.Context) {
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	var input AllergyInput
+	fields, ok := bindValidated(c, &input)
+	if !ok {
+		return
+	}
+	if len(fields) > 0 {
+		respondFieldErrors(c, fields)
+		return
+	}
+
+	allergy := models.PatientAllergy{
+		PatientID:    uint(patientID),
+		Substance:    input.Substance,
+		Reaction:     input.Reaction,
+		Severity:     input.Severity,
+		RecordedByID: userID,
+	}
+	err = repository.Atomically(func(uow repository.UnitOfWork) error {
+		if err := uow.CreatePatientAllergy(&allergy); err != nil {
+			return err
+		}
+		return services.RecordPatientChangeIn(uow, auditActor(c), models.AuditAllergyCreate, allergy.PatientID, nil, allergy)
+	})
+	if err != nil {
+		respondError(c, err, "Patient not found", "Failed to record allergy")
+		return
+	}
+
+	c.JSON(http.StatusCreated, allergy)
+}
+
+// DeletePatientAllergy - Doctors remove an allergy recorded in error
+func DeletePatientAllergy(c *gin.Context) {
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+	allergyID, err := strconv.Atoi(c.Param("allergyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid allergy ID"})
+		return
+	}
+
+	err = repository.Atomically(func(uow repository.UnitOfWork) error {
+		removed, err := uow.DeletePatientAllergy(patientID, allergyID)
+		if err != nil {
+			return err
+		}
+		return services.RecordPatientChangeIn(uow, auditActor(c), models.AuditAllergyDelete, removed.PatientID, removed, nil)
+	})
+	if err != nil {
+		respondError(c, err, "Allergy not found", "Failed to remove allergy")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Allergy removed"})
+}
+
+// ImportInteractionRules - Admin-only rules import. Accepts a JSON array or a
+// CSV file (drug_a, drug_b, severity, description), as a multipart "file"
+// field or the raw body. Existing pairs are updated.
+func ImportInteractionRules(c *gin.Context) {
+	body, closeBody, ok := uploadBody(c)
+	if !ok {
+		return
+	}
+	defer closeBody()
+
+	parse := services.ParseInteractionCSV
+	if c.ContentType() == "application/json" || c.Query("format") == "json" {
+		parse = services.ParseInteractionJSON
+	}
+	rules, err := parse(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rules file: " + err.Error()})
+		return
+	}
+
+	affected, err := repository.ImportInteractionRules(rules, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import interaction rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Interaction rules imported",
+		"rows":     len(rules),
+		"affected": affected,
+	})
+}
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+type MFAChallengeInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// MFAVerifyInput answers a login challenge with an authenticator code or,
+// for users who lost their device, a recovery code
+type MFAVerifyInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type MFACodeInput struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type RoleMFAInput struct {
+	Required *bool `json:"required" binding:"required"`
+}
+
+// VerifyMFA - Second login step: swaps the challenge token and a code for the
+// session tokens. When the user had to enroll, the response also carries
+// their recovery codes.
+func VerifyMFA(c *gin.Context) {
+	var input MFAVerifyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "challenge_token is required"})
+		return
+	}
+	if input.Code == "" && input.RecoveryCode == "" {
+		respondFieldErrors(c, FieldErrors{"code": "enter the code from your authenticator app or a recovery code"})
+		return
+	}
+
+	user, recoveryCodes, err := services.CompleteLoginChallenge(input.ChallengeToken, input.Code, input.RecoveryCode, c.ClientIP())
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	tokens, err := services.StartSession(user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	body := sessionResponse(tokens, user)
+	if recoveryCodes != nil {
+		body["recovery_codes"] = recoveryCodes
+		actor := services.AuditActor{UserID: user.ID, Role: user.Role, ClientIP: c.ClientIP()}
+		if err := services.RecordChange(actor, models.AuditUserMFAEnable, nil, gin.H{"user_id": user.ID}); err != nil {
+			log.Println("audit: failed to record MFA enrollment:", err)
+		}
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// EnrollMFAForChallenge - Authenticator setup for users whose role requires
+// MFA and who have not enrolled yet; confirm with VerifyMFA
+func EnrollMFAForChallenge(c *gin.Context) {
+	var input MFAChallengeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "challenge_token is required"})
+		return
+	}
+	enrollment, err := services.EnrollForChallenge(input.ChallengeToken)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// GetMyMFA - Whether the caller has MFA, and whether their role requires it
+func GetMyMFA(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	status, err := services.GetMFAStatus(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch MFA status"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollMFA - Starts authenticator setup for the signed-in user
+func EnrollMFA(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	enrollment, err := services.BeginEnrollment(user)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmMFA - Turns MFA on with a code from the new authenticator. The
+// recovery codes are only shown in this response.
+func ConfirmMFA(c *gin.Context) {
+	var input MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Code == "" {
+		respondFieldErrors(c, FieldErrors{"code": "enter the code from your authenticator app"})
+		return
+	}
+	userID, _ := currentUserID(c)
+	codes, err := services.ConfirmEnrollment(userID, input.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditUserMFAEnable, nil, gin.H{"user_id": userID}); err != nil {
+		log.Println("audit: failed to record MFA enrollment:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// RegenerateRecoveryCodes - Replaces the caller's recovery codes
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var input MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Code == "" {
+		respondFieldErrors(c, FieldErrors{"code": "enter the code from your authenticator app"})
+		return
+	}
+	userID, _ := currentUserID(c)
+	codes, err := services.RegenerateRecoveryCodes(userID, input.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditUserMFARecoveryCodes, nil, gin.H{"user_id": userID}); err != nil {
+		log.Println("audit: failed to record recovery code regeneration:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableMyMFA - Removes the caller's authenticator, confirmed with a code.
+// Not allowed when the caller's role requires MFA.
+func DisableMyMFA(c *gin.Context) {
+	var input MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil || (input.Code == "" && input.RecoveryCode == "") {
+		respondFieldErrors(c, FieldErrors{"code": "enter the code from your authenticator app or a recovery code"})
+		return
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if err := services.DisableMFA(user, input.Code, input.RecoveryCode); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditUserMFADisable, gin.H{"user_id": user.ID}, nil); err != nil {
+		log.Println("audit: failed to record MFA disable:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// ResetUserMFA - Admins remove a user's authenticator, e.g. after a lost
+// phone, and sign them out everywhere
+func ResetUserMFA(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	if _, err := repository.GetUserByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := services.ResetMFA(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset two-factor authentication"})
+		return
+	}
+	if err := repository.RevokeUserSessions(id, models.SessionAdminRevoked); err != nil {
+		log.Println("failed to revoke sessions after MFA reset:", err)
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditUserMFAReset, gin.H{"user_id": id}, nil); err != nil {
+		log.Println("audit: failed to record MFA reset:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+// UpdateRoleMFA - Requires, or stops requiring, MFA for a role. Members
+// without MFA are signed out and must enroll at their next sign-in.
+func UpdateRoleMFA(c *gin.Context) {
+	role := c.Param("role")
+	if !models.ValidRole(role) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown role", "allowed": models.Roles})
+		return
+	}
+	var input RoleMFAInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "required must be true or false"})
+		return
+	}
+
+	before, err := services.RoleMFAPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch MFA policy"})
+		return
+	}
+	policy, err := services.SetRoleMFARequired(role, *input.Required)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save MFA policy"})
+		return
+	}
+
+	change := func(required bool) gin.H { return gin.H{"role": role, "mfa_required": required} }
+	if err := services.RecordChange(auditActor(c), models.AuditRoleMFAUpdate, change(before[role]), change(policy.Required)); err != nil {
+		log.Println("audit: failed to record MFA policy update:", err)
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func currentUser(c *gin.Context) (models.User, bool) {
+	userID, _ := currentUserID(c)
+	user, err := repository.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return models.User{}, false
+	}
+	return user, true
+}
+
+func respondMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrLoginThrottled):
+		respondLoginError(c, err)
+	case errors.Is(err, repository.ErrMFAChallengeInvalid), errors.Is(err, services.ErrAccountDisabled):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": repository.ErrMFAChallengeInvalid.Error()})
+	case errors.Is(err, services.ErrMFACodeInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMFAAlreadyEnabled), errors.Is(err, services.ErrMFANotEnabled),
+		errors.Is(err, services.ErrMFARequiredForRole):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Two-factor authentication failed"})
+	}
+}
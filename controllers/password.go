@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ResetPasswordInput struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// GetPasswordPolicy - The rules a new password must meet, for forms
+func GetPasswordPolicy(c *gin.Context) {
+	c.JSON(http.StatusOK, services.GetPasswordRules())
+}
+
+// ChangeMyPassword - Self-service password change. Other devices are signed
+// out; this one stays signed in.
+func ChangeMyPassword(c *gin.Context) {
+	var input ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "current_password and new_password are required"})
+		return
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	sessionID, _ := c.Get("session_id")
+	keep, _ := sessionID.(uint)
+	if err := services.ChangePassword(user, input.CurrentPassword, input.NewPassword, c.ClientIP(), keep); err != nil {
+		var throttled *services.LoginThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			respondLoginError(c, err)
+		case errors.Is(err, services.ErrWrongPassword):
+			respondFieldErrors(c, FieldErrors{"current_password": err.Error()})
+		case errors.Is(err, services.ErrPasswordPolicy):
+			respondFieldErrors(c, FieldErrors{"new_password": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		}
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditUserPasswordChange, nil, gin.H{"user_id": user.ID}); err != nil {
+		log.Println("audit: failed to record password change:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
+// ResetPasswordWithToken - Sets a new password from a reset link and signs
+// the user out everywhere
+func ResetPasswordWithToken(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token and new_password are required"})
+		return
+	}
+
+	user, err := services.CompletePasswordReset(input.Token, input.NewPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrResetTokenInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrPasswordPolicy):
+			respondFieldErrors(c, FieldErrors{"new_password": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		}
+		return
+	}
+
+	actor := services.AuditActor{UserID: user.ID, Role: user.Role, ClientIP: c.ClientIP()}
+	if err := services.RecordChange(actor, models.AuditUserPasswordChange, nil, gin.H{"user_id": user.ID, "via": "reset_link"}); err != nil {
+		log.Println("audit: failed to record password reset:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated. Please log in."})
+}
@@ -1,72 +1,275 @@
 package controllers
 
 import (
+    "errors"
     "net/http"
     "strconv"
+    "time"
 
     "github.com/gin-gonic/gin"
+    "github.com/Sathwik-145/hospital-portal/config"
     "github.com/Sathwik-145/hospital-portal/models"
     "github.com/Sathwik-145/hospital-portal/repository"
+    "github.com/Sathwik-145/hospital-portal/services"
 )
 
 // CreatePatient - Only receptionists can create patients
 func CreatePatient(c *gin.Context) {
-    role := c.MustGet("role").(string)
-    if role != "receptionist" {
-        c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: only receptionists can create patients"})
+    var input CreatePatientInput
+    fields, ok := bindValidated(c, &input)
+    if !ok {
         return
     }
-
-    var p models.Patient
-    if err := c.ShouldBindJSON(&p); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+    if fields = fields.merge(input.validate(hasPermission(c, models.PermPatientClinicalWrite))); len(fields) > 0 {
+        respondFieldErrors(c, fields)
         return
     }
+    if input.HouseholdID != nil {
+        if _, err := repository.GetHousehold(int(*input.HouseholdID)); err != nil {
+            respondFieldErrors(c, FieldErrors{"household_id": "household does not exist"})
+            return
+        }
+    }
+    if input.PrimaryDoctorID != nil {
+        if !hasPermission(c, models.PermPatientAssign) {
+            respondFieldErrors(c, FieldErrors{"primary_doctor_id": "assigning a doctor needs the " + models.PermPatientAssign + " permission"})
+            return
+        }
+        if err := services.CheckPrimaryDoctor(*input.PrimaryDoctorID); err != nil {
+            respondFieldErrors(c, FieldErrors{"primary_doctor_id": err.Error()})
+            return
+        }
+    }
 
-    // Set default relationship if not provided
-    if p.Relationship == "" {
-        p.Relationship = "self"
+    // The patient, their first visit and their household are saved together
+    // so a failure part way leaves nothing behind
+    p := input.toModel()
+    encounter, hasEncounter := input.firstEncounter()
+    var created models.Patient
+    err := repository.Atomically(func(uow repository.UnitOfWork) error {
+        if err := uow.CreatePatient(&p); err != nil {
+            return err
+        }
+        // Clinical details given at registration become the first visit
+        if hasEncounter {
+            encounter.PatientID = p.ID
+            userID, _ := currentUserID(c)
+            if err := uow.CreateEncounter(&encounter, userID); err != nil {
+                return err
+            }
+        }
+        // A new "self" patient starts their own household unless they are
+        // joining an existing one
+        var err error
+        if input.HouseholdID != nil {
+            err = uow.AddHouseholdMember(*input.HouseholdID, p.ID, input.Relationship)
+        } else {
+            household := models.Household{Name: p.Name + " household", PhoneNumber: p.PhoneNumber}
+            err = uow.CreateHousehold(&household, p.ID)
+        }
+        if err != nil {
+            return err
+        }
+
+        // Audited in the same transaction, so nothing is saved unaudited
+        created, err = uow.GetPatientByID(int(p.ID))
+        if err != nil {
+            return err
+        }
+        if err := services.RecordPatientChangeIn(uow, auditActor(c), models.AuditPatientCreate, created.ID, nil, created); err != nil {
+            return err
+        }
+        if hasEncounter {
+            return services.RecordPatientChangeIn(uow, auditActor(c), models.AuditPatientEncounter, created.ID, nil, encounter)
+        }
+        return nil
+    })
+    if err != nil {
+        if input.HouseholdID != nil && repository.KindOf(err) == repository.ErrNotFound {
+            respondFieldErrors(c, FieldErrors{"household_id": "household does not exist"})
+            return
+        }
+        respondError(c, err, "Patient not found", "Failed to create patient")
+        return
     }
 
-    if err := repository.CreatePatient(p); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create patient"})
+    setPatientETag(c, created)
+    c.JSON(http.StatusCreated, projectFields(c, created))
+}
+
+// GetAllPatients - Lists patients one page at a time; roles limited to assigned patients only see theirs.
+// Query: limit, cursor, sort (name|created_at|updated_at), order (asc|desc), gender,
+// relationship, min_age, max_age, diagnosis, appointment_date (YYYY-MM-DD), include_history
+func GetAllPatients(c *gin.Context) {
+    query, err := parsePatientQuery(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    // Matching on diagnosis would reveal what the response leaves out
+    if query.Diagnosis != "" && !hasPermission(c, models.PermPatientClinicalRead) {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Filtering by diagnosis needs the " + models.PermPatientClinicalRead + " permission"})
         return
     }
+    // Doctors (by default) only list the patients assigned to them
+    scope, ok := patientScope(c)
+    if !ok {
+        return
+    }
+    query.AssignedTo = scope
 
-    created, err := repository.GetPatientByNameAndAge(p.Name, p.Age)
+    page, err := repository.ListPatients(query)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Patient created but failed to fetch data"})
+        respondError(c, err, "Patient not found", "Failed to fetch patients")
         return
     }
 
-    c.JSON(http.StatusCreated, created)
+    ids := make([]uint, len(page.Data))
+    for i, p := range page.Data {
+        ids[i] = p.ID
+    }
+    if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientList, ids...); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
+        return
+    }
+
+    c.JSON(http.StatusOK, projectFields(c, page))
 }
 
-// GetAllPatients - Receptionists and doctors can view all patients
-func GetAllPatients(c *gin.Context) {
-    role := c.MustGet("role").(string)
-    if role != "receptionist" && role != "doctor" {
-        c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: only receptionists or doctors can view patients"})
+const (
+    defaultPatientPageSize = 25
+    maxPatientPageSize     = 100
+)
+
+func parsePatientQuery(c *gin.Context) (repository.PatientQuery, error) {
+    q := repository.PatientQuery{
+        Limit:        defaultPatientPageSize,
+        Cursor:       c.Query("cursor"),
+        Sort:         c.DefaultQuery("sort", "created_at"),
+        Gender:       c.Query("gender"),
+        Relationship: c.Query("relationship"),
+        Diagnosis:    c.Query("diagnosis"),
+    }
+
+    if v := c.Query("limit"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 1 || n > maxPatientPageSize {
+            return q, errors.New("limit must be between 1 and 100")
+        }
+        q.Limit = n
+    }
+    if !repository.ValidPatientSort(q.Sort) {
+        return q, errors.New("sort must be one of name, created_at, updated_at")
+    }
+    switch c.DefaultQuery("order", "asc") {
+    case "asc":
+    case "desc":
+        q.Desc = true
+    default:
+        return q, errors.New("order must be asc or desc")
+    }
+
+    for param, dst := range map[string]**int{"min_age": &q.MinAge, "max_age": &q.MaxAge} {
+        if v := c.Query(param); v != "" {
+            n, err := strconv.Atoi(v)
+            if err != nil || n < 0 {
+                return q, errors.New("invalid " + param)
+            }
+            *dst = &n
+        }
+    }
+    if v := c.Query("appointment_date"); v != "" {
+        day, err := time.ParseInLocation("2006-01-02", v, time.Local)
+        if err != nil {
+            return q, errors.New("invalid appointment_date, expected YYYY-MM-DD")
+        }
+        q.AppointmentOn = day
+    }
+    if v := c.Query("include_history"); v != "" {
+        include, err := strconv.ParseBool(v)
+        if err != nil {
+            return q, errors.New("include_history must be true or false")
+        }
+        q.IncludeHistory = include
+    }
+    return q, nil
+}
+
+// UpdatePatient - Receptionists and doctors can update patient demographics;
+// visits are recorded with CreateEncounter. Send If-Match with the ETag from
+// GET to avoid overwriting someone else's changes.
+func UpdatePatient(c *gin.Context) {
+    idStr := c.Param("id")
+    id, err := strconv.Atoi(idStr)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
         return
     }
 
-    patients, err := repository.GetAllPatients()
+    var input UpdatePatientInput
+    fields, ok := bindValidated(c, &input)
+    if !ok {
+        return
+    }
+    if fields = fields.merge(input.validate(hasPermission(c, models.PermPatientClinicalWrite))); len(fields) > 0 {
+        respondFieldErrors(c, fields)
+        return
+    }
+
+    before, err := repository.GetPatientByID(id)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch patients"})
+        respondError(c, err, "Patient not found", "Failed to fetch patient")
+        return
+    }
+    version, ok := checkIfMatch(c, before)
+    if !ok {
+        return
+    }
+    // Clinical fields follow the latest encounter; clients that send the
+    // whole patient back may repeat them but not change them here
+    if fields = input.clinicalChanges(before); len(fields) > 0 {
+        respondFieldErrors(c, fields)
         return
     }
 
-    c.JSON(http.StatusOK, patients)
+    savePatientUpdate(c, before, input, version)
 }
 
-// UpdatePatient - Receptionists and doctors can update patient info
-func UpdatePatient(c *gin.Context) {
-    role := c.MustGet("role").(string)
-    if role != "receptionist" && role != "doctor" {
-        c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: only receptionists or doctors can update patients"})
+// savePatientUpdate finishes PUT and PATCH: saves and audits in one
+// transaction and answers with the updated patient and its new ETag
+func savePatientUpdate(c *gin.Context, before models.Patient, input UpdatePatientInput, version uint) {
+    id := int(before.ID)
+    var updated models.Patient
+    err := repository.Atomically(func(uow repository.UnitOfWork) error {
+        if err := uow.UpdatePatient(id, input.toModel(), version); err != nil {
+            return err
+        }
+        var err error
+        updated, err = uow.GetPatientByID(id)
+        if err != nil {
+            return err
+        }
+        return services.RecordPatientChangeIn(uow, auditActor(c), models.AuditPatientUpdate, updated.ID, before, updated)
+    })
+    if err != nil {
+        if errors.Is(err, repository.ErrVersionConflict) {
+            respondVersionConflict(c, id, version != 0)
+            return
+        }
+        respondError(c, err, "Patient not found", "Failed to update patient")
         return
     }
 
+    setPatientETag(c, updated)
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Patient updated successfully",
+        "patient": projectFields(c, updated),
+    })
+}
+
+// DeletePatient - Only receptionists can delete patients. The record is archived, not destroyed;
+// an optional reason can be sent as JSON {"reason": "..."} or ?reason=
+func DeletePatient(c *gin.Context) {
     idStr := c.Param("id")
     id, err := strconv.Atoi(idStr)
     if err != nil {
@@ -74,63 +277,130 @@ func UpdatePatient(c *gin.Context) {
         return
     }
 
-    var p models.Patient
-    if err := c.ShouldBindJSON(&p); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+    before, err := repository.GetPatientByID(id)
+    if err != nil {
+        respondError(c, err, "Patient not found", "Failed to fetch patient")
         return
     }
 
-    // Set the ID for the update
-    p.ID = uint(id)
+    var input struct {
+        Reason string `json:"reason"`
+    }
+    _ = c.ShouldBindJSON(&input)
+    if input.Reason == "" {
+        input.Reason = c.Query("reason")
+    }
 
-    if err := repository.UpdatePatient(id, p); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update patient"})
+    userID, _ := currentUserID(c)
+    err = repository.Atomically(func(uow repository.UnitOfWork) error {
+        if err := uow.DeletePatient(id, userID, input.Reason); err != nil {
+            return err
+        }
+        after, err := uow.GetPatientIncludingArchived(id)
+        if err != nil {
+            return err
+        }
+        return services.RecordPatientChangeIn(uow, auditActor(c), models.AuditPatientDelete, before.ID, before, after)
+    })
+    if err != nil {
+        respondError(c, err, "Patient not found", "Failed to delete patient")
         return
     }
 
-    updated, err := repository.GetPatientByID(id)
+    c.JSON(http.StatusOK, gin.H{"message": "Patient archived successfully"})
+}
+
+// RestorePatient - Only receptionists can restore archived patients
+func RestorePatient(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+        return
+    }
+
+    before, err := repository.GetPatientIncludingArchived(id)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Updated patient but fetch failed"})
+        respondError(c, err, "Patient not found", "Failed to fetch patient")
+        return
+    }
+
+    var restored models.Patient
+    err = repository.Atomically(func(uow repository.UnitOfWork) error {
+        if err := uow.RestorePatient(id); err != nil {
+            return err
+        }
+        var err error
+        restored, err = uow.GetPatientByID(id)
+        if err != nil {
+            return err
+        }
+        return services.RecordPatientChangeIn(uow, auditActor(c), models.AuditPatientRestore, restored.ID, before, restored)
+    })
+    if err != nil {
+        respondError(c, err, "Patient not found", "Failed to restore patient")
         return
     }
 
     c.JSON(http.StatusOK, gin.H{
-        "message": "Patient updated successfully",
-        "patient": updated,
+        "message": "Patient restored successfully",
+        "patient": projectFields(c, restored),
     })
 }
 
-// DeletePatient - Only receptionists can delete patients
-func DeletePatient(c *gin.Context) {
-    role := c.MustGet("role").(string)
-    if role != "receptionist" {
-        c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: only receptionists can delete patients"})
+// GetArchivedPatients - Archived patients, which the normal list excludes
+func GetArchivedPatients(c *gin.Context) {
+    scope, ok := patientScope(c)
+    if !ok {
+        return
+    }
+    patients, err := repository.GetArchivedPatients(scope)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch archived patients"})
         return
     }
 
-    idStr := c.Param("id")
-    id, err := strconv.Atoi(idStr)
+    ids := make([]uint, len(patients))
+    for i, p := range patients {
+        ids[i] = p.ID
+    }
+    if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientList, ids...); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
+        return
+    }
+
+    c.JSON(http.StatusOK, projectFields(c, patients))
+}
+
+// PurgePatient - Admin-only permanent removal of an archived patient past the retention period
+func PurgePatient(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
         return
     }
 
-    if err := repository.DeletePatient(id); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete patient"})
+    before, err := repository.GetPatientIncludingArchived(id)
+    if err != nil {
+        respondError(c, err, "Patient not found", "Failed to fetch patient")
+        return
+    }
+
+    err = repository.Atomically(func(uow repository.UnitOfWork) error {
+        if err := uow.PurgePatient(id, config.RecordRetention()); err != nil {
+            return err
+        }
+        return services.RecordPatientChangeIn(uow, auditActor(c), models.AuditPatientPurge, before.ID, before, nil)
+    })
+    if err != nil {
+        respondError(c, err, "Patient not found", "Failed to purge patient")
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"message": "Patient deleted successfully"})
+    c.JSON(http.StatusOK, gin.H{"message": "Patient purged permanently"})
 }
 
 // GetPatientHistory - Get patient with medical history
 func GetPatientHistory(c *gin.Context) {
-    role := c.MustGet("role").(string)
-    if role != "receptionist" && role != "doctor" {
-        c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: only receptionists or doctors can view patient history"})
-        return
-    }
-
     idStr := c.Param("id")
     id, err := strconv.Atoi(idStr)
     if err != nil {
@@ -138,66 +408,99 @@ func GetPatientHistory(c *gin.Context) {
         return
     }
 
-    patient, err := repository.GetPatientWithHistory(id)
+    // Optional filter: only visits recorded by this doctor
+    var doctorID uint
+    if v := c.Query("doctor_id"); v != "" {
+        parsed, err := strconv.Atoi(v)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor_id"})
+            return
+        }
+        doctorID = uint(parsed)
+    }
+
+    patient, err := repository.GetPatientWithHistory(id, doctorID)
     if err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+        respondError(c, err, "Patient not found", "Failed to fetch patient")
+        return
+    }
+
+    if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientHistoryRead, patient.ID); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
         return
     }
 
     c.JSON(http.StatusOK, patient)
 }
 
-// GetFamilyHistoryByPhone - Get complete family medical history by phone number
-func GetFamilyHistoryByPhone(c *gin.Context) {
-    role := c.MustGet("role").(string)
-    if role != "receptionist" && role != "doctor" {
-        c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: only receptionists or doctors can view patient history"})
+// GetHistoryByDoctor - Visits recorded by a doctor (defaults to the caller when they are a doctor).
+// Optional from/to dates as YYYY-MM-DD
+func GetHistoryByDoctor(c *gin.Context) {
+    var doctorID uint
+    if v := c.Query("doctor_id"); v != "" {
+        parsed, err := strconv.Atoi(v)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor_id"})
+            return
+        }
+        doctorID = uint(parsed)
+    } else if c.GetString("role") == models.RoleDoctor {
+        doctorID, _ = currentUserID(c)
+    }
+    if doctorID == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "doctor_id is required"})
         return
     }
 
-    phoneNumber := c.Param("phone")
-    if phoneNumber == "" {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Phone number is required"})
-        return
+    var from, to time.Time
+    if v := c.Query("from"); v != "" {
+        parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, expected YYYY-MM-DD"})
+            return
+        }
+        from = parsed
+    }
+    if v := c.Query("to"); v != "" {
+        parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, expected YYYY-MM-DD"})
+            return
+        }
+        to = parsed.AddDate(0, 0, 1)
     }
 
-    // Get complete family history by phone number
-    history, err := repository.GetFamilyHistoryByPhone(phoneNumber)
+    history, err := repository.GetHistoryByDoctor(doctorID, from, to)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch family history"})
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
         return
     }
 
-    // Get all family members with this phone number
-    familyMembers, err := repository.GetFamilyMembersByPhone(phoneNumber)
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch family members"})
+    // A doctor's own visits are always theirs to see; another doctor's are
+    // limited to the caller's patients when their role is restricted
+    scope, ok := patientScope(c)
+    if !ok {
         return
     }
+    if scope != 0 && scope != doctorID {
+        allowed, err := repository.FilterAssignedPatients(scope, historyPatientIDs(history))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check patient access"})
+            return
+        }
+        history = visitsForPatients(history, allowed)
+    }
 
-    // Get family visit summary
-    summary, err := repository.GetFamilyVisitSummary(phoneNumber)
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get family summary"})
+    if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientHistoryRead, historyPatientIDs(history)...); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{
-        "phone_number":     phoneNumber,
-        "family_summary":   summary,
-        "medical_history":  history,
-        "family_members":   familyMembers,
-    })
+    c.JSON(http.StatusOK, history)
 }
 
 // GetPatient - Get single patient by ID
 func GetPatient(c *gin.Context) {
-    role := c.MustGet("role").(string)
-    if role != "receptionist" && role != "doctor" {
-        c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: only receptionists or doctors can view patients"})
-        return
-    }
-
     idStr := c.Param("id")
     id, err := strconv.Atoi(idStr)
     if err != nil {
@@ -207,9 +510,43 @@ func GetPatient(c *gin.Context) {
 
     patient, err := repository.GetPatientByID(id)
     if err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+        respondError(c, err, "Patient not found", "Failed to fetch patient")
         return
     }
 
-    c.JSON(http.StatusOK, patient)
+    if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientRead, patient.ID); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
+        return
+    }
+
+    setPatientETag(c, patient)
+    c.JSON(http.StatusOK, projectFields(c, patient))
+}
+
+// visitsForPatients keeps the visits of the given patients
+func visitsForPatients(history []models.MedicalHistory, patientIDs []uint) []models.MedicalHistory {
+    keep := map[uint]bool{}
+    for _, id := range patientIDs {
+        keep[id] = true
+    }
+    visits := make([]models.MedicalHistory, 0, len(history))
+    for _, h := range history {
+        if keep[h.PatientID] {
+            visits = append(visits, h)
+        }
+    }
+    return visits
+}
+
+// historyPatientIDs returns the distinct patients referenced by visits
+func historyPatientIDs(history []models.MedicalHistory) []uint {
+    seen := map[uint]bool{}
+    var ids []uint
+    for _, h := range history {
+        if !seen[h.PatientID] {
+            seen[h.PatientID] = true
+            ids = append(ids, h.PatientID)
+        }
+    }
+    return ids
 }
\ No newline at end of file
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+// Oldest date of birth we accept
+const maxPatientAgeYears = 130
+
+// CreatePatientInput is the registration payload for POST /api/patients
+type CreatePatientInput struct {
+	Name         string      `json:"name" binding:"required,max=100"`
+	DateOfBirth  models.Date `json:"date_of_birth"`
+	Gender       string      `json:"gender" binding:"required,oneof=male female other unknown"`
+	PhoneNumber  string      `json:"phone_number" binding:"required,e164"`
+	Relationship string      `json:"relationship" binding:"omitempty,relationship"`
+	// Join an existing household instead of starting a new one
+	HouseholdID *uint `json:"household_id"`
+	// Needs patient.assign
+	PrimaryDoctorID *uint `json:"primary_doctor_id"`
+	// Clinical fields; need patient.clinical.write and are recorded as the
+	// patient's first encounter
+	Diagnosis     string `json:"diagnosis"`
+	MedicalNotes  string `json:"medical_notes"`
+	Prescriptions string `json:"prescriptions"`
+}
+
+// UpdatePatientInput is the payload for PUT /api/patients/:id. Relationship
+// is managed through households, appointments drive the checkup fields.
+type UpdatePatientInput struct {
+	Name        string      `json:"name" binding:"required,max=100"`
+	DateOfBirth models.Date `json:"date_of_birth"`
+	Gender      string      `json:"gender" binding:"required,oneof=male female other unknown"`
+	PhoneNumber string      `json:"phone_number" binding:"required,e164"`
+	// Clinical fields are recorded with POST /api/patients/:id/encounters.
+	// They are accepted here only unchanged, for clients that send the
+	// whole patient back.
+	Diagnosis     string `json:"diagnosis"`
+	MedicalNotes  string `json:"medical_notes"`
+	Prescriptions string `json:"prescriptions"`
+}
+
+func (in *CreatePatientInput) normalize() {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Gender = strings.ToLower(strings.TrimSpace(in.Gender))
+	in.PhoneNumber = normalizePhone(in.PhoneNumber)
+	in.Relationship = strings.ToLower(strings.TrimSpace(in.Relationship))
+	if in.Relationship == "" {
+		in.Relationship = "self"
+	}
+}
+
+func (in *UpdatePatientInput) normalize() {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Gender = strings.ToLower(strings.TrimSpace(in.Gender))
+	in.PhoneNumber = normalizePhone(in.PhoneNumber)
+}
+
+// validate runs the checks binding tags can't express
+func (in CreatePatientInput) validate(clinical bool) FieldErrors {
+	fields := FieldErrors{}
+	if in.DateOfBirth.IsZero() {
+		fields["date_of_birth"] = "is required"
+	} else if msg := checkDateOfBirth(in.DateOfBirth); msg != "" {
+		fields["date_of_birth"] = msg
+	}
+	if in.HouseholdID != nil && in.Relationship == "self" {
+		fields["relationship"] = "must describe the relationship to the head when joining a household"
+	}
+	if !clinical {
+		fields = fields.merge(clinicalFieldErrors(in.Diagnosis, in.MedicalNotes, in.Prescriptions))
+	}
+	return fields
+}
+
+func (in UpdatePatientInput) validate(clinical bool) FieldErrors {
+	fields := FieldErrors{}
+	if !in.DateOfBirth.IsZero() {
+		if msg := checkDateOfBirth(in.DateOfBirth); msg != "" {
+			fields["date_of_birth"] = msg
+		}
+	}
+	if !clinical {
+		fields = fields.merge(clinicalFieldErrors(in.Diagnosis, in.MedicalNotes, in.Prescriptions))
+	}
+	return fields
+}
+
+// clinicalChanges rejects clinical fields that differ from the patient's
+// current ones; those changes need an encounter
+func (in UpdatePatientInput) clinicalChanges(current models.Patient) FieldErrors {
+	fields := FieldErrors{}
+	for name, values := range map[string][2]string{
+		"diagnosis":     {in.Diagnosis, current.Diagnosis},
+		"medical_notes": {in.MedicalNotes, current.MedicalNotes},
+		"prescriptions": {in.Prescriptions, current.Prescriptions},
+	} {
+		if values[0] != "" && values[0] != values[1] {
+			fields[name] = "is recorded with an encounter: POST /api/patients/:id/encounters"
+		}
+	}
+	return fields
+}
+
+// clinicalFieldErrors rejects clinical fields sent by callers without
+// patient.clinical.write
+func clinicalFieldErrors(diagnosis, medicalNotes, prescriptions string) FieldErrors {
+	fields := FieldErrors{}
+	for name, value := range map[string]string{
+		"diagnosis":     diagnosis,
+		"medical_notes": medicalNotes,
+		"prescriptions": prescriptions,
+	} {
+		if strings.TrimSpace(value) != "" {
+			fields[name] = "clinical fields can only be recorded by clinical staff"
+		}
+	}
+	return fields
+}
+
+func (in CreatePatientInput) toModel() models.Patient {
+	return models.Patient{
+		Name:            in.Name,
+		DateOfBirth:     in.DateOfBirth,
+		Gender:          in.Gender,
+		PhoneNumber:     in.PhoneNumber,
+		Relationship:    in.Relationship,
+		PrimaryDoctorID: in.PrimaryDoctorID,
+	}
+}
+
+// firstEncounter returns the clinical fields as an encounter, if any were sent
+func (in CreatePatientInput) firstEncounter() (models.MedicalHistory, bool) {
+	e := models.MedicalHistory{
+		Diagnosis:     strings.TrimSpace(in.Diagnosis),
+		MedicalNotes:  strings.TrimSpace(in.MedicalNotes),
+		Prescriptions: strings.TrimSpace(in.Prescriptions),
+	}
+	return e, e.Diagnosis != "" || e.MedicalNotes != "" || e.Prescriptions != ""
+}
+
+func (in UpdatePatientInput) toModel() models.Patient {
+	return models.Patient{
+		Name:        in.Name,
+		DateOfBirth: in.DateOfBirth,
+		Gender:      in.Gender,
+		PhoneNumber: in.PhoneNumber,
+	}
+}
+
+func checkDateOfBirth(dob models.Date) string {
+	now := time.Now()
+	if dob.After(now) {
+		return "cannot be in the future"
+	}
+	if dob.Before(now.AddDate(-maxPatientAgeYears, 0, 0)) {
+		return "is too far in the past"
+	}
+	return ""
+}
+
+// normalizePhone strips formatting and applies DEFAULT_COUNTRY_CODE to
+// local numbers, so "098765 43210" can still validate as E.164.
+func normalizePhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + phone[2:]
+	}
+	plus := strings.HasPrefix(phone, "+")
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+	if plus {
+		return "+" + digits
+	}
+	if code := config.DefaultCountryCode(); code != "" && digits != "" {
+		return code + strings.TrimLeft(digits, "0")
+	}
+	return digits
+}
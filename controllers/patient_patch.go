@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+// Largest merge patch we read; a patient's details are far smaller
+const maxPatchBytes = 64 << 10
+
+// PatchPatient - Partial update with JSON Merge Patch (RFC 7396): fields left
+// out keep their value, null clears one. Demographics only, like PUT, and
+// If-Match is honoured the same way.
+func PatchPatient(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Send the patch as application/merge-patch+json"})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPatchBytes+1))
+	if err != nil || len(body) > maxPatchBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patch"})
+		return
+	}
+	var patch map[string]interface{}
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The patch must be a JSON object"})
+		return
+	}
+
+	before, err := repository.GetPatientByID(id)
+	if err != nil {
+		respondError(c, err, "Patient not found", "Failed to fetch patient")
+		return
+	}
+	version, ok := checkIfMatch(c, before)
+	if !ok {
+		return
+	}
+
+	// The date of birth can be corrected but not removed
+	if v, sent := patch["date_of_birth"]; sent && v == nil {
+		respondFieldErrors(c, FieldErrors{"date_of_birth": "cannot be removed"})
+		return
+	}
+
+	current := map[string]interface{}{
+		"name":          before.Name,
+		"date_of_birth": before.DateOfBirth,
+		"gender":        before.Gender,
+		"phone_number":  before.PhoneNumber,
+	}
+	merged, err := json.Marshal(mergePatch(current, patch))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply patch"})
+		return
+	}
+	var input UpdatePatientInput
+	dec := json.NewDecoder(bytes.NewReader(merged))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patch: " + err.Error()})
+		return
+	}
+
+	fields, ok := validateInput(c, &input)
+	if !ok {
+		return
+	}
+	fields = fields.merge(input.validate(hasPermission(c, models.PermPatientClinicalWrite)))
+	if fields = fields.merge(input.clinicalChanges(before)); len(fields) > 0 {
+		respondFieldErrors(c, fields)
+		return
+	}
+	// A zero date keeps the stored one, and whether it was estimated
+	if _, sent := patch["date_of_birth"]; !sent {
+		input.DateOfBirth = models.Date{}
+	}
+
+	savePatientUpdate(c, before, input, version)
+}
+
+// mergePatch applies an RFC 7396 merge patch to target
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
+// patientETag is the entity tag of a patient's current version
+func patientETag(p models.Patient) string {
+	return `"` + strconv.FormatUint(uint64(p.Version), 10) + `"`
+}
+
+func setPatientETag(c *gin.Context, p models.Patient) {
+	c.Header("ETag", patientETag(p))
+}
+
+// checkIfMatch returns the version an If-Match header asks to update, or 0
+// when there is none. It answers 412 itself when the patient has moved on.
+func checkIfMatch(c *gin.Context, current models.Patient) (uint, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return 0, true
+	}
+	if header == "*" {
+		return current.Version, true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == patientETag(current) {
+			return current.Version, true
+		}
+	}
+	respondStalePatient(c, http.StatusPreconditionFailed, current)
+	return 0, false
+}
+
+// respondVersionConflict answers when the patient changed while an update
+// was being saved: 412 if the client sent If-Match, 409 otherwise
+func respondVersionConflict(c *gin.Context, id int, preconditioned bool) {
+	current, err := repository.GetPatientByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update patient"})
+		return
+	}
+	status := http.StatusConflict
+	if preconditioned {
+		status = http.StatusPreconditionFailed
+	}
+	respondStalePatient(c, status, current)
+}
+
+// respondStalePatient sends the current patient so the client can merge
+// its changes and retry with the new ETag
+func respondStalePatient(c *gin.Context, status int, current models.Patient) {
+	setPatientETag(c, current)
+	c.JSON(status, gin.H{
+		"error":   repository.ErrVersionConflict.Error(),
+		"code":    "version_conflict",
+		"patient": projectFields(c, current),
+	})
+}
@@ -0,0 +1,340 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+const (
+	defaultMedicationLimit = 20
+	maxMedicationLimit     = 100
+	// Upper bound for an uploaded catalog or rules file
+	maxUploadBytes = 10 << 20
+)
+
+// PrescriptionInput is the payload for POST /api/patients/:id/prescriptions.
+// Either medication_id (catalog entry) or drug (free text) is required.
+type PrescriptionInput struct {
+	VisitID      *uint       `json:"visit_id"`
+	MedicationID *uint       `json:"medication_id"`
+	Drug         string      `json:"drug" binding:"max=200"`
+	Strength     string      `json:"strength" binding:"max=50"`
+	Form         string      `json:"form" binding:"max=50"`
+	Dose         string      `json:"dose" binding:"required,max=100"`
+	Route        string      `json:"route" binding:"max=50"`
+	Frequency    string      `json:"frequency" binding:"required,max=100"`
+	DurationDays int         `json:"duration_days" binding:"gte=0,lte=3650"`
+	Quantity     int         `json:"quantity" binding:"gte=0"`
+	Refills      int         `json:"refills" binding:"gte=0,lte=12"`
+	Instructions string      `json:"instructions" binding:"max=1000"`
+	StartDate    models.Date `json:"start_date"`
+	// Required to go ahead when the safety check returns a blocking warning
+	OverrideReason string `json:"override_reason" binding:"max=500"`
+}
+
+type DiscontinueInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+func (in *PrescriptionInput) normalize() {
+	in.Drug = strings.TrimSpace(in.Drug)
+	in.Strength = strings.TrimSpace(in.Strength)
+	in.Form = strings.ToLower(strings.TrimSpace(in.Form))
+	in.Dose = strings.TrimSpace(in.Dose)
+	in.Route = strings.ToLower(strings.TrimSpace(in.Route))
+	in.Frequency = strings.TrimSpace(in.Frequency)
+	in.Instructions = strings.TrimSpace(in.Instructions)
+	in.OverrideReason = strings.TrimSpace(in.OverrideReason)
+}
+
+func (in PrescriptionInput) validate() FieldErrors {
+	fields := FieldErrors{}
+	if in.MedicationID == nil && in.Drug == "" {
+		fields["drug"] = "is required when medication_id is not given"
+	}
+	return fields
+}
+
+func (in PrescriptionInput) toModel(patientID, prescriberID uint) models.Prescription {
+	return models.Prescription{
+		PatientID:    patientID,
+		VisitID:      in.VisitID,
+		MedicationID: in.MedicationID,
+		Drug:         in.Drug,
+		Strength:     in.Strength,
+		Form:         in.Form,
+		Dose:         in.Dose,
+		Route:        in.Route,
+		Frequency:    in.Frequency,
+		DurationDays: in.DurationDays,
+		Quantity:     in.Quantity,
+		Refills:      in.Refills,
+		Instructions: in.Instructions,
+		PrescriberID: prescriberID,
+		StartDate:    in.StartDate,
+	}
+}
+
+// CreatePrescription - Only doctors can prescribe
+func CreatePrescription(c *gin.Context) {
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	var input PrescriptionInput
+	fields, ok := bindValidated(c, &input)
+	if !ok {
+		return
+	}
+	if fields = fields.merge(input.validate()); len(fields) > 0 {
+		respondFieldErrors(c, fields)
+		return
+	}
+
+	prescription := input.toModel(uint(patientID), userID)
+	check, err := services.CheckPrescription(uint(patientID), prescription)
+	if err != nil {
+		respondPrescriptionError(c, err, "Failed to check allergies and interactions")
+		return
+	}
+	if check.Blocked {
+		if input.OverrideReason == "" {
+			respondSafetyBlocked(c, check)
+			return
+		}
+		prescription.OverrideReason = input.OverrideReason
+	}
+
+	err = repository.Atomically(func(uow repository.UnitOfWork) error {
+		if err := uow.CreatePrescription(&prescription); err != nil {
+			return err
+		}
+		return services.RecordPatientChangeIn(uow, auditActor(c), models.AuditPrescriptionCreate, prescription.PatientID, nil, prescription)
+	})
+	if err != nil {
+		respondPrescriptionError(c, err, "Failed to create prescription")
+		return
+	}
+
+	created, err := repository.GetPrescriptionByID(int(prescription.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Prescription created but failed to fetch data"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"prescription": created,
+		"warnings":     check.Warnings,
+	})
+}
+
+// CheckPrescription - Dry run of the allergy and interaction check for a
+// prescription payload; nothing is saved
+func CheckPrescription(c *gin.Context) {
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var input PrescriptionInput
+	fields, ok := bindValidated(c, &input)
+	if !ok {
+		return
+	}
+	if fields = fields.merge(input.validate()); len(fields) > 0 {
+		respondFieldErrors(c, fields)
+		return
+	}
+
+	if _, err := repository.GetPatientByID(patientID); err != nil {
+		respondError(c, err, "Patient not found", "Failed to fetch patient")
+		return
+	}
+	check, err := services.CheckPrescription(uint(patientID), input.toModel(uint(patientID), 0))
+	if err != nil {
+		respondPrescriptionError(c, err, "Failed to check allergies and interactions")
+		return
+	}
+	c.JSON(http.StatusOK, check)
+}
+
+// DiscontinuePrescription - Doctors can stop an active prescription, with a reason
+func DiscontinuePrescription(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prescription ID"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	var input DiscontinueInput
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required to discontinue a prescription"})
+		return
+	}
+
+	before, err := repository.GetPrescriptionByID(id)
+	if err != nil {
+		respondPrescriptionError(c, err, "Failed to fetch prescription")
+		return
+	}
+	if !requirePatientAccess(c, before.PatientID) {
+		return
+	}
+	var updated models.Prescription
+	err = repository.Atomically(func(uow repository.UnitOfWork) error {
+		var err error
+		updated, err = uow.DiscontinuePrescription(id, userID, strings.TrimSpace(input.Reason))
+		if err != nil {
+			return err
+		}
+		return services.RecordPatientChangeIn(uow, auditActor(c), models.AuditPrescriptionDiscontinue, updated.PatientID, before, updated)
+	})
+	if err != nil {
+		respondPrescriptionError(c, err, "Failed to discontinue prescription")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Prescription discontinued",
+		"prescription": updated,
+	})
+}
+
+// GetPatientMedications - Active medications for a patient; ?status=all includes
+// discontinued and finished courses
+func GetPatientMedications(c *gin.Context) {
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	activeOnly := true
+	switch c.DefaultQuery("status", "active") {
+	case "active":
+	case "all":
+		activeOnly = false
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active or all"})
+		return
+	}
+
+	if _, err := repository.GetPatientByID(patientID); err != nil {
+		respondError(c, err, "Patient not found", "Failed to fetch patient")
+		return
+	}
+
+	prescriptions, err := repository.GetPatientPrescriptions(patientID, activeOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch medications"})
+		return
+	}
+
+	if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientMedicationsRead, uint(patientID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"patient_id":    patientID,
+		"prescriptions": prescriptions,
+	})
+}
+
+// SearchMedications - Look up the medication catalog by name, generic name or code
+func SearchMedications(c *gin.Context) {
+	limit := defaultMedicationLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+	if limit > maxMedicationLimit {
+		limit = maxMedicationLimit
+	}
+
+	meds, err := repository.SearchMedications(c.Query("q"), c.Query("include_inactive") == "true", limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search medications"})
+		return
+	}
+	c.JSON(http.StatusOK, meds)
+}
+
+// ImportMedications - Admin-only catalog import. Accepts a multipart "file"
+// field or a raw text/csv body.
+func ImportMedications(c *gin.Context) {
+	body, closeBody, ok := uploadBody(c)
+	if !ok {
+		return
+	}
+	defer closeBody()
+
+	meds, err := services.ParseMedicationCSV(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSV: " + err.Error()})
+		return
+	}
+
+	affected, err := repository.ImportMedications(meds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import medications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Medication catalog imported",
+		"rows":     len(meds),
+		"affected": affected,
+	})
+}
+
+// uploadBody returns the uploaded file from a multipart "file" field, or the
+// raw request body otherwise. Uploads are capped at maxUploadBytes.
+func uploadBody(c *gin.Context) (io.Reader, func() error, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadBytes)
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return c.Request.Body, func() error { return nil }, true
+	}
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing upload in field \"file\""})
+		return nil, nil, false
+	}
+	return file, file.Close, true
+}
+
+// respondSafetyBlocked reports blocking allergy or interaction warnings
+func respondSafetyBlocked(c *gin.Context, check services.SafetyCheck) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":    "Prescription conflicts with a documented allergy or active medication; resend with override_reason to proceed",
+		"warnings": check.Warnings,
+	})
+}
+
+func respondPrescriptionError(c *gin.Context, err error, fallback string) {
+	respondError(c, err, "Patient, visit, medication or prescription not found", fallback)
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+type RolePermissionsInput struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// GetRolePermissions - The permission catalog, what each role is granted and
+// which roles require MFA
+func GetRolePermissions(c *gin.Context) {
+	matrix, err := services.RolePermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch role permissions"})
+		return
+	}
+	mfa, err := services.RoleMFAPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch MFA policy"})
+		return
+	}
+	scopes, err := services.RolePatientScopes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch patient scope"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"roles":         matrix,
+		"permissions":   models.Permissions,
+		"mfa_required":  mfa,
+		"assigned_only": scopes,
+	})
+}
+
+// UpdateRolePermissions - Replaces the permissions granted to one role
+func UpdateRolePermissions(c *gin.Context) {
+	role := c.Param("role")
+	if !models.ValidRole(role) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown role", "allowed": models.Roles})
+		return
+	}
+
+	var input RolePermissionsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	before, err := services.RolePermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch role permissions"})
+		return
+	}
+
+	granted, err := services.SetRolePermissions(role, input.Permissions)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownPermission):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAdminLockout):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save role permissions"})
+		}
+		return
+	}
+
+	change := func(permissions []string) gin.H { return gin.H{"role": role, "permissions": permissions} }
+	if err := services.RecordChange(auditActor(c), models.AuditRolePermissionsUpdate, change(before[role]), change(granted)); err != nil {
+		log.Println("audit: failed to record role permissions update:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"role": role, "permissions": granted})
+}
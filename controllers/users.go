@@ -0,0 +1,452 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+const (
+	defaultUserLimit = 50
+	maxUserLimit     = 200
+)
+
+type CreateUserInput struct {
+	Name               string `json:"name" binding:"required,max=100"`
+	Email              string `json:"email" binding:"required,email"`
+	Password           string `json:"password" binding:"required"`
+	Role               string `json:"role" binding:"required"`
+	RegistrationNumber string `json:"registration_number" binding:"max=50"`
+}
+
+type InviteInput struct {
+	Email              string `json:"email" binding:"required,email"`
+	Name               string `json:"name" binding:"max=100"`
+	Role               string `json:"role" binding:"required"`
+	RegistrationNumber string `json:"registration_number" binding:"max=50"`
+}
+
+type UserRoleInput struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type PasswordResetInput struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// ListUsers - Staff accounts. Query: role, status (active|disabled), q, limit, offset
+func ListUsers(c *gin.Context) {
+	filter := repository.UserFilter{
+		Role:   c.Query("role"),
+		Query:  strings.TrimSpace(c.Query("q")),
+		Limit:  defaultUserLimit,
+		Offset: 0,
+	}
+	switch c.Query("status") {
+	case "":
+	case "active":
+		disabled := false
+		filter.Disabled = &disabled
+	case "disabled":
+		disabled := true
+		filter.Disabled = &disabled
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active or disabled"})
+		return
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		filter.Limit = n
+	}
+	if filter.Limit > maxUserLimit {
+		filter.Limit = maxUserLimit
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+			return
+		}
+		filter.Offset = n
+	}
+
+	users, total, err := repository.ListUsers(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":  users,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// GetUser - One staff account
+func GetUser(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	user, err := repository.GetUserByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// CreateUser - Admins create an account directly
+func CreateUser(c *gin.Context) {
+	var input CreateUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	user, err := services.CreateUser(services.NewUser{
+		Name:               input.Name,
+		Email:              input.Email,
+		Password:           input.Password,
+		Role:               input.Role,
+		RegistrationNumber: input.RegistrationNumber,
+		MustChangePassword: true,
+	})
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditUserCreate, nil, user); err != nil {
+		log.Println("audit: failed to record user create:", err)
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUserRole - Moves a user to another role
+func UpdateUserRole(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	var input UserRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if !models.ValidRole(input.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role", "allowed": models.Roles})
+		return
+	}
+
+	before, err := repository.GetUserByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	user, err := repository.UpdateUserRole(id, input.Role)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditUserRoleChange, before, user); err != nil {
+		log.Println("audit: failed to record role change:", err)
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DisableUser - Blocks sign-in and ends the user's sessions
+func DisableUser(c *gin.Context) {
+	setUserDisabled(c, true)
+}
+
+// EnableUser - Re-enables a disabled account
+func EnableUser(c *gin.Context) {
+	setUserDisabled(c, false)
+}
+
+func setUserDisabled(c *gin.Context, disabled bool) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	if self, _ := currentUserID(c); disabled && self == id {
+		c.JSON(http.StatusConflict, gin.H{"error": "You cannot disable your own account"})
+		return
+	}
+
+	before, err := repository.GetUserByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	user, err := repository.SetUserDisabled(id, disabled)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	if disabled {
+		if err := repository.RevokeUserSessions(id, models.SessionAdminRevoked); err != nil {
+			log.Println("failed to revoke sessions of disabled user:", err)
+		}
+	}
+
+	action := models.AuditUserEnable
+	if disabled {
+		action = models.AuditUserDisable
+	}
+	if err := services.RecordChange(auditActor(c), action, before, user); err != nil {
+		log.Println("audit: failed to record user status change:", err)
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ResetUserPassword - Admins set a temporary password for a user, who must
+// change it at their next sign-in
+func ResetUserPassword(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	var input PasswordResetInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := services.ResetPassword(id, input.Password); err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	target := gin.H{"user_id": id}
+	if err := services.RecordChange(auditActor(c), models.AuditUserPasswordReset, nil, target); err != nil {
+		log.Println("audit: failed to record password reset:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Temporary password set; the user must change it at next sign-in"})
+}
+
+// SendPasswordReset - Emails the user a single-use link to choose a new
+// password. The link is never shown to the admin.
+func SendPasswordReset(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	requestedBy, _ := currentUserID(c)
+
+	reset, err := services.RequestPasswordReset(id, requestedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAccountDisabled):
+			c.JSON(http.StatusConflict, gin.H{"error": "Account is disabled; enable it first"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			log.Println("failed to send password reset:", err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send the password reset"})
+		}
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditUserPasswordLink, nil, reset); err != nil {
+		log.Println("audit: failed to record password reset link:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset link sent", "expires_at": reset.ExpiresAt})
+}
+
+// RequirePasswordChange - Makes the user pick a new password before they can
+// use the API again
+func RequirePasswordChange(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	before, err := repository.GetUserByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	user, err := repository.SetMustChangePassword(id, true)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditUserPasswordExpire, before, user); err != nil {
+		log.Println("audit: failed to record password change requirement:", err)
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UnlockUser - Lifts a lockout after too many failed sign-ins
+func UnlockUser(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	user, err := repository.GetUserByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := services.UnlockAccount(user.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account"})
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditLoginUnlock, nil, gin.H{"scope": "account", "user_id": id, "email": user.Email}); err != nil {
+		log.Println("audit: failed to record unlock:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
+
+// UnlockIP - Lifts a lockout on a client address, e.g. a shared front-desk
+// network after someone mistyped repeatedly
+func UnlockIP(c *gin.Context) {
+	ip := net.ParseIP(c.Param("ip"))
+	if ip == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IP address"})
+		return
+	}
+	if err := services.UnlockIP(ip.String()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock address"})
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditLoginUnlock, nil, gin.H{"scope": "ip", "ip": ip.String()}); err != nil {
+		log.Println("audit: failed to record unlock:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Address unlocked"})
+}
+
+// RevokeUserSessions - Signs a user out on every device
+func RevokeUserSessions(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	if _, err := repository.GetUserByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := repository.RevokeUserSessions(id, models.SessionAdminRevoked); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditUserSessionsRevoke, nil, gin.H{"user_id": id}); err != nil {
+		log.Println("audit: failed to record session revoke:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions revoked"})
+}
+
+// CreateInvite - Issues a single-use registration link for one email and role.
+// The token is only shown in this response.
+func CreateInvite(c *gin.Context) {
+	var input InviteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	invitedBy, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	invite, token, err := services.CreateInvite(input.Email, input.Name, input.Role, input.RegistrationNumber, invitedBy)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditUserInviteCreate, nil, invite); err != nil {
+		log.Println("audit: failed to record invite:", err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"invite": invite,
+		"token":  token,
+	})
+}
+
+// ListInvites - Invites, newest first. Query: status=pending|all (default pending)
+func ListInvites(c *gin.Context) {
+	invites, err := repository.ListInvites(c.DefaultQuery("status", "pending") != "all")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invites"})
+		return
+	}
+	c.JSON(http.StatusOK, invites)
+}
+
+// RevokeInvite - Deletes an unused invite
+func RevokeInvite(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite ID"})
+		return
+	}
+	if err := repository.DeleteInvite(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found or already used"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invite"})
+		return
+	}
+
+	if err := services.RecordChange(auditActor(c), models.AuditUserInviteRevoke, gin.H{"invite_id": id}, nil); err != nil {
+		log.Println("audit: failed to record invite revoke:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invite revoked"})
+}
+
+func userIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role", "allowed": models.Roles})
+	case errors.Is(err, services.ErrPasswordPolicy):
+		respondFieldErrors(c, FieldErrors{"password": err.Error()})
+	default:
+		respondError(c, err, "User not found", "Failed to update user")
+	}
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+// FieldErrors maps a JSON field name to what is wrong with it
+type FieldErrors map[string]string
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	// Report JSON field names rather than Go struct field names
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	_ = v.RegisterValidation("relationship", func(fl validator.FieldLevel) bool {
+		return models.ValidRelationship(fl.Field().String())
+	})
+}
+
+// normalizer is implemented by inputs that clean up values before validation
+type normalizer interface {
+	normalize()
+}
+
+// bindValidated decodes the JSON body, normalizes it, then runs the binding
+// tag validation. Malformed JSON gets a 400 and ok=false; otherwise the
+// field errors (possibly empty) are returned so callers can add their own.
+func bindValidated(c *gin.Context, input normalizer) (fields FieldErrors, ok bool) {
+	if err := json.NewDecoder(c.Request.Body).Decode(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return nil, false
+	}
+	return validateInput(c, input)
+}
+
+// validateInput is bindValidated for an input that is already decoded
+func validateInput(c *gin.Context, input normalizer) (fields FieldErrors, ok bool) {
+	input.normalize()
+
+	fields = FieldErrors{}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		fields = fieldErrors(verrs)
+	}
+	return fields, true
+}
+
+// merge adds other's entries without overwriting existing messages
+func (f FieldErrors) merge(other FieldErrors) FieldErrors {
+	for name, msg := range other {
+		if _, exists := f[name]; !exists {
+			f[name] = msg
+		}
+	}
+	return f
+}
+
+func respondFieldErrors(c *gin.Context, fields FieldErrors) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":  "Validation failed",
+		"fields": fields,
+	})
+}
+
+func fieldErrors(verrs validator.ValidationErrors) FieldErrors {
+	fields := FieldErrors{}
+	for _, fe := range verrs {
+		fields[fe.Field()] = validationMessage(fe)
+	}
+	return fields
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "e164":
+		return "must be a phone number in E.164 format, e.g. +919876543210"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "relationship":
+		return "must be one of: " + strings.Join(models.Relationships, ", ")
+	case "min", "max", "gte", "lte":
+		bound := map[string]string{"min": "at least", "gte": "at least", "max": "at most", "lte": "at most"}[fe.Tag()]
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be %s %s characters", bound, fe.Param())
+		}
+		return fmt.Sprintf("must be %s %s", bound, fe.Param())
+	case "email":
+		return "must be a valid email address"
+	}
+	return "is invalid"
+}
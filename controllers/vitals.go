@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+const (
+	defaultVitalsLimit = 50
+	maxVitalsLimit     = 500
+	// Allowed clock skew for recorded_at in the future
+	vitalsClockSkew = 5 * time.Minute
+)
+
+// VitalsInput is the payload for POST /api/patients/:id/vitals. The bounds
+// reject typos, not abnormal values; those are flagged instead.
+type VitalsInput struct {
+	VisitID        *uint      `json:"visit_id"`
+	RecordedAt     *time.Time `json:"recorded_at"`
+	SystolicBP     *int       `json:"systolic_bp" binding:"omitempty,gte=40,lte=300"`
+	DiastolicBP    *int       `json:"diastolic_bp" binding:"omitempty,gte=20,lte=200"`
+	Pulse          *int       `json:"pulse" binding:"omitempty,gte=20,lte=300"`
+	Temperature    *float64   `json:"temperature" binding:"omitempty,gte=25,lte=45"`
+	SpO2           *int       `json:"spo2" binding:"omitempty,gte=50,lte=100"`
+	Weight         *float64   `json:"weight" binding:"omitempty,gte=0.3,lte=500"`
+	Height         *float64   `json:"height" binding:"omitempty,gte=20,lte=275"`
+	BloodGlucose   *float64   `json:"blood_glucose" binding:"omitempty,gte=10,lte=1500"`
+	GlucoseContext string     `json:"glucose_context" binding:"omitempty,oneof=fasting random post-meal"`
+	Notes          string     `json:"notes" binding:"max=1000"`
+}
+
+func (in *VitalsInput) normalize() {
+	in.GlucoseContext = strings.ToLower(strings.TrimSpace(in.GlucoseContext))
+	in.Notes = strings.TrimSpace(in.Notes)
+	if in.BloodGlucose != nil && in.GlucoseContext == "" {
+		in.GlucoseContext = "random"
+	}
+}
+
+func (in VitalsInput) validate() FieldErrors {
+	fields := FieldErrors{}
+	if in.SystolicBP == nil && in.DiastolicBP == nil && in.Pulse == nil && in.Temperature == nil &&
+		in.SpO2 == nil && in.Weight == nil && in.Height == nil && in.BloodGlucose == nil {
+		fields["vitals"] = "at least one measurement is required"
+	}
+	if (in.SystolicBP == nil) != (in.DiastolicBP == nil) {
+		fields["diastolic_bp"] = "systolic_bp and diastolic_bp must be recorded together"
+	} else if in.SystolicBP != nil && *in.SystolicBP <= *in.DiastolicBP {
+		fields["systolic_bp"] = "must be higher than diastolic_bp"
+	}
+	if in.BloodGlucose == nil && in.GlucoseContext != "" {
+		fields["glucose_context"] = "only applies when blood_glucose is recorded"
+	}
+	if in.RecordedAt != nil && in.RecordedAt.After(time.Now().Add(vitalsClockSkew)) {
+		fields["recorded_at"] = "cannot be in the future"
+	}
+	return fields
+}
+
+func (in VitalsInput) toModel(patientID, userID uint) models.Vitals {
+	recordedAt := time.Now()
+	if in.RecordedAt != nil {
+		recordedAt = *in.RecordedAt
+	}
+	return models.Vitals{
+		PatientID:      patientID,
+		VisitID:        in.VisitID,
+		RecordedAt:     recordedAt,
+		RecordedByID:   userID,
+		SystolicBP:     in.SystolicBP,
+		DiastolicBP:    in.DiastolicBP,
+		Pulse:          in.Pulse,
+		Temperature:    in.Temperature,
+		SpO2:           in.SpO2,
+		Weight:         in.Weight,
+		Height:         in.Height,
+		BloodGlucose:   in.BloodGlucose,
+		GlucoseContext: in.GlucoseContext,
+		Notes:          in.Notes,
+	}
+}
+
+// RecordVitals - Receptionists, nurses and doctors record a set of vitals
+func RecordVitals(c *gin.Context) {
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	var input VitalsInput
+	fields, ok := bindValidated(c, &input)
+	if !ok {
+		return
+	}
+	if fields = fields.merge(input.validate()); len(fields) > 0 {
+		respondFieldErrors(c, fields)
+		return
+	}
+
+	vitals := input.toModel(uint(patientID), userID)
+	err = repository.Atomically(func(uow repository.UnitOfWork) error {
+		if err := uow.CreateVitals(&vitals); err != nil {
+			return err
+		}
+		return services.RecordPatientChangeIn(uow, auditActor(c), models.AuditVitalsCreate, vitals.PatientID, nil, vitals)
+	})
+	if err != nil {
+		respondError(c, err, "Patient or visit not found", "Failed to record vitals")
+		return
+	}
+
+	patient, err := repository.GetPatientByID(patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Vitals recorded but failed to fetch patient"})
+		return
+	}
+	flagged := []models.Vitals{vitals}
+	if err := services.FlagVitals(flagged, patient.DateOfBirth, patient.Gender); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Vitals recorded but failed to check reference ranges"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, flagged[0])
+}
+
+// GetPatientVitals - Recorded vitals, newest first, with abnormal readings flagged.
+// Query: from, to (RFC3339 or YYYY-MM-DD), limit
+func GetPatientVitals(c *gin.Context) {
+	patient, from, to, ok := vitalsQuery(c)
+	if !ok {
+		return
+	}
+
+	limit := defaultVitalsLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+	if limit > maxVitalsLimit {
+		limit = maxVitalsLimit
+	}
+
+	vitals, err := repository.GetPatientVitals(int(patient.ID), from, to, limit, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vitals"})
+		return
+	}
+	if err := services.FlagVitals(vitals, patient.DateOfBirth, patient.Gender); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check reference ranges"})
+		return
+	}
+
+	if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientVitalsRead, patient.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"patient_id": patient.ID,
+		"vitals":     vitals,
+	})
+}
+
+// GetVitalSeries - Time series for charting, oldest first, one series per measure.
+// Query: measures (comma-separated, default all), from, to
+func GetVitalSeries(c *gin.Context) {
+	patient, from, to, ok := vitalsQuery(c)
+	if !ok {
+		return
+	}
+
+	measures := models.Measures
+	if v := c.Query("measures"); v != "" {
+		measures = nil
+		for _, m := range strings.Split(v, ",") {
+			m = strings.TrimSpace(m)
+			if !models.ValidMeasure(m) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown measure " + m, "allowed": models.Measures})
+				return
+			}
+			measures = append(measures, m)
+		}
+	}
+
+	vitals, err := repository.GetPatientVitals(int(patient.ID), from, to, 0, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vitals"})
+		return
+	}
+	if err := services.FlagVitals(vitals, patient.DateOfBirth, patient.Gender); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check reference ranges"})
+		return
+	}
+	series, err := services.BuildVitalSeries(vitals, measures, patient.DateOfBirth, patient.Gender)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build vitals series"})
+		return
+	}
+
+	if err := services.RecordPatientAccess(auditActor(c), models.AuditPatientVitalsRead, patient.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"patient_id": patient.ID,
+		"series":     series,
+	})
+}
+
+// GetReferenceRanges - The configured normal ranges used for flagging
+func GetReferenceRanges(c *gin.Context) {
+	ranges, err := repository.GetReferenceRanges()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reference ranges"})
+		return
+	}
+	c.JSON(http.StatusOK, ranges)
+}
+
+// ReplaceReferenceRanges - Admin-only; replaces every range with the JSON array sent
+func ReplaceReferenceRanges(c *gin.Context) {
+	ranges, err := services.ParseReferenceRanges(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reference ranges: " + err.Error()})
+		return
+	}
+	if err := repository.ReplaceReferenceRanges(ranges); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save reference ranges"})
+		return
+	}
+	c.JSON(http.StatusOK, ranges)
+}
+
+// vitalsQuery parses the patient and from/to shared by the read endpoints
+func vitalsQuery(c *gin.Context) (models.Patient, time.Time, time.Time, bool) {
+	var from, to time.Time
+
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return models.Patient{}, from, to, false
+	}
+	if from, err = parseTimeQuery(c.Query("from"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, expected RFC3339 or YYYY-MM-DD"})
+		return models.Patient{}, from, to, false
+	}
+	if to, err = parseTimeQuery(c.Query("to"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, expected RFC3339 or YYYY-MM-DD"})
+		return models.Patient{}, from, to, false
+	}
+
+	patient, err := repository.GetPatientByID(patientID)
+	if err != nil {
+		respondError(c, err, "Patient not found", "Failed to fetch patient")
+		return models.Patient{}, from, to, false
+	}
+	return patient, from, to, true
+}
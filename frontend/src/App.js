@@ -1,6 +1,8 @@
 import { Routes, Route, Navigate } from "react-router-dom"
 import LoginPage from "./pages/LoginPage"
 import RegisterPage from "./pages/RegisterPage"
+import ChangePasswordPage from "./pages/ChangePasswordPage"
+import ResetPasswordPage from "./pages/ResetPasswordPage"
 import ReceptionistDashboard from "./pages/ReceptionistDashboard"
 import DoctorDashboard from "./pages/DoctorDashboard"
 import ProtectedRoute from "./components/ProtectedRoute"
@@ -11,6 +13,15 @@ function App() {
       <Routes>
         <Route path="/login" element={<LoginPage />} />
         <Route path="/register" element={<RegisterPage />} />
+        <Route path="/reset-password" element={<ResetPasswordPage />} />
+        <Route
+          path="/change-password"
+          element={
+            <ProtectedRoute>
+              <ChangePasswordPage />
+            </ProtectedRoute>
+          }
+        />
 
         <Route
           path="/receptionist"
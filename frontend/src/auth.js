@@ -0,0 +1,74 @@
+// Access tokens are short-lived; authFetch refreshes them with the stored
+// refresh token and retries once when the API answers 401.
+const API_BASE = "http://localhost:8080"
+
+let refreshing = null
+
+export function clearSession() {
+  localStorage.removeItem("token")
+  localStorage.removeItem("refresh_token")
+  localStorage.removeItem("user")
+}
+
+async function refreshAccessToken() {
+  const refreshToken = localStorage.getItem("refresh_token")
+  if (!refreshToken) return false
+
+  const res = await fetch(`${API_BASE}/auth/refresh`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ refresh_token: refreshToken }),
+  })
+  if (!res.ok) {
+    clearSession()
+    return false
+  }
+  const data = await res.json()
+  localStorage.setItem("token", data.token)
+  localStorage.setItem("refresh_token", data.refresh_token)
+  return true
+}
+
+export async function authFetch(url, options = {}) {
+  const withToken = () => ({
+    ...options,
+    headers: { ...(options.headers || {}), Authorization: `Bearer ${localStorage.getItem("token")}` },
+  })
+
+  const res = await fetch(url, withToken())
+  if (res.status === 403) {
+    // Users with a temporary password must set their own first
+    const body = await res.clone().json().catch(() => ({}))
+    if (body.code === "password_change_required") {
+      window.location.href = "/change-password"
+    }
+    return res
+  }
+  if (res.status !== 401) return res
+
+  // Several requests can fail at once; share one refresh between them
+  if (!refreshing) {
+    refreshing = refreshAccessToken().finally(() => {
+      refreshing = null
+    })
+  }
+  if (!(await refreshing)) {
+    window.location.href = "/login"
+    return res
+  }
+  return fetch(url, withToken())
+}
+
+// logout ends the session on the server as well as in this browser
+export function logout() {
+  const refreshToken = localStorage.getItem("refresh_token")
+  clearSession()
+  if (refreshToken) {
+    fetch(`${API_BASE}/auth/logout`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+      keepalive: true,
+    }).catch(() => {})
+  }
+}
@@ -1,6 +1,7 @@
 "use client"
 
 import { Outlet } from "react-router-dom"
+import { logout } from "../auth"
 
 export default function Layout() {
   // ✅ FIXED: Get role from user object
@@ -21,9 +22,7 @@ export default function Layout() {
   const userDisplay = role ? `${role.charAt(0).toUpperCase() + role.slice(1)}` : "User"
 
   const handleLogout = () => {
-    // ✅ FIXED: Remove correct localStorage items
-    localStorage.removeItem("token")
-    localStorage.removeItem("user")
+    logout()
     window.location.href = "/login"
   }
 
@@ -1,14 +1,13 @@
 "use client"
 
 import { useNavigate } from "react-router-dom"
+import { logout } from "../auth"
 
 export default function LogoutButton() {
   const navigate = useNavigate()
 
   const handleLogout = () => {
-    // ✅ FIXED: Remove correct localStorage items
-    localStorage.removeItem("token")
-    localStorage.removeItem("user")
+    logout()
     navigate("/login")
   }
 
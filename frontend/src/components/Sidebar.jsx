@@ -1,6 +1,7 @@
 "use client"
 
 import { NavLink, useNavigate } from "react-router-dom"
+import { logout } from "../auth"
 
 export default function Sidebar() {
   const navigate = useNavigate()
@@ -19,9 +20,7 @@ export default function Sidebar() {
   }
 
   const handleLogout = () => {
-    // ✅ FIXED: Remove correct localStorage items
-    localStorage.removeItem("token")
-    localStorage.removeItem("user")
+    logout()
     navigate("/login")
   }
 
@@ -0,0 +1,75 @@
+import { useState } from 'react';
+import { useNavigate } from 'react-router-dom';
+import { authFetch } from '../auth';
+
+// Also where users with a temporary password land after logging in
+export default function ChangePasswordPage() {
+  const [currentPassword, setCurrentPassword] = useState('');
+  const [newPassword, setNewPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const navigate = useNavigate();
+
+  const handleChange = async () => {
+    if (newPassword !== confirmPassword) {
+      alert("The new passwords don't match");
+      return;
+    }
+    try {
+      const res = await authFetch('http://localhost:8080/auth/password', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ current_password: currentPassword, new_password: newPassword }),
+      });
+
+      const data = await res.json();
+      if (!res.ok) {
+        const fieldError = data.fields && (data.fields.current_password || data.fields.new_password);
+        alert(fieldError || data.error || "Password change failed");
+        return;
+      }
+
+      const user = JSON.parse(localStorage.getItem('user') || '{}');
+      localStorage.setItem('user', JSON.stringify({ ...user, must_change_password: false }));
+      alert("✅ Password changed.");
+      navigate(user.role === 'doctor' ? '/doctor' : '/receptionist');
+    } catch (err) {
+      alert("Password change failed. Server error.");
+      console.error(err);
+    }
+  };
+
+  return (
+    <div className="min-h-screen flex flex-col items-center justify-center bg-gradient-to-br from-green-300 to-blue-300">
+      <div className="bg-white p-6 rounded shadow-md w-80">
+        <h1 className="text-2xl font-bold mb-4 text-center">Change password</h1>
+        <input
+          type="password"
+          placeholder="Current password"
+          className="w-full mb-3 px-4 py-2 border rounded"
+          value={currentPassword}
+          onChange={(e) => setCurrentPassword(e.target.value)}
+        />
+        <input
+          type="password"
+          placeholder="New password"
+          className="w-full mb-3 px-4 py-2 border rounded"
+          value={newPassword}
+          onChange={(e) => setNewPassword(e.target.value)}
+        />
+        <input
+          type="password"
+          placeholder="Repeat new password"
+          className="w-full mb-3 px-4 py-2 border rounded"
+          value={confirmPassword}
+          onChange={(e) => setConfirmPassword(e.target.value)}
+        />
+        <button
+          onClick={handleChange}
+          className="w-full bg-green-500 hover:bg-green-600 text-white py-2 rounded"
+        >
+          Change password
+        </button>
+      </div>
+    </div>
+  );
+}
@@ -1,4 +1,5 @@
 import { useState, useEffect } from 'react';
+import { authFetch, logout } from '../auth';
 
 export default function DoctorDashboard() {
   const [patients, setPatients] = useState([]);
@@ -18,7 +19,7 @@ export default function DoctorDashboard() {
     try {
       setLoading(true);
       console.log('Fetching patients...');
-      const response = await fetch('http://localhost:8080/api/patients', {
+      const response = await authFetch('http://localhost:8080/api/patients?limit=100&include_history=true', {
         headers: { 
           'Authorization': `Bearer ${token}`,
           'Content-Type': 'application/json'
@@ -31,7 +32,7 @@ export default function DoctorDashboard() {
       
       const data = await response.json();
       console.log('Patients fetched:', data);
-      setPatients(data || []);
+      setPatients(data.data || []);
       setError('');
     } catch (err) {
       setError('Failed to load patients');
@@ -41,12 +42,30 @@ export default function DoctorDashboard() {
     }
   };
 
-  // Fetch complete family history by phone number
-  const fetchFamilyHistoryByPhone = async (phoneNumber) => {
+  // Fetch complete household history for a patient
+  const openVisitPdf = async (history, type) => {
+    try {
+      const response = await authFetch(
+        `http://localhost:8080/api/patients/${history.patient_id}/history/${history.id}/pdf?type=${type}`,
+        { headers: { 'Authorization': `Bearer ${token}` } }
+      );
+      if (!response.ok) {
+        const data = await response.json().catch(() => ({}));
+        alert(data.error || 'Failed to generate PDF');
+        return;
+      }
+      const blob = await response.blob();
+      window.open(URL.createObjectURL(blob), '_blank');
+    } catch (err) {
+      console.error('Failed to open visit PDF:', err);
+    }
+  };
+
+  const fetchHouseholdHistory = async (patientId) => {
     try {
       setLoadingHistory(true);
-      console.log(`Fetching complete family history for phone ${phoneNumber}...`);
-      const response = await fetch(`http://localhost:8080/api/patients/phone/${phoneNumber}/family-history`, {
+      console.log(`Fetching complete household history for patient ${patientId}...`);
+      const response = await authFetch(`http://localhost:8080/api/patients/${patientId}/household`, {
         headers: { 
           'Authorization': `Bearer ${token}`,
           'Content-Type': 'application/json'
@@ -75,50 +94,54 @@ export default function DoctorDashboard() {
     }
   };
 
-  // Update patient
-  const updatePatient = async (e) => {
-    e.preventDefault();
+  // Record today's visit. The patient's current diagnosis and medications
+  // follow the newest visit; earlier visits stay in the history.
+  const recordEncounter = async (e, overrideReason = '') => {
+    if (e) e.preventDefault();
     setSaving(true);
     
     try {
-      console.log('Updating patient:', editingPatient);
-      
-      const response = await fetch(`http://localhost:8080/api/patients/${editingPatient.id}`, {
-        method: 'PUT',
+      const response = await authFetch(`http://localhost:8080/api/patients/${editingPatient.id}/encounters`, {
+        method: 'POST',
         headers: {
           'Content-Type': 'application/json',
           'Authorization': `Bearer ${token}`,
         },
         body: JSON.stringify({
-          name: editingPatient.name,
-          age: editingPatient.age,
-          gender: editingPatient.gender,
-          phone_number: editingPatient.phone_number,
-          relationship: editingPatient.relationship,
+          chief_complaint: editingPatient.chief_complaint || '',
+          findings: editingPatient.findings || '',
           diagnosis: editingPatient.diagnosis || '',
+          plan: editingPatient.plan || '',
           medical_notes: editingPatient.medical_notes || '',
           prescriptions: editingPatient.prescriptions || '',
-          last_checkup: editingPatient.last_checkup || '',
-          next_appointment: editingPatient.next_appointment || ''
+          override_reason: overrideReason
         }),
       });
       
+      // Allergy or interaction conflict: the doctor may go ahead with a reason
+      if (response.status === 409) {
+        const data = await response.json();
+        const warnings = (data.warnings || []).map(w => `• ${w.message}`).join('\n');
+        const reason = window.prompt(`⚠️ ${data.error}\n\n${warnings}\n\nReason to prescribe anyway:`);
+        if (reason && reason.trim()) {
+          setSaving(false);
+          await recordEncounter(null, reason.trim());
+        }
+        return;
+      }
       if (!response.ok) {
         const errorData = await response.text();
-        throw new Error(`Failed to update patient: ${errorData}`);
+        throw new Error(`Failed to record visit: ${errorData}`);
       }
       
-      const result = await response.json();
-      console.log('Update result:', result);
-      
       // Refresh patient list and family history
       await fetchPatients();
-      await fetchFamilyHistoryByPhone(editingPatient.phone_number);
+      await fetchHouseholdHistory(editingPatient.id);
       
-      alert('✅ Patient medical record updated successfully!');
+      alert('✅ Visit recorded successfully!');
     } catch (err) {
-      alert(`❌ Error updating patient: ${err.message}`);
-      console.error('Update error:', err);
+      alert(`❌ Error recording visit: ${err.message}`);
+      console.error('Encounter error:', err);
     } finally {
       setSaving(false);
     }
@@ -127,19 +150,56 @@ export default function DoctorDashboard() {
   // Open edit modal
   const handleEdit = async (patient) => {
     console.log('Editing patient:', patient);
+    // Each visit is a new record; medications usually carry over
     setEditingPatient({
       ...patient,
-      medical_notes: patient.medical_notes || '',
-      prescriptions: patient.prescriptions || '',
-      last_checkup: patient.last_checkup || new Date().toISOString().split('T')[0],
-      next_appointment: patient.next_appointment || ''
+      chief_complaint: '',
+      findings: '',
+      diagnosis: '',
+      plan: '',
+      medical_notes: '',
+      prescriptions: patient.prescriptions || ''
     });
     
-    // Fetch complete family history by phone number
-    await fetchFamilyHistoryByPhone(patient.phone_number);
+    // Fetch complete household history
+    await fetchHouseholdHistory(patient.id);
     setShowEditModal(true);
   };
 
+  // Break glass: open a patient who isn't assigned to you in an emergency.
+  // The reason is audited and every request is logged.
+  const handleEmergencyAccess = async () => {
+    const patientId = window.prompt('Patient ID for emergency access:');
+    if (!patientId) return;
+    const reason = window.prompt('Describe the emergency. This access is audited and will be reviewed.');
+    if (!reason) return;
+
+    try {
+      const grant = await authFetch(`http://localhost:8080/api/patients/${patientId}/break-glass`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ reason }),
+      });
+      const grantData = await grant.json();
+      // 409 means the patient is already yours; open it anyway
+      if (!grant.ok && grant.status !== 409) {
+        alert((grantData.fields && grantData.fields.reason) || grantData.error || 'Emergency access was not granted');
+        return;
+      }
+
+      const response = await authFetch(`http://localhost:8080/api/patients/${patientId}`);
+      if (!response.ok) {
+        const data = await response.json().catch(() => ({}));
+        alert(data.error || 'Failed to open patient');
+        return;
+      }
+      await handleEdit(await response.json());
+    } catch (err) {
+      console.error('Emergency access failed:', err);
+      alert('Emergency access failed. Server error.');
+    }
+  };
+
   // Close edit modal
   const handleCloseModal = () => {
     setShowEditModal(false);
@@ -207,8 +267,7 @@ export default function DoctorDashboard() {
         </div>
        <button
       onClick={() => {
-        localStorage.removeItem("token")
-        localStorage.removeItem("user")
+        logout()
         window.location.href = "/login"
       }}
       style={{
@@ -280,8 +339,22 @@ export default function DoctorDashboard() {
             📋 Patient Records
           </h2>
           <p style={{ color: '#6b7280', marginBottom: '24px' }}>
-            Click on any patient to view their complete family medical history and update current condition
+            Patients assigned to you. Click on any patient to view their complete family medical history and update current condition
           </p>
+          <button
+            onClick={handleEmergencyAccess}
+            style={{
+              marginBottom: '24px',
+              padding: '8px 16px',
+              backgroundColor: '#dc2626',
+              color: 'white',
+              border: 'none',
+              borderRadius: '4px',
+              cursor: 'pointer'
+            }}
+          >
+            🚨 Emergency access
+          </button>
 
           {loading && (
             <div style={{ textAlign: 'center', padding: '40px' }}>
@@ -381,7 +454,7 @@ export default function DoctorDashboard() {
 //     let familyData = { family_members: [], medical_history: [], family_summary: {} };
     
 //     if (patient.phone_number && token) {
-//       const response = await fetch(`http://localhost:8080/api/patients/family/${patient.phone_number}`, {
+//       const response = await authFetch(`http://localhost:8080/api/patients/family/${patient.phone_number}`, {
 //         headers: { 
 //           'Authorization': `Bearer ${token}`,
 //           'Content-Type': 'application/json'
@@ -760,15 +833,30 @@ export default function DoctorDashboard() {
                                 </span>
                               </div>
                               <div className="history-details">
+                                {history.chief_complaint && (
+                                  <p><strong>🗣️ Complaint:</strong> {history.chief_complaint}</p>
+                                )}
+                                {history.findings && (
+                                  <p><strong>🔍 Findings:</strong> {history.findings}</p>
+                                )}
                                 {history.diagnosis && (
                                   <p><strong>🏥 Diagnosis:</strong> {history.diagnosis}</p>
                                 )}
+                                {history.plan && (
+                                  <p><strong>📋 Plan:</strong> {history.plan}</p>
+                                )}
                                 {history.medical_notes && (
                                   <p><strong>📝 Notes:</strong> {history.medical_notes}</p>
                                 )}
                                 {history.prescriptions && (
                                   <p><strong>💊 Prescriptions:</strong> {history.prescriptions}</p>
                                 )}
+                                <div className="history-actions">
+                                  <button type="button" onClick={() => openVisitPdf(history, 'summary')}>🖨️ Visit summary</button>
+                                  {history.prescriptions && (
+                                    <button type="button" onClick={() => openVisitPdf(history, 'prescription')}>🖨️ Prescription</button>
+                                  )}
+                                </div>
                               </div>
                             </div>
                           ))}
@@ -790,11 +878,41 @@ export default function DoctorDashboard() {
                     🩺 Current Visit - {getRelationshipEmoji(editingPatient.relationship)} {editingPatient.name}
                     <span className="patient-relationship">({getRelationshipDisplay(editingPatient.relationship)})</span>
                   </h3>
-                  <form onSubmit={updatePatient}>
+                  <form onSubmit={recordEncounter}>
+                    <div className="form-group">
+                      <label className="form-label">
+                        🗣️ Chief Complaint
+                        <span className="helper-text">Why the patient came in today</span>
+                      </label>
+                      <textarea
+                        name="chief_complaint"
+                        className="form-input"
+                        value={editingPatient.chief_complaint || ''}
+                        onChange={handleInputChange}
+                        placeholder="e.g., Cough and fever for 3 days"
+                        rows="2"
+                      />
+                    </div>
+                    
+                    <div className="form-group">
+                      <label className="form-label">
+                        🔍 Findings
+                        <span className="helper-text">Examination findings and vitals observations</span>
+                      </label>
+                      <textarea
+                        name="findings"
+                        className="form-input"
+                        value={editingPatient.findings || ''}
+                        onChange={handleInputChange}
+                        placeholder="e.g., Temp 38.5°C, throat congested, chest clear"
+                        rows="3"
+                      />
+                    </div>
+                    
                     <div className="form-group">
                       <label className="form-label">
-                        🏥 Current Diagnosis *
-                        <span className="helper-text">What is the current medical condition or complaint?</span>
+                        🏥 Diagnosis
+                        <span className="helper-text">What is the current medical condition?</span>
                       </label>
                       <textarea
                         name="diagnosis"
@@ -802,22 +920,36 @@ export default function DoctorDashboard() {
                         value={editingPatient.diagnosis || ''}
                         onChange={handleInputChange}
                         placeholder="e.g., Cough, Cold, Fever, Headache..."
+                        rows="2"
+                      />
+                    </div>
+                    
+                    <div className="form-group">
+                      <label className="form-label">
+                        📋 Plan
+                        <span className="helper-text">Tests ordered, advice and follow-up</span>
+                      </label>
+                      <textarea
+                        name="plan"
+                        className="form-input"
+                        value={editingPatient.plan || ''}
+                        onChange={handleInputChange}
+                        placeholder="e.g., CBC, rest and fluids, review in 5 days"
                         rows="3"
-                        required
                       />
                     </div>
                     
                     <div className="form-group">
                       <label className="form-label">
                         📝 Medical Notes
-                        <span className="helper-text">Detailed observations, symptoms, examination findings</span>
+                        <span className="helper-text">Anything else worth recording</span>
                       </label>
                       <textarea
                         name="medical_notes"
                         className="form-input"
                         value={editingPatient.medical_notes || ''}
                         onChange={handleInputChange}
-                        placeholder="Enter detailed medical notes, observations, symptoms, examination findings..."
+                        placeholder="Enter any other notes..."
                         rows="4"
                       />
                     </div>
@@ -837,31 +969,6 @@ export default function DoctorDashboard() {
                       />
                     </div>
                     
-                    <div className="form-row">
-                      <div className="form-group">
-                        <label className="form-label">📅 Checkup Date</label>
-                        <input
-                          type="date"
-                          name="last_checkup"
-                          className="form-input"
-                          value={editingPatient.last_checkup || ''}
-                          onChange={handleInputChange}
-                        />
-                      </div>
-                      
-                      <div className="form-group">
-                        <label className="form-label">🗓️ Next Appointment</label>
-                        <input
-                          type="date"
-                          name="next_appointment"
-                          className="form-input"
-                          value={editingPatient.next_appointment || ''}
-                          onChange={handleInputChange}
-                          min={new Date().toISOString().split('T')[0]}
-                        />
-                      </div>
-                    </div>
-                    
                     <div className="form-actions">
                       <button 
                         type="button" 
@@ -876,7 +983,7 @@ export default function DoctorDashboard() {
                         className="btn btn-submit"
                         disabled={saving}
                       >
-                        {saving ? '💾 Saving...' : '💾 Save Medical Record'}
+                        {saving ? '💾 Saving...' : '💾 Record Visit'}
                       </button>
                     </div>
                   </form>
@@ -11,6 +11,11 @@ const LoginPage = () => {
   })
   const [error, setError] = useState("")
   const [loading, setLoading] = useState(false)
+  // Second step when the server answers with mfa_required
+  const [challenge, setChallenge] = useState(null)
+  const [enrollment, setEnrollment] = useState(null)
+  const [mfaCode, setMfaCode] = useState("")
+  const [useRecoveryCode, setUseRecoveryCode] = useState(false)
   const navigate = useNavigate()
 
   // ✅ ADDED: Check if user is already logged in
@@ -89,6 +94,80 @@ const LoginPage = () => {
         throw new Error(data.error || `Login failed with status: ${response.status}`)
       }
 
+      if (data.mfa_required) {
+        setChallenge(data)
+        if (data.enrollment_required) {
+          await startEnrollment(data.challenge_token)
+        }
+        return
+      }
+
+      finishLogin(data)
+    } catch (error) {
+      showLoginError(error)
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  // Users whose role requires MFA set up their authenticator before the first sign-in completes
+  const startEnrollment = async (challengeToken) => {
+    const response = await fetch("http://localhost:8080/auth/mfa/setup", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ challenge_token: challengeToken }),
+    })
+    const data = await response.json()
+    if (!response.ok) {
+      throw new Error(data.error || "Could not start authenticator setup")
+    }
+    setEnrollment(data)
+  }
+
+  const handleVerify = async (e) => {
+    e.preventDefault()
+    setError("")
+    setLoading(true)
+
+    try {
+      const response = await fetch("http://localhost:8080/auth/mfa/verify", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          challenge_token: challenge.challenge_token,
+          [useRecoveryCode ? "recovery_code" : "code"]: mfaCode,
+        }),
+      })
+      const data = await response.json()
+      if (!response.ok) {
+        if (data.error && data.error.includes("log in again")) {
+          resetChallenge()
+        }
+        throw new Error(data.error || "Verification failed")
+      }
+
+      if (data.recovery_codes) {
+        alert(
+          "Two-factor authentication is on. Save these recovery codes somewhere safe; each works once if you lose your phone:\n\n" +
+            data.recovery_codes.join("\n"),
+        )
+      }
+      finishLogin(data)
+    } catch (error) {
+      showLoginError(error)
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  const resetChallenge = () => {
+    setChallenge(null)
+    setEnrollment(null)
+    setMfaCode("")
+    setUseRecoveryCode(false)
+  }
+
+  const finishLogin = (data) => {
       console.log("Login successful:", data)
 
       // ✅ IMPROVED: Better response validation
@@ -117,11 +196,18 @@ const LoginPage = () => {
 
       // Store token and user data
       localStorage.setItem("token", data.token)
+      localStorage.setItem("refresh_token", data.refresh_token)
       localStorage.setItem("user", JSON.stringify(userData))
 
       // ✅ ADDED: Success feedback
       console.log(`✅ Login successful! Redirecting to ${userRole} dashboard...`)
 
+      // Temporary passwords must be replaced before anything else
+      if (userData.must_change_password) {
+        navigate("/change-password")
+        return
+      }
+
       // Navigate based on role
       if (userRole === "doctor") {
         navigate("/doctor")
@@ -130,7 +216,9 @@ const LoginPage = () => {
       } else {
         throw new Error(`Unknown user role: ${userRole}`)
       }
-    } catch (error) {
+  }
+
+  const showLoginError = (error) => {
       console.error("Login error:", error)
 
       // ✅ IMPROVED: Better error messages
@@ -143,9 +231,6 @@ const LoginPage = () => {
       } else {
         setError(error.message || "Login failed. Please try again.")
       }
-    } finally {
-      setLoading(false)
-    }
   }
 
   // ✅ ADDED: Clear error when user types
@@ -171,6 +256,61 @@ const LoginPage = () => {
           </div>
         )}
 
+        {challenge ? (
+          <form onSubmit={handleVerify} className="login-form">
+            {enrollment && (
+              <div className="form-group">
+                <p className="mfa-help">
+                  Your role requires two-factor authentication. Scan this code with an authenticator app, then enter
+                  the 6-digit code it shows.
+                </p>
+                <img src={enrollment.qr_code} alt="Authenticator QR code" className="mfa-qr" />
+                <p className="mfa-help">
+                  Can't scan? Enter this key: <span className="server-url">{enrollment.secret}</span>
+                </p>
+              </div>
+            )}
+
+            <div className="form-group">
+              <label htmlFor="mfa-code" className="form-label">
+                🔐 {useRecoveryCode ? "Recovery code" : "Authentication code"}
+              </label>
+              <input
+                id="mfa-code"
+                type="text"
+                required
+                autoFocus
+                autoComplete="one-time-code"
+                inputMode={useRecoveryCode ? "text" : "numeric"}
+                value={mfaCode}
+                onChange={(e) => setMfaCode(e.target.value)}
+                className="form-input"
+                placeholder={useRecoveryCode ? "xxxx-xxxx" : "123456"}
+                disabled={loading}
+              />
+            </div>
+
+            <button type="submit" disabled={loading} className="submit-button">
+              {loading ? "Verifying..." : "Verify"}
+            </button>
+
+            <p className="mfa-help">
+              {!enrollment && (
+                <button
+                  type="button"
+                  onClick={() => setUseRecoveryCode(!useRecoveryCode)}
+                  className="link-button"
+                  disabled={loading}
+                >
+                  {useRecoveryCode ? "Use authenticator code" : "Lost your phone? Use a recovery code"}
+                </button>
+              )}{" "}
+              <button type="button" onClick={resetChallenge} className="link-button" disabled={loading}>
+                Back to sign in
+              </button>
+            </p>
+          </form>
+        ) : (
         <form onSubmit={handleSubmit} className="login-form">
           <div className="form-group">
             <label htmlFor="email" className="form-label">
@@ -232,6 +372,7 @@ const LoginPage = () => {
             )}
           </button>
         </form>
+        )}
 
         <div className="login-footer">
           <p>
@@ -426,6 +567,19 @@ const LoginPage = () => {
           cursor: not-allowed;
         }
         
+        .mfa-help {
+          font-size: 14px;
+          color: #374151;
+          margin: 0 0 8px 0;
+        }
+
+        .mfa-qr {
+          width: 192px;
+          height: 192px;
+          align-self: center;
+          margin-bottom: 8px;
+        }
+
         .server-url {
           font-family: monospace;
           color: #2563eb;
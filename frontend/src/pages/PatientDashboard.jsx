@@ -1,5 +1,6 @@
 import { useEffect, useState } from 'react';
 import Toast from '../components/Toast';
+import { authFetch } from '../auth';
 
 export default function PatientDashboard() {
   const [patients, setPatients] = useState([]);
@@ -21,7 +22,7 @@ export default function PatientDashboard() {
 
   const fetchPatients = async () => {
     try {
-      const res = await fetch('http://localhost:8080/api/patients', {
+      const res = await authFetch('http://localhost:8080/api/patients?limit=100', {
         headers: {
           'Authorization': `Bearer ${token}`
         }
@@ -29,7 +30,7 @@ export default function PatientDashboard() {
       
       if (res.ok) {
         const data = await res.json();
-        setPatients(data);
+        setPatients(data.data || []);
       } else {
         setToast({ message: 'Failed to fetch patients', type: 'error' });
       }
@@ -61,7 +62,7 @@ export default function PatientDashboard() {
         method = 'PUT';
       }
 
-      const res = await fetch(url, {
+      const res = await authFetch(url, {
         method,
         headers: {
           'Content-Type': 'application/json',
@@ -1,6 +1,7 @@
 "use client"
 
 import { useState, useEffect } from "react"
+import { authFetch, logout } from "../auth"
 
 const ReceptionistDashboard = () => {
   const [patients, setPatients] = useState([])
@@ -8,13 +9,14 @@ const ReceptionistDashboard = () => {
   const [showForm, setShowForm] = useState(false)
   const [formData, setFormData] = useState({
     name: "",
-    age: "",
+    date_of_birth: "",
     gender: "",
-    diagnosis: "",
     phone_number: "",
     relationship: "self",
   })
   const [editingId, setEditingId] = useState(null)
+  // Version the edit started from; the server refuses the save if it moved on
+  const [editingVersion, setEditingVersion] = useState(null)
   const [error, setError] = useState("")
   const [success, setSuccess] = useState("")
 
@@ -28,7 +30,7 @@ const ReceptionistDashboard = () => {
   const fetchPatients = async () => {
     try {
       setLoading(true)
-      const response = await fetch("http://localhost:8080/api/patients", {
+      const response = await authFetch("http://localhost:8080/api/patients?limit=100", {
         headers: {
           Authorization: `Bearer ${token}`,
         },
@@ -36,7 +38,7 @@ const ReceptionistDashboard = () => {
 
       if (response.ok) {
         const data = await response.json()
-        setPatients(data || [])
+        setPatients(data.data || [])
         setError("")
       } else {
         setError("Failed to fetch patients")
@@ -55,31 +57,38 @@ const ReceptionistDashboard = () => {
     try {
       const patientData = {
         name: formData.name,
-        age: Number.parseInt(formData.age),
+        date_of_birth: formData.date_of_birth,
         gender: formData.gender,
-        diagnosis: formData.diagnosis,
         phone_number: formData.phone_number,
         relationship: formData.relationship,
       }
 
       let url = "http://localhost:8080/api/patients"
       let method = "POST"
+      const headers = {
+        "Content-Type": "application/json",
+        Authorization: `Bearer ${token}`,
+      }
 
       if (editingId) {
         url = `http://localhost:8080/api/patients/${editingId}`
         method = "PUT"
+        if (editingVersion) headers["If-Match"] = `"${editingVersion}"`
       }
 
-      const response = await fetch(url, {
+      const response = await authFetch(url, {
         method,
-        headers: {
-          "Content-Type": "application/json",
-          Authorization: `Bearer ${token}`,
-        },
+        headers,
         body: JSON.stringify(patientData),
       })
 
-      if (response.ok) {
+      if (response.status === 412) {
+        // Someone else saved first: show their version and let the user redo the edit
+        const errorData = await response.json()
+        handleEdit(errorData.patient)
+        setError("This patient was changed by someone else. The form now shows the latest details; please make your changes again.")
+        fetchPatients()
+      } else if (response.ok) {
         setSuccess(editingId ? "Patient updated successfully!" : "Patient added successfully!")
         resetForm()
         fetchPatients()
@@ -97,7 +106,7 @@ const ReceptionistDashboard = () => {
     if (!window.confirm("Are you sure you want to delete this patient?")) return
 
     try {
-      const response = await fetch(`http://localhost:8080/api/patients/${id}`, {
+      const response = await authFetch(`http://localhost:8080/api/patients/${id}`, {
         method: "DELETE",
         headers: {
           Authorization: `Bearer ${token}`,
@@ -120,26 +129,26 @@ const ReceptionistDashboard = () => {
   const handleEdit = (patient) => {
     setFormData({
       name: patient.name || "",
-      age: patient.age ? String(patient.age) : "",
+      date_of_birth: patient.date_of_birth || "",
       gender: patient.gender || "",
-      diagnosis: patient.diagnosis || "",
       phone_number: patient.phone_number || "",
       relationship: patient.relationship || "self",
     })
     setEditingId(patient.id)
+    setEditingVersion(patient.version)
     setShowForm(true)
   }
 
   const resetForm = () => {
     setFormData({
       name: "",
-      age: "",
+      date_of_birth: "",
       gender: "",
-      diagnosis: "",
-      phone_number: "",
+        phone_number: "",
       relationship: "self",
     })
     setEditingId(null)
+    setEditingVersion(null)
     setShowForm(false)
   }
 
@@ -151,8 +160,7 @@ const ReceptionistDashboard = () => {
       </div>
       <button
       onClick={() => {
-        localStorage.removeItem("token")
-        localStorage.removeItem("user")
+        logout()
         window.location.href = "/login"
       }}
       style={{
@@ -192,16 +200,6 @@ const ReceptionistDashboard = () => {
           <div className="stat-icon">👥</div>
         </div>
 
-        <div className="stat-card" style={{ backgroundColor: "#60a5fa" }}>
-          <div className="stat-content">
-            <h3>Active Cases</h3>
-            <p className="stat-number">
-              {patients.filter((p) => p.diagnosis && p.diagnosis !== "None specified").length}
-            </p>
-          </div>
-          <div className="stat-icon">🏥</div>
-        </div>
-
         <div className="stat-card" style={{ backgroundColor: "#c084fc" }}>
           <div className="stat-content">
             <h3>Family Members</h3>
@@ -280,9 +278,6 @@ const ReceptionistDashboard = () => {
                       <span>⚧️</span> Gender: {patient.gender}
                     </p>
                   )}
-                  <p>
-                    <span>🏥</span> Diagnosis: {patient.diagnosis || "None specified"}
-                  </p>
                   {patient.phone_number && (
                     <p>
                       <span>📞</span> Phone: {patient.phone_number}
@@ -329,12 +324,11 @@ const ReceptionistDashboard = () => {
 
               <div className="form-row">
                 <div className="form-group">
-                  <label>🎂 Age</label>
+                  <label>🎂 Date of Birth</label>
                   <input
-                    type="number"
-                    value={formData.age}
-                    onChange={(e) => setFormData({ ...formData, age: e.target.value })}
-                    placeholder="Enter age"
+                    type="date"
+                    value={formData.date_of_birth}
+                    onChange={(e) => setFormData({ ...formData, date_of_birth: e.target.value })}
                     required
                   />
                 </div>
@@ -382,16 +376,6 @@ const ReceptionistDashboard = () => {
                 </select>
               </div>
 
-              <div className="form-group">
-                <label>🏥 Diagnosis</label>
-                <textarea
-                  value={formData.diagnosis}
-                  onChange={(e) => setFormData({ ...formData, diagnosis: e.target.value })}
-                  placeholder="Enter diagnosis or condition (optional)"
-                  rows="3"
-                ></textarea>
-              </div>
-
               <div className="form-actions">
                 <button type="button" className="cancel-button" onClick={resetForm}>
                   Cancel
@@ -1,18 +1,20 @@
 import { useState } from 'react';
-import { useNavigate } from 'react-router-dom';
+import { useNavigate, useSearchParams } from 'react-router-dom';
 
+// Registration needs an invite link from an admin: /register?invite=<token>
 export default function RegisterPage() {
-  const [email, setEmail] = useState('');
+  const [searchParams] = useSearchParams();
+  const [inviteToken, setInviteToken] = useState(searchParams.get('invite') || '');
+  const [name, setName] = useState('');
   const [password, setPassword] = useState('');
-  const [role, setRole] = useState('receptionist');
   const navigate = useNavigate();
 
   const handleRegister = async () => {
     try {
-      const res = await fetch('http://localhost:8080/register', {
+      const res = await fetch('http://localhost:8080/auth/register', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ email, password, role }),
+        body: JSON.stringify({ invite_token: inviteToken, name, password }),
       });
 
       const data = await res.json();
@@ -34,27 +36,26 @@ export default function RegisterPage() {
       <div className="bg-white p-6 rounded shadow-md w-80">
         <h1 className="text-2xl font-bold mb-4 text-center">Register</h1>
         <input
-          type="email"
-          placeholder="Email"
+          type="text"
+          placeholder="Invite code"
           className="w-full mb-3 px-4 py-2 border rounded"
-          value={email}
-          onChange={(e) => setEmail(e.target.value)}
+          value={inviteToken}
+          onChange={(e) => setInviteToken(e.target.value)}
+        />
+        <input
+          type="text"
+          placeholder="Full name"
+          className="w-full mb-3 px-4 py-2 border rounded"
+          value={name}
+          onChange={(e) => setName(e.target.value)}
         />
         <input
           type="password"
-          placeholder="Password"
+          placeholder="Password (at least 8 characters)"
           className="w-full mb-3 px-4 py-2 border rounded"
           value={password}
           onChange={(e) => setPassword(e.target.value)}
         />
-        <select
-          className="w-full mb-3 px-4 py-2 border rounded"
-          value={role}
-          onChange={(e) => setRole(e.target.value)}
-        >
-          <option value="receptionist">Receptionist</option>
-          <option value="doctor">Doctor</option>
-        </select>
         <button
           onClick={handleRegister}
           className="w-full bg-green-500 hover:bg-green-600 text-white py-2 rounded"
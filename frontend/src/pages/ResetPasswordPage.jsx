@@ -0,0 +1,65 @@
+import { useState } from 'react';
+import { useNavigate, useSearchParams } from 'react-router-dom';
+
+// Opened from the emailed link: /reset-password?token=<token>
+export default function ResetPasswordPage() {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token') || '';
+  const [newPassword, setNewPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const navigate = useNavigate();
+
+  const handleReset = async () => {
+    if (newPassword !== confirmPassword) {
+      alert("The passwords don't match");
+      return;
+    }
+    try {
+      const res = await fetch('http://localhost:8080/auth/password/reset', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ token, new_password: newPassword }),
+      });
+
+      const data = await res.json();
+      if (!res.ok) {
+        alert((data.fields && data.fields.new_password) || data.error || "Password reset failed");
+        return;
+      }
+
+      alert("✅ Password updated. Please login.");
+      navigate('/');
+    } catch (err) {
+      alert("Password reset failed. Server error.");
+      console.error(err);
+    }
+  };
+
+  return (
+    <div className="min-h-screen flex flex-col items-center justify-center bg-gradient-to-br from-green-300 to-blue-300">
+      <div className="bg-white p-6 rounded shadow-md w-80">
+        <h1 className="text-2xl font-bold mb-4 text-center">Choose a new password</h1>
+        <input
+          type="password"
+          placeholder="New password"
+          className="w-full mb-3 px-4 py-2 border rounded"
+          value={newPassword}
+          onChange={(e) => setNewPassword(e.target.value)}
+        />
+        <input
+          type="password"
+          placeholder="Repeat new password"
+          className="w-full mb-3 px-4 py-2 border rounded"
+          value={confirmPassword}
+          onChange={(e) => setConfirmPassword(e.target.value)}
+        />
+        <button
+          onClick={handleReset}
+          className="w-full bg-green-500 hover:bg-green-600 text-white py-2 rounded"
+        >
+          Set password
+        </button>
+      </div>
+    </div>
+  );
+}
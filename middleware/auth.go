@@ -3,15 +3,25 @@
 package middleware
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+	"github.com/Sathwik-145/hospital-portal/services"
 )
 
-// AuthMiddleware checks JWT and allows only specific roles
+// AuthMiddleware checks JWT and allows only specific roles. With no roles
+// listed any known role is let through; routes then use RequirePermission.
 func AuthMiddleware(allowedRoles ...string) gin.HandlerFunc {
+	if len(allowedRoles) == 0 {
+		allowedRoles = models.Roles
+	}
+
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -27,17 +37,31 @@ func AuthMiddleware(allowedRoles ...string) gin.HandlerFunc {
 
 		tokenString := fields[1]
 
-		claims := jwt.MapClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte("your-secret-key"), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := services.ParseAccessToken(tokenString)
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
 		}
 
-		role := claims["role"].(string)
+		// Logged-out sessions are rejected even if the token has not expired
+		if err := services.CheckSession(claims.SessionID, claims.UserID); err != nil {
+			if errors.Is(err, services.ErrSessionRevoked) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session has ended, please log in again"})
+				return
+			}
+			log.Println("auth: failed to check session:", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check session"})
+			return
+		}
+
+		// The account is looked up on every request so that disabling a
+		// user or changing their role applies to tokens already issued
+		user, err := repository.GetUserByID(claims.UserID)
+		if err != nil || user.Disabled() {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Account is disabled or no longer exists"})
+			return
+		}
+		role := user.Role
 
 		// Check if user's role is allowed
 		authorized := false
@@ -53,8 +77,10 @@ func AuthMiddleware(allowedRoles ...string) gin.HandlerFunc {
 			return
 		}
 
-		c.Set("user_id", claims["user_id"])
+		c.Set("user_id", user.ID)
 		c.Set("role", role)
+		c.Set("session_id", claims.SessionID)
+		c.Set("must_change_password", user.MustChangePassword)
 		c.Next()
 	}
 }
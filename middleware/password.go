@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordChangeGuard blocks API access for users who have a temporary
+// password until they change it at /auth/password. Use after AuthMiddleware.
+func PasswordChangeGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("must_change_password") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "You must change your password before continuing",
+				"code":  "password_change_required",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+// RequirePatientAccess limits routes for the patient in :id to callers on
+// the patient's care team, when their role only sees assigned patients.
+// Requests made through break glass are audited one by one. Use after
+// RequirePermission.
+func RequirePatientAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		patientID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			// The handler reports the bad ID
+			c.Next()
+			return
+		}
+
+		actor := services.AuditActor{UserID: c.GetUint("user_id"), Role: c.GetString("role"), ClientIP: c.ClientIP()}
+		if err := services.AuthorizePatientAccess(actor, patientID, c.Request.Method, c.Request.URL.Path); err != nil {
+			if errors.Is(err, services.ErrPatientNotAssigned) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error": "This patient is not assigned to you. In an emergency, request break-glass access with a reason.",
+					"code":  "patient_not_assigned",
+				})
+				return
+			}
+			log.Println("patient access:", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check patient access"})
+			return
+		}
+		c.Next()
+	}
+}
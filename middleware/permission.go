@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sathwik-145/hospital-portal/services"
+)
+
+// RequirePermission lets the request through when the caller's role has at
+// least one of the permissions. It must run after AuthMiddleware.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, p := range permissions {
+			ok, err := services.HasPermission(role, p)
+			if err != nil {
+				log.Println("rbac: failed to load permissions:", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+				return
+			}
+			if ok {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":    "Access denied: your role lacks the required permission",
+			"required": permissions,
+		})
+	}
+}
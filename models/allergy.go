@@ -0,0 +1,80 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Allergy severities
+var AllergySeverities = []string{"mild", "moderate", "severe", "unknown"}
+
+// PatientAllergy is a documented allergy or intolerance. Substance is matched
+// against drug and generic names when prescribing.
+type PatientAllergy struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	PatientID    uint      `json:"patient_id" gorm:"index;not null"`
+	Substance    string    `json:"substance" gorm:"not null"`
+	Reaction     string    `json:"reaction"`
+	Severity     string    `json:"severity" gorm:"not null;default:unknown"`
+	RecordedByID uint      `json:"recorded_by_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Interaction severities, least to most serious
+const (
+	InteractionMinor           = "minor"
+	InteractionModerate        = "moderate"
+	InteractionMajor           = "major"
+	InteractionContraindicated = "contraindicated"
+)
+
+// InteractionRule flags a pair of drugs, by generic name, that interact.
+// DrugA and DrugB are stored lowercased and in sorted order so each pair has
+// one row.
+type InteractionRule struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	DrugA       string    `json:"drug_a" gorm:"not null;uniqueIndex:idx_interaction_pair"`
+	DrugB       string    `json:"drug_b" gorm:"not null;uniqueIndex:idx_interaction_pair"`
+	Severity    string    `json:"severity" gorm:"not null"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Normalize lowercases the drug names and puts them in sorted order
+func (r *InteractionRule) Normalize() {
+	r.DrugA = NormalizeDrugName(r.DrugA)
+	r.DrugB = NormalizeDrugName(r.DrugB)
+	if r.DrugB < r.DrugA {
+		r.DrugA, r.DrugB = r.DrugB, r.DrugA
+	}
+	r.Severity = strings.ToLower(strings.TrimSpace(r.Severity))
+}
+
+// IsBlocking reports whether prescribing needs an override reason
+func (r InteractionRule) IsBlocking() bool {
+	return r.Severity == InteractionMajor || r.Severity == InteractionContraindicated
+}
+
+func ValidInteractionSeverity(s string) bool {
+	switch s {
+	case InteractionMinor, InteractionModerate, InteractionMajor, InteractionContraindicated:
+		return true
+	}
+	return false
+}
+
+func ValidAllergySeverity(s string) bool {
+	for _, valid := range AllergySeverities {
+		if s == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeDrugName is the form drug names are compared in
+func NormalizeDrugName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Appointment statuses
+const (
+	AppointmentBooked    = "booked"
+	AppointmentCheckedIn = "checked-in"
+	AppointmentCompleted = "completed"
+	AppointmentNoShow    = "no-show"
+	AppointmentCancelled = "cancelled"
+)
+
+type Appointment struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PatientID uint      `json:"patient_id" gorm:"index;not null"`
+	Patient   *Patient  `json:"patient,omitempty" gorm:"foreignKey:PatientID"`
+	DoctorID  uint      `json:"doctor_id" gorm:"index;not null"`
+	Doctor    *User     `json:"doctor,omitempty" gorm:"foreignKey:DoctorID"`
+	StartTime time.Time `json:"start_time" gorm:"index;not null"`
+	EndTime   time.Time `json:"end_time" gorm:"not null"`
+	Status    string    `json:"status" gorm:"index;not null;default:booked"`
+	Reason    string    `json:"reason"`
+	// Who booked / cancelled the appointment
+	BookedByID   uint   `json:"booked_by_id"`
+	CancelReason string `json:"cancel_reason,omitempty"`
+	// Timestamps
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsActive reports whether the appointment still occupies the doctor's time.
+func (a Appointment) IsActive() bool {
+	return a.Status == AppointmentBooked || a.Status == AppointmentCheckedIn || a.Status == AppointmentCompleted
+}
+
+// ValidAppointmentStatus reports whether s is one of the known statuses.
+func ValidAppointmentStatus(s string) bool {
+	switch s {
+	case AppointmentBooked, AppointmentCheckedIn, AppointmentCompleted, AppointmentNoShow, AppointmentCancelled:
+		return true
+	}
+	return false
+}
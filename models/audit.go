@@ -0,0 +1,97 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Audit actions
+const (
+	AuditPatientCreate      = "patient.create"
+	AuditPatientRead        = "patient.read"
+	AuditPatientList        = "patient.list"
+	AuditPatientSearch      = "patient.search"
+	AuditPatientHistoryRead = "patient.history.read"
+	AuditPatientFamilyRead  = "patient.family_history.read"
+	AuditPatientUpdate      = "patient.update"
+	AuditPatientDelete      = "patient.delete"
+	AuditPatientRestore     = "patient.restore"
+	AuditPatientPurge       = "patient.purge"
+	AuditPatientEncounter   = "patient.encounter.create"
+
+	AuditPatientMedicationsRead  = "patient.medications.read"
+	AuditPrescriptionCreate      = "prescription.create"
+	AuditPrescriptionDiscontinue = "prescription.discontinue"
+	AuditPrescriptionOverride    = "prescription.override"
+	AuditPatientAllergiesRead    = "patient.allergies.read"
+	AuditAllergyCreate           = "allergy.create"
+	AuditAllergyDelete           = "allergy.delete"
+	AuditPatientDocumentPrint    = "patient.document.print"
+	AuditPatientVitalsRead       = "patient.vitals.read"
+	AuditPatientAppointmentsRead = "patient.appointments.read"
+	AuditVitalsCreate            = "vitals.create"
+	AuditPatientCareTeamUpdate   = "patient.care_team.update"
+	AuditPatientHouseholdRead    = "patient.household.read"
+	AuditPatientHouseholdUpdate  = "patient.household.update"
+	AuditPatientBreakGlass       = "patient.break_glass"
+	AuditPatientBreakGlassUse    = "patient.break_glass.access"
+
+	AuditRolePermissionsUpdate = "role.permissions.update"
+	AuditUserCreate            = "user.create"
+	AuditUserRegister          = "user.register"
+	AuditUserRoleChange        = "user.role.change"
+	AuditUserDisable           = "user.disable"
+	AuditUserEnable            = "user.enable"
+	AuditUserPasswordReset     = "user.password.reset"
+	AuditUserPasswordChange    = "user.password.change"
+	AuditUserPasswordLink      = "user.password.reset_link"
+	AuditUserPasswordExpire    = "user.password.require_change"
+	AuditLoginLockout          = "auth.lockout"
+	AuditLoginUnlock           = "auth.unlock"
+	AuditUserInviteCreate      = "user.invite.create"
+	AuditUserInviteRevoke      = "user.invite.revoke"
+	AuditUserSessionsRevoke    = "user.sessions.revoke"
+	AuditUserMFAEnable         = "user.mfa.enable"
+	AuditUserMFADisable        = "user.mfa.disable"
+	AuditUserMFAReset          = "user.mfa.reset"
+	AuditUserMFARecoveryCodes  = "user.mfa.recovery_codes"
+	AuditRoleMFAUpdate         = "role.mfa.update"
+	AuditRolePatientScope      = "role.patient_scope.update"
+)
+
+var ErrAuditImmutable = errors.New("audit events are append-only")
+
+// JSONText is JSON stored as text and emitted as raw JSON in responses
+type JSONText string
+
+func (j JSONText) MarshalJSON() ([]byte, error) {
+	if j == "" {
+		return []byte("null"), nil
+	}
+	return []byte(j), nil
+}
+
+// AuditEvent is one append-only access record. Rows are never updated or deleted.
+type AuditEvent struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	ActorID   uint   `json:"actor_id" gorm:"index"`
+	ActorRole string `json:"actor_role"`
+	Action    string `json:"action" gorm:"index"`
+	PatientID *uint  `json:"patient_id" gorm:"index"`
+	ClientIP  string `json:"client_ip"`
+	// Snapshots and field-level diff for writes
+	Before    JSONText  `json:"before" gorm:"type:text"`
+	After     JSONText  `json:"after" gorm:"type:text"`
+	Diff      JSONText  `json:"diff" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+func (AuditEvent) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditImmutable
+}
+
+func (AuditEvent) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditImmutable
+}
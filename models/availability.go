@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// DoctorAvailability is one working window in a doctor's weekly template.
+// A doctor may have several windows per weekday (e.g. split shifts).
+type DoctorAvailability struct {
+	ID       uint  `json:"id" gorm:"primaryKey"`
+	DoctorID uint  `json:"doctor_id" gorm:"index;not null"`
+	Doctor   *User `json:"-" gorm:"foreignKey:DoctorID"`
+	// 0 = Sunday ... 6 = Saturday
+	Weekday int `json:"weekday"`
+	// Clock times in "15:04" format, clinic local time
+	StartTime   string    `json:"start_time"`
+	EndTime     string    `json:"end_time"`
+	SlotMinutes int       `json:"slot_minutes"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DoctorBreak is a recurring weekly break (lunch, rounds) inside working hours
+type DoctorBreak struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	DoctorID  uint      `json:"doctor_id" gorm:"index;not null"`
+	Doctor    *User     `json:"-" gorm:"foreignKey:DoctorID"`
+	Weekday   int       `json:"weekday"`
+	StartTime string    `json:"start_time"`
+	EndTime   string    `json:"end_time"`
+	Label     string    `json:"label"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DoctorLeave blocks whole days, StartDate through EndDate inclusive
+type DoctorLeave struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	DoctorID  uint      `json:"doctor_id" gorm:"index;not null"`
+	Doctor    *User     `json:"-" gorm:"foreignKey:DoctorID"`
+	StartDate time.Time `json:"start_date" gorm:"type:date;not null"`
+	EndDate   time.Time `json:"end_date" gorm:"type:date;not null"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Covers reports whether the leave includes the calendar day of t
+func (l DoctorLeave) Covers(t time.Time) bool {
+	day := t.Format("2006-01-02")
+	return day >= l.StartDate.Format("2006-01-02") && day <= l.EndDate.Format("2006-01-02")
+}
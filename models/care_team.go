@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// CareTeamMember gives a staff member besides the primary doctor access to
+// a patient, e.g. a consultant or the ward nurse
+type CareTeamMember struct {
+	PatientID uint      `json:"patient_id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"primaryKey;index"`
+	User      *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Note      string    `json:"note"`
+	AddedByID uint      `json:"added_by_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BreakGlassAccess is emergency access to a patient outside the caller's
+// care team. It needs a reason, expires on its own and every use is audited.
+type BreakGlassAccess struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PatientID uint      `json:"patient_id" gorm:"index;not null"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	User      *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Reason    string    `json:"reason" gorm:"not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// RolePatientScope says whether members of a role only see the patients
+// they are assigned to
+type RolePatientScope struct {
+	Role         string    `json:"role" gorm:"primaryKey"`
+	AssignedOnly bool      `json:"assigned_only"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// DefaultAssignedOnlyRoles are limited to their own patients until an admin
+// says otherwise
+var DefaultAssignedOnlyRoles = map[string]bool{
+	RoleDoctor: true,
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Relationships relative to the head of household
+var Relationships = []string{
+	"self", "spouse", "son", "daughter", "father", "mother", "brother", "sister",
+	"grandfather", "grandmother", "grandson", "granddaughter", "guardian", "other",
+}
+
+func ValidRelationship(r string) bool {
+	for _, valid := range Relationships {
+		if r == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Household groups patients who are one family, independent of phone numbers
+type Household struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+	// Contact number for the household; not used for grouping
+	PhoneNumber   string            `json:"phone_number"`
+	HeadPatientID *uint             `json:"head_patient_id" gorm:"index"`
+	HeadPatient   *Patient          `json:"-" gorm:"foreignKey:HeadPatientID"`
+	Members       []HouseholdMember `json:"members" gorm:"foreignKey:HouseholdID"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// HouseholdMember links a patient to one household
+type HouseholdMember struct {
+	ID          uint     `json:"id" gorm:"primaryKey"`
+	HouseholdID uint     `json:"household_id" gorm:"index;not null"`
+	PatientID   uint     `json:"patient_id" gorm:"uniqueIndex;not null"`
+	Patient     *Patient `json:"patient,omitempty" gorm:"foreignKey:PatientID"`
+	// Relative to the head of household; the head is "self"
+	Relationship string    `json:"relationship"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// UserInvite lets one person register with a role chosen by an admin. Only
+// a hash of the token is stored; the token itself is shown once.
+type UserInvite struct {
+	ID                 uint       `json:"id" gorm:"primaryKey"`
+	TokenHash          string     `json:"-" gorm:"uniqueIndex;not null"`
+	Email              string     `json:"email" gorm:"index;not null"`
+	Name               string     `json:"name"`
+	Role               string     `json:"role" gorm:"not null"`
+	RegistrationNumber string     `json:"registration_number"`
+	InvitedByID        uint       `json:"invited_by_id"`
+	ExpiresAt          time.Time  `json:"expires_at"`
+	UsedAt             *time.Time `json:"used_at"`
+	UserID             *uint      `json:"user_id"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// Usable reports whether the invite can still be redeemed
+func (i UserInvite) Usable(now time.Time) bool {
+	return i.UsedAt == nil && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// UserMFA is a user's TOTP authenticator. It only counts, and login only asks
+// for a code, once the user has confirmed it with a code from their app.
+type UserMFA struct {
+	UserID      uint       `json:"user_id" gorm:"primaryKey"`
+	Secret      string     `json:"-" gorm:"not null"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	// Time step of the last accepted code, so a code can't be replayed
+	LastStep  int64     `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (m UserMFA) Enabled() bool {
+	return m.ConfirmedAt != nil
+}
+
+// RecoveryCode is a single-use code for signing in without the authenticator
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	CodeHash  string `gorm:"index;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// MFAChallenge is handed out after a correct password and swapped for a
+// session once the second factor checks out. With Enroll set the user has no
+// authenticator yet and must set one up to finish signing in.
+type MFAChallenge struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	TokenHash string `gorm:"uniqueIndex;not null"`
+	Enroll    bool
+	Attempts  int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// RoleMFAPolicy says whether members of a role must use MFA to sign in
+type RoleMFAPolicy struct {
+	Role      string    `json:"role" gorm:"primaryKey"`
+	Required  bool      `json:"required"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DefaultMFARoles must use MFA until an admin says otherwise
+var DefaultMFARoles = map[string]bool{
+	RoleDoctor: true,
+}
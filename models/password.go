@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// PasswordHistory keeps hashes of a user's previous passwords so they can't
+// be reused
+type PasswordHistory struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	Hash      string `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// PasswordResetToken is a single-use link an admin sends to a user who
+// can't sign in. Only the hash is stored; the link goes out by notifier.
+type PasswordResetToken struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"index;not null"`
+	TokenHash     string     `json:"-" gorm:"uniqueIndex;not null"`
+	RequestedByID uint       `json:"requested_by_id"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	UsedAt        *time.Time `json:"used_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// LoginAttempt counts failed sign-ins for one account or client IP when
+// LOGIN_ATTEMPT_STORE=database. Key is "account:<email>" or "ip:<address>".
+type LoginAttempt struct {
+	Key           string `gorm:"primaryKey"`
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+	UpdatedAt     time.Time
+}
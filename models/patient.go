@@ -1,26 +1,45 @@
 package models
 
-import "time"
+import (
+    "time"
+
+    "gorm.io/gorm"
+)
 
 type Patient struct {
     ID              uint             `json:"id" gorm:"primaryKey"`
     Name            string           `json:"name"`
-    Age             int              `json:"age"`
+    // Age is computed from DateOfBirth whenever the patient is loaded
+    Age             int              `json:"age" gorm:"-"`
+    DateOfBirth     Date             `json:"date_of_birth"`
+    // Set when DateOfBirth was estimated from the old static age column
+    DOBEstimated    bool             `json:"dob_estimated"`
     Gender          string           `json:"gender"`
-    Diagnosis       string           `json:"diagnosis"`
+    // Clinical fields are left out for callers without patient.clinical.read (see Project).
+    // Diagnosis, MedicalNotes and Prescriptions mirror the latest encounter
+    // and only change when one is recorded (repository.CreateEncounter).
+    Diagnosis       string           `json:"diagnosis" access:"clinical"`
     PhoneNumber     string           `json:"phone_number"`
     // New relationship field
     Relationship    string           `json:"relationship"`     // self, son, daughter, mother, father, spouse, etc.
-    // Medical fields for doctor updates
-    MedicalNotes    string           `json:"medical_notes"`
-    Prescriptions   string           `json:"prescriptions"`
+    // Doctor responsible for the patient; see also CareTeamMember
+    PrimaryDoctorID *uint            `json:"primary_doctor_id" gorm:"index"`
+    MedicalNotes    string           `json:"medical_notes" access:"clinical"`
+    Prescriptions   string           `json:"prescriptions" access:"clinical"`
     LastCheckup     string           `json:"last_checkup"`
     NextAppointment string           `json:"next_appointment"`
     // Relationship with medical history
-    MedicalHistory  []MedicalHistory `json:"medical_history" gorm:"foreignKey:PatientID"`
+    MedicalHistory  []MedicalHistory `json:"medical_history" gorm:"foreignKey:PatientID" access:"clinical"`
     // Timestamps
     CreatedAt       time.Time        `json:"created_at"`
     UpdatedAt       time.Time        `json:"updated_at"`
+    // Bumped whenever the patient's details, clinical fields or primary
+    // doctor change; sent as the ETag for optimistic concurrency
+    Version         uint             `json:"version" gorm:"not null;default:1"`
+    // Soft delete (archive); rows are only removed by the admin purge
+    DeletedAt       gorm.DeletedAt   `json:"deleted_at" gorm:"index"`
+    DeletedBy       *uint            `json:"deleted_by,omitempty"`
+    DeletionReason  string           `json:"deletion_reason,omitempty"`
 }
 
 type MedicalHistory struct {
@@ -29,12 +48,35 @@ type MedicalHistory struct {
     PatientName   string    `json:"patient_name"`
     PhoneNumber   string    `json:"phone_number"`
     Relationship  string    `json:"relationship"`      // Added relationship tracking
-    Age           int       `json:"age"`               // Added age at time of visit
+    // Snapshot of the patient's date of birth; Age is the age at VisitDate
+    DateOfBirth   Date      `json:"date_of_birth"`
+    Age           int       `json:"age" gorm:"-"`
     Gender        string    `json:"gender"`            // Added gender
+    // Authenticated user who recorded the visit; DoctorName is a snapshot of their name
+    DoctorID      *uint     `json:"doctor_id" gorm:"index"`
+    Doctor        *User     `json:"-" gorm:"foreignKey:DoctorID"`
     DoctorName    string    `json:"doctor_name"`
     VisitDate     time.Time `json:"visit_date"`
-    Diagnosis     string    `json:"diagnosis"`
-    MedicalNotes  string    `json:"medical_notes"`
-    Prescriptions string    `json:"prescriptions"`
+    // What was recorded at the encounter
+    ChiefComplaint string   `json:"chief_complaint" access:"clinical"`
+    Findings      string    `json:"findings" access:"clinical"`
+    Diagnosis     string    `json:"diagnosis" access:"clinical"`
+    Plan          string    `json:"plan" access:"clinical"`
+    MedicalNotes  string    `json:"medical_notes" access:"clinical"`
+    Prescriptions string    `json:"prescriptions" access:"clinical"`
     CreatedAt     time.Time `json:"created_at"`
-}
\ No newline at end of file
+    // Archived together with the patient
+    DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+    DeletedBy      *uint          `json:"deleted_by,omitempty"`
+    DeletionReason string         `json:"deletion_reason,omitempty"`
+}
+
+func (p *Patient) AfterFind(tx *gorm.DB) error {
+    p.Age = p.DateOfBirth.AgeOn(time.Now())
+    return nil
+}
+
+func (h *MedicalHistory) AfterFind(tx *gorm.DB) error {
+    h.Age = h.DateOfBirth.AgeOn(h.VisitDate)
+    return nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Prescription statuses
+const (
+	PrescriptionActive       = "active"
+	PrescriptionDiscontinued = "discontinued"
+)
+
+// Medication is an entry in the local medication catalog
+type Medication struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"not null;uniqueIndex:idx_medication_product"`
+	GenericName string `json:"generic_name" gorm:"index"`
+	Strength    string `json:"strength" gorm:"uniqueIndex:idx_medication_product"`
+	Form        string `json:"form" gorm:"uniqueIndex:idx_medication_product"`
+	Route       string `json:"route"`
+	// Optional external code, e.g. RxNorm or ATC
+	Code      string    `json:"code" gorm:"index"`
+	Active    bool      `json:"active" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Prescription is one medication ordered for a patient, optionally at a visit
+type Prescription struct {
+	ID        uint `json:"id" gorm:"primaryKey"`
+	PatientID uint `json:"patient_id" gorm:"index;not null"`
+	// Visit (medical history entry) the prescription was written at
+	VisitID      *uint       `json:"visit_id" gorm:"index"`
+	MedicationID *uint       `json:"medication_id" gorm:"index"`
+	Medication   *Medication `json:"medication,omitempty" gorm:"foreignKey:MedicationID"`
+	// Drug details are copied from the catalog so later catalog edits
+	// don't change what was prescribed
+	Drug         string `json:"drug" gorm:"not null"`
+	Strength     string `json:"strength"`
+	Form         string `json:"form"`
+	Dose         string `json:"dose"`
+	Route        string `json:"route"`
+	Frequency    string `json:"frequency"`
+	DurationDays int    `json:"duration_days"`
+	Quantity     int    `json:"quantity"`
+	Refills      int    `json:"refills"`
+	Instructions string `json:"instructions"`
+	// Why the prescriber went ahead despite an allergy or interaction warning
+	OverrideReason string `json:"override_reason,omitempty"`
+	PrescriberID   uint   `json:"prescriber_id" gorm:"index;not null"`
+	Prescriber     *User  `json:"-" gorm:"foreignKey:PrescriberID"`
+	Status         string `json:"status" gorm:"index;not null;default:active"`
+	StartDate      Date   `json:"start_date"`
+	// Derived from StartDate and DurationDays; zero for open-ended courses
+	EndDate           Date       `json:"end_date"`
+	DiscontinuedAt    *time.Time `json:"discontinued_at,omitempty"`
+	DiscontinuedByID  *uint      `json:"discontinued_by_id,omitempty"`
+	DiscontinueReason string     `json:"discontinue_reason,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// IsActiveOn reports whether the patient should still be taking the medication on day t
+func (p Prescription) IsActiveOn(t time.Time) bool {
+	if p.Status != PrescriptionActive {
+		return false
+	}
+	return p.EndDate.IsZero() || !NewDate(t).After(p.EndDate.Time)
+}
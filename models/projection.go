@@ -0,0 +1,195 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Access levels for the `access` struct tag. Untagged fields go to anyone
+// who may read the record; tagged ones only to callers who may see that level.
+const AccessClinical = "clinical"
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// Project prepares v for JSON, leaving out fields tagged access:"<level>"
+// unless visible(level). Nested structs, pointers, slices and maps are
+// projected too, so a patient inside an appointment loses the same fields.
+// Field names, order and omitempty follow the json tags.
+func Project(v interface{}, visible func(level string) bool) interface{} {
+	if v == nil {
+		return nil
+	}
+	return project(reflect.ValueOf(v), visible)
+}
+
+// FieldNames lists the json names of the fields of struct v tagged
+// access:"<level>", e.g. to strip them from data that is no longer typed
+func FieldNames(v interface{}, level string) []string {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Tag.Get("access") != level {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "" {
+			name = sf.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func project(v reflect.Value, visible func(string) bool) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	// Types with their own encoding (time.Time, Date, JSONText, ...) are
+	// left to it
+	if v.Type().Implements(jsonMarshalerType) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil
+		}
+		return v.Interface()
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(jsonMarshalerType) {
+		return v.Addr().Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return project(v.Elem(), visible)
+	case reflect.Struct:
+		obj := projectedObject{}
+		projectStruct(v, visible, &obj)
+		return obj
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		fallthrough
+	case reflect.Array:
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = project(v.Index(i), visible)
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return v.Interface()
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = project(iter.Value(), visible)
+		}
+		return out
+	}
+	return v.Interface()
+}
+
+func projectStruct(v reflect.Value, visible func(string) bool, obj *projectedObject) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if level := sf.Tag.Get("access"); level != "" && !visible(level) {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+
+		// Embedded structs without a json name are flattened, as encoding/json does
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv, ft = fv.Elem(), ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				projectStruct(fv, visible, obj)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(fv) {
+			continue
+		}
+		*obj = append(*obj, projectedField{name, project(fv, visible)})
+	}
+}
+
+// isEmptyValue matches encoding/json's idea of empty for omitempty
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+type projectedField struct {
+	name  string
+	value interface{}
+}
+
+// projectedObject is a JSON object that keeps the struct's field order
+type projectedObject []projectedField
+
+func (o projectedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,151 @@
+package models
+
+import "time"
+
+// Roles
+const (
+	RoleReceptionist = "receptionist"
+	RoleDoctor       = "doctor"
+	RoleNurse        = "nurse"
+	RoleAdmin        = "admin"
+	RolePharmacist   = "pharmacist"
+	RoleLab          = "lab"
+)
+
+var Roles = []string{RoleReceptionist, RoleDoctor, RoleNurse, RoleAdmin, RolePharmacist, RoleLab}
+
+// Permissions. Routes require one of these instead of checking role names.
+const (
+	PermPatientRead           = "patient.read"
+	PermPatientCreate         = "patient.create"
+	PermPatientUpdate         = "patient.update"
+	PermPatientDelete         = "patient.delete"
+	PermPatientPurge          = "patient.purge"
+	PermPatientClinicalRead   = "patient.clinical.read"
+	PermPatientClinicalWrite  = "patient.clinical.write"
+	PermPatientClinicalSearch = "patient.clinical.search"
+	PermPatientAssign         = "patient.assign"
+	PermPrescriptionWrite     = "prescription.write"
+	PermVitalsWrite           = "vitals.write"
+	PermMedicationRead        = "medication.read"
+	PermHouseholdRead         = "household.read"
+	PermHouseholdManage       = "household.manage"
+	PermAppointmentRead       = "appointment.read"
+	PermAppointmentManage     = "appointment.manage"
+	PermAppointmentComplete   = "appointment.complete"
+	PermScheduleRead          = "schedule.read"
+	PermScheduleManage        = "schedule.manage"
+	PermScheduleManageOwn     = "schedule.manage.own"
+	PermCatalogManage         = "catalog.manage"
+	PermAuditRead             = "audit.read"
+	PermUserManage            = "user.manage"
+	PermRoleManage            = "role.manage"
+)
+
+// Permissions describes every permission, in display order
+var Permissions = []PermissionInfo{
+	{PermPatientRead, "View, list and search patient demographics"},
+	{PermPatientCreate, "Register new patients"},
+	{PermPatientUpdate, "Edit patient demographics"},
+	{PermPatientDelete, "Archive and restore patients"},
+	{PermPatientPurge, "Permanently purge archived patients"},
+	{PermPatientClinicalRead, "View diagnoses, notes, visit history, allergies, medications, vitals and visit documents"},
+	{PermPatientClinicalWrite, "Record diagnoses, notes and allergies"},
+	{PermPatientClinicalSearch, "Match patient search against diagnoses, notes and prescriptions"},
+	{PermPatientAssign, "Assign patients to a primary doctor and care team"},
+	{PermPrescriptionWrite, "Prescribe and discontinue medication"},
+	{PermVitalsWrite, "Record vitals"},
+	{PermMedicationRead, "Search the medication catalog"},
+	{PermHouseholdRead, "View households"},
+	{PermHouseholdManage, "Create households and change their members"},
+	{PermAppointmentRead, "View appointments"},
+	{PermAppointmentManage, "Book and cancel appointments, check patients in, mark no-shows"},
+	{PermAppointmentComplete, "Complete or mark no-show on one's own appointments"},
+	{PermScheduleRead, "View doctors, availability and free slots"},
+	{PermScheduleManage, "Change any doctor's availability and leave"},
+	{PermScheduleManageOwn, "Change one's own availability and leave"},
+	{PermCatalogManage, "Import medications and interaction rules, edit vital reference ranges"},
+	{PermAuditRead, "Read the audit trail"},
+	{PermUserManage, "Manage staff accounts"},
+	{PermRoleManage, "Change which permissions each role has"},
+}
+
+type PermissionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// DefaultRolePermissions is installed when the role_permissions table is
+// empty; after that admins edit the matrix through the API. Changes to it
+// reach existing installs only through PermissionChanges.
+var DefaultRolePermissions = map[string][]string{
+	RoleReceptionist: {
+		PermPatientRead, PermPatientCreate, PermPatientUpdate, PermPatientDelete,
+		PermPatientAssign, PermVitalsWrite, PermHouseholdRead, PermHouseholdManage,
+		PermAppointmentRead, PermAppointmentManage, PermScheduleRead, PermScheduleManage,
+	},
+	RoleDoctor: {
+		PermPatientRead, PermPatientUpdate, PermPatientClinicalRead, PermPatientClinicalWrite, PermPatientClinicalSearch,
+		PermPrescriptionWrite, PermVitalsWrite, PermMedicationRead, PermHouseholdRead,
+		PermAppointmentRead, PermAppointmentComplete, PermScheduleRead, PermScheduleManageOwn,
+	},
+	RoleNurse: {
+		PermPatientRead, PermPatientClinicalRead, PermPatientClinicalSearch, PermVitalsWrite, PermMedicationRead,
+		PermHouseholdRead, PermAppointmentRead, PermScheduleRead,
+	},
+	RoleAdmin: {
+		PermPatientPurge, PermCatalogManage, PermAuditRead, PermUserManage, PermRoleManage,
+	},
+	RolePharmacist: {
+		PermPatientRead, PermPatientClinicalRead, PermMedicationRead,
+	},
+	RoleLab: {
+		PermPatientRead,
+	},
+}
+
+// PermissionChange is a change to DefaultRolePermissions made after installs
+// were already seeded
+type PermissionChange struct {
+	ID     string
+	Grant  map[string][]string
+	Revoke map[string][]string
+}
+
+// PermissionChanges are applied once each, in order, to installs seeded
+// before them; new installs get them with the defaults. Don't edit an entry
+// once released, add a new one.
+var PermissionChanges = []PermissionChange{
+	{ID: "receptionist-patient-assign", Grant: map[string][]string{RoleReceptionist: {PermPatientAssign}}},
+	{ID: "receptionist-no-clinical-read", Revoke: map[string][]string{RoleReceptionist: {PermPatientClinicalRead}}},
+}
+
+// AppliedPermissionChange records that a PermissionChange has been made
+type AppliedPermissionChange struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// RolePermission is one cell of the role/permission matrix
+type RolePermission struct {
+	Role       string `json:"role" gorm:"primaryKey"`
+	Permission string `json:"permission" gorm:"primaryKey"`
+}
+
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidPermission(name string) bool {
+	for _, p := range Permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
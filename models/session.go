@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Session revoke reasons
+const (
+	SessionLogout       = "logout"
+	SessionLogoutAll    = "logout-all"
+	SessionReuse        = "refresh-token-reuse"
+	SessionAdminRevoked = "admin"
+	SessionMFARequired  = "mfa-required"
+	SessionPasswordSet  = "password-changed"
+)
+
+// Session is one sign-in on one device. Access tokens carry its ID and stop
+// working as soon as it is revoked.
+type Session struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"index;not null"`
+	UserAgent    string     `json:"user_agent"`
+	ClientIP     string     `json:"client_ip"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	LastUsedAt   time.Time  `json:"last_used_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	RevokeReason string     `json:"revoke_reason,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RefreshToken is one link in a session's rotation chain. Only the newest
+// unused token is valid; presenting a used one means it was stolen, and the
+// whole session is revoked.
+type RefreshToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	SessionID uint   `gorm:"index;not null"`
+	TokenHash string `gorm:"uniqueIndex;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
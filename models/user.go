@@ -1,4 +1,5 @@
 package models
+import "time"
 import "github.com/golang-jwt/jwt/v4"
 import "gorm.io/gorm"
 
@@ -8,8 +9,20 @@ type User struct {
 	gorm.Model
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" gorm:"unique" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Password string `json:"-" binding:"required"`
 	Role     string `json:"role" binding:"required"`
+	// Medical council registration number, printed on prescriptions
+	RegistrationNumber string `json:"registration_number"`
+	// Set when an admin disables the account; disabled users cannot sign in
+	DisabledAt *time.Time `json:"disabled_at"`
+	// Set for temporary passwords; API access is blocked until the user
+	// picks their own
+	MustChangePassword bool       `json:"must_change_password"`
+	PasswordChangedAt  *time.Time `json:"password_changed_at"`
+}
+
+func (u User) Disabled() bool {
+	return u.DisabledAt != nil
 }
 //model for login in
 
@@ -22,5 +35,7 @@ type LoginInput struct {
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Role   string `json:"role"`
+	// Session the token was issued for; checked for revocation on every request
+	SessionID uint `json:"sid"`
 	jwt.RegisteredClaims
 }
\ No newline at end of file
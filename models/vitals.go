@@ -0,0 +1,162 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Vital sign measures, as used in reference ranges and time-series queries
+const (
+	MeasureSystolicBP          = "systolic_bp"
+	MeasureDiastolicBP         = "diastolic_bp"
+	MeasurePulse               = "pulse"
+	MeasureTemperature         = "temperature"
+	MeasureSpO2                = "spo2"
+	MeasureWeight              = "weight"
+	MeasureHeight              = "height"
+	MeasureBMI                 = "bmi"
+	MeasureGlucoseFasting      = "blood_glucose_fasting"
+	MeasureGlucoseRandom       = "blood_glucose_random"
+	MeasureGlucosePostprandial = "blood_glucose_post_meal"
+)
+
+// Measures lists every measure, in display order
+var Measures = []string{
+	MeasureSystolicBP, MeasureDiastolicBP, MeasurePulse, MeasureTemperature, MeasureSpO2,
+	MeasureWeight, MeasureHeight, MeasureBMI,
+	MeasureGlucoseFasting, MeasureGlucoseRandom, MeasureGlucosePostprandial,
+}
+
+// When a blood glucose reading was taken
+var GlucoseContexts = map[string]string{
+	"fasting":   MeasureGlucoseFasting,
+	"random":    MeasureGlucoseRandom,
+	"post-meal": MeasureGlucosePostprandial,
+}
+
+// Vitals is one set of measurements, optionally taken at a visit. Every
+// measurement is optional; units are mmHg, beats/min, °C, %, kg, cm, kg/m²
+// and mg/dL.
+type Vitals struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	PatientID      uint      `json:"patient_id" gorm:"index:idx_vitals_patient_time;not null"`
+	VisitID        *uint     `json:"visit_id" gorm:"index"`
+	RecordedAt     time.Time `json:"recorded_at" gorm:"index:idx_vitals_patient_time;not null"`
+	RecordedByID   uint      `json:"recorded_by_id"`
+	SystolicBP     *int      `json:"systolic_bp"`
+	DiastolicBP    *int      `json:"diastolic_bp"`
+	Pulse          *int      `json:"pulse"`
+	Temperature    *float64  `json:"temperature"`
+	SpO2           *int      `json:"spo2"`
+	Weight         *float64  `json:"weight"`
+	Height         *float64  `json:"height"`
+	BMI            *float64  `json:"bmi"`
+	BloodGlucose   *float64  `json:"blood_glucose"`
+	GlucoseContext string    `json:"glucose_context,omitempty"`
+	Notes          string    `json:"notes"`
+	// Abnormal readings against the current reference ranges, filled on read
+	Flags     []VitalFlag `json:"flags" gorm:"-"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Values returns the recorded measurements keyed by measure
+func (v Vitals) Values() map[string]float64 {
+	values := map[string]float64{}
+	setInt := func(measure string, p *int) {
+		if p != nil {
+			values[measure] = float64(*p)
+		}
+	}
+	setFloat := func(measure string, p *float64) {
+		if p != nil {
+			values[measure] = *p
+		}
+	}
+	setInt(MeasureSystolicBP, v.SystolicBP)
+	setInt(MeasureDiastolicBP, v.DiastolicBP)
+	setInt(MeasurePulse, v.Pulse)
+	setFloat(MeasureTemperature, v.Temperature)
+	setInt(MeasureSpO2, v.SpO2)
+	setFloat(MeasureWeight, v.Weight)
+	setFloat(MeasureHeight, v.Height)
+	setFloat(MeasureBMI, v.BMI)
+	if v.BloodGlucose != nil {
+		measure, ok := GlucoseContexts[v.GlucoseContext]
+		if !ok {
+			measure = MeasureGlucoseRandom
+		}
+		values[measure] = *v.BloodGlucose
+	}
+	return values
+}
+
+// ComputeBMI sets BMI from weight (kg) and height (cm), rounded to one decimal
+func (v *Vitals) ComputeBMI() {
+	if v.Weight == nil || v.Height == nil || *v.Height <= 0 {
+		return
+	}
+	m := *v.Height / 100
+	bmi := math.Round(*v.Weight/(m*m)*10) / 10
+	v.BMI = &bmi
+}
+
+// Vital flag statuses
+const (
+	VitalLow          = "low"
+	VitalHigh         = "high"
+	VitalCriticalLow  = "critical-low"
+	VitalCriticalHigh = "critical-high"
+)
+
+type VitalFlag struct {
+	Measure string   `json:"measure"`
+	Value   float64  `json:"value"`
+	Status  string   `json:"status"`
+	Low     *float64 `json:"low,omitempty"`
+	High    *float64 `json:"high,omitempty"`
+	Unit    string   `json:"unit"`
+}
+
+// VitalReferenceRange is the normal band for one measure. Gender "" applies
+// to everyone; ages are in completed years, inclusive.
+type VitalReferenceRange struct {
+	ID           uint     `json:"id" gorm:"primaryKey"`
+	Measure      string   `json:"measure" gorm:"index;not null"`
+	Gender       string   `json:"gender"`
+	MinAge       int      `json:"min_age"`
+	MaxAge       int      `json:"max_age"`
+	Low          *float64 `json:"low"`
+	High         *float64 `json:"high"`
+	CriticalLow  *float64 `json:"critical_low"`
+	CriticalHigh *float64 `json:"critical_high"`
+	Unit         string   `json:"unit"`
+}
+
+// Applies reports whether the range covers a patient of this age and gender
+func (r VitalReferenceRange) Applies(age int, gender string) bool {
+	return age >= r.MinAge && age <= r.MaxAge && (r.Gender == "" || r.Gender == gender)
+}
+
+// Classify returns the flag status for value, or "" when it is in range
+func (r VitalReferenceRange) Classify(value float64) string {
+	switch {
+	case r.CriticalLow != nil && value < *r.CriticalLow:
+		return VitalCriticalLow
+	case r.CriticalHigh != nil && value > *r.CriticalHigh:
+		return VitalCriticalHigh
+	case r.Low != nil && value < *r.Low:
+		return VitalLow
+	case r.High != nil && value > *r.High:
+		return VitalHigh
+	}
+	return ""
+}
+
+func ValidMeasure(m string) bool {
+	for _, valid := range Measures {
+		if m == valid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+func GetPatientAllergies(patientID int) ([]models.PatientAllergy, error) {
+	var allergies []models.PatientAllergy
+	err := config.DB.Where("patient_id = ?", patientID).Order("substance").Find(&allergies).Error
+	return allergies, err
+}
+
+func CreatePatientAllergy(a *models.PatientAllergy) error {
+	return Atomically(func(uow UnitOfWork) error {
+		return uow.CreatePatientAllergy(a)
+	})
+}
+
+func createPatientAllergy(tx *gorm.DB, a *models.PatientAllergy) error {
+	if err := tx.First(&models.Patient{}, a.PatientID).Error; err != nil {
+		return err
+	}
+	return tx.Create(a).Error
+}
+
+// DeletePatientAllergy removes the allergy and returns it for auditing
+func DeletePatientAllergy(patientID, allergyID int) (models.PatientAllergy, error) {
+	var a models.PatientAllergy
+	err := Atomically(func(uow UnitOfWork) error {
+		var err error
+		a, err = uow.DeletePatientAllergy(patientID, allergyID)
+		return err
+	})
+	return a, err
+}
+
+func deletePatientAllergy(tx *gorm.DB, patientID, allergyID int) (models.PatientAllergy, error) {
+	var a models.PatientAllergy
+	if err := tx.Where("patient_id = ?", patientID).First(&a, allergyID).Error; err != nil {
+		return a, err
+	}
+	return a, tx.Delete(&a).Error
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+var (
+	ErrAppointmentConflict = conflictError("doctor already has an appointment in this time range")
+	ErrInvalidTransition   = conflictError("invalid appointment status change")
+)
+
+// Statuses that still block the doctor's calendar
+var activeAppointmentStatuses = []string{
+	models.AppointmentBooked,
+	models.AppointmentCheckedIn,
+	models.AppointmentCompleted,
+}
+
+// Allowed status changes, keyed by current status
+var appointmentTransitions = map[string][]string{
+	models.AppointmentBooked:    {models.AppointmentCheckedIn, models.AppointmentNoShow, models.AppointmentCancelled},
+	models.AppointmentCheckedIn: {models.AppointmentCompleted, models.AppointmentCancelled},
+}
+
+type AppointmentFilter struct {
+	DoctorID  uint
+	PatientID uint
+	Status    string
+	From      time.Time
+	To        time.Time
+	// Only patients this user may open (see assignedPatients); 0 for all
+	AssignedTo uint
+}
+
+// CreateAppointment books an appointment, refusing overlaps for the same doctor.
+// The doctor row is locked so two concurrent bookings can't both pass the check.
+func CreateAppointment(a *models.Appointment) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.User{}, a.DoctorID).Error; err != nil {
+			return err
+		}
+
+		var overlapping int64
+		err := tx.Model(&models.Appointment{}).
+			Where("doctor_id = ? AND status IN ?", a.DoctorID, activeAppointmentStatuses).
+			Where("start_time < ? AND end_time > ?", a.EndTime, a.StartTime).
+			Count(&overlapping).Error
+		if err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return ErrAppointmentConflict
+		}
+
+		a.Status = models.AppointmentBooked
+		if err := tx.Create(a).Error; err != nil {
+			return err
+		}
+		return syncPatientAppointmentFields(tx, a.PatientID)
+	})
+}
+
+func GetAppointmentByID(id int) (models.Appointment, error) {
+	var a models.Appointment
+	err := config.DB.Preload("Patient").Preload("Doctor").First(&a, id).Error
+	return a, err
+}
+
+func ListAppointments(f AppointmentFilter) ([]models.Appointment, error) {
+	var appointments []models.Appointment
+	q := config.DB.Preload("Patient").Preload("Doctor")
+	if f.DoctorID != 0 {
+		q = q.Where("doctor_id = ?", f.DoctorID)
+	}
+	if f.PatientID != 0 {
+		q = q.Where("patient_id = ?", f.PatientID)
+	}
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("start_time >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("start_time < ?", f.To)
+	}
+	if f.AssignedTo != 0 {
+		q = q.Where("patient_id IN (?)", assignedPatients(f.AssignedTo))
+	}
+	err := q.Order("start_time").Find(&appointments).Error
+	return appointments, err
+}
+
+// GetDoctorDayAppointments returns a doctor's appointments starting on the given day
+func GetDoctorDayAppointments(doctorID uint, day time.Time) ([]models.Appointment, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	return ListAppointments(AppointmentFilter{
+		DoctorID: doctorID,
+		From:     start,
+		To:       start.AddDate(0, 0, 1),
+	})
+}
+
+// UpdateAppointmentStatus moves an appointment along its lifecycle
+func UpdateAppointmentStatus(id int, status, reason string) (models.Appointment, error) {
+	var a models.Appointment
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&a, id).Error; err != nil {
+			return err
+		}
+		if !canTransition(a.Status, status) {
+			return ErrInvalidTransition
+		}
+
+		a.Status = status
+		if status == models.AppointmentCancelled {
+			a.CancelReason = reason
+		}
+		if err := tx.Save(&a).Error; err != nil {
+			return err
+		}
+		return syncPatientAppointmentFields(tx, a.PatientID)
+	})
+	return a, err
+}
+
+func canTransition(from, to string) bool {
+	for _, s := range appointmentTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// syncPatientAppointmentFields keeps the legacy NextAppointment / LastCheckup
+// strings on the patient derived from the appointments table. LastCheckup is
+// left alone until the patient has a completed appointment.
+func syncPatientAppointmentFields(tx *gorm.DB, patientID uint) error {
+	next := ""
+	var upcoming models.Appointment
+	err := tx.Where("patient_id = ? AND status IN ? AND start_time >= ?",
+		patientID, []string{models.AppointmentBooked, models.AppointmentCheckedIn}, time.Now()).
+		Order("start_time").First(&upcoming).Error
+	if err == nil {
+		next = upcoming.StartTime.Format("2006-01-02 15:04")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	updates := map[string]interface{}{"next_appointment": next}
+	var completed models.Appointment
+	err = tx.Where("patient_id = ? AND status = ?", patientID, models.AppointmentCompleted).
+		Order("start_time DESC").First(&completed).Error
+	if err == nil {
+		updates["last_checkup"] = completed.StartTime.Format("2006-01-02")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return tx.Model(&models.Patient{}).Where("id = ?", patientID).Updates(updates).Error
+}
+
+// GetDoctorBusyAppointments returns the appointments that block a doctor's
+// calendar and overlap [from, to)
+func GetDoctorBusyAppointments(doctorID uint, from, to time.Time) ([]models.Appointment, error) {
+	var appointments []models.Appointment
+	err := config.DB.Where("doctor_id = ? AND status IN ?", doctorID, activeAppointmentStatuses).
+		Where("start_time < ? AND end_time > ?", to, from).
+		Order("start_time").Find(&appointments).Error
+	return appointments, err
+}
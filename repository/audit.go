@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+type AuditFilter struct {
+	ActorID   uint
+	PatientID uint
+	Action    string
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Offset    int
+}
+
+// CreateAuditEvents appends events; there is deliberately no update or delete
+func CreateAuditEvents(events []models.AuditEvent) error {
+	return createAuditEvents(config.DB, events)
+}
+
+func createAuditEvents(db *gorm.DB, events []models.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return db.CreateInBatches(&events, 500).Error
+}
+
+func ListAuditEvents(f AuditFilter) ([]models.AuditEvent, int64, error) {
+	var events []models.AuditEvent
+	var total int64
+
+	q := config.DB.Model(&models.AuditEvent{})
+	if f.ActorID != 0 {
+		q = q.Where("actor_id = ?", f.ActorID)
+	}
+	if f.PatientID != 0 {
+		q = q.Where("patient_id = ?", f.PatientID)
+	}
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("created_at < ?", f.To)
+	}
+
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := q.Order("created_at DESC, id DESC").Limit(f.Limit).Offset(f.Offset).Find(&events).Error
+	return events, total, err
+}
+
+// ProtectAuditTable installs a trigger so the audit table stays append-only
+// even for writes that bypass GORM hooks.
+func ProtectAuditTable() error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_events_no_modify ON audit_events`,
+		`CREATE TRIGGER audit_events_no_modify
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only()`,
+	}
+	for _, stmt := range statements {
+		if err := config.DB.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+func GetDoctorSchedule(doctorID uint) ([]models.DoctorAvailability, []models.DoctorBreak, error) {
+	var hours []models.DoctorAvailability
+	var breaks []models.DoctorBreak
+	if err := config.DB.Where("doctor_id = ?", doctorID).Order("weekday, start_time").Find(&hours).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := config.DB.Where("doctor_id = ?", doctorID).Order("weekday, start_time").Find(&breaks).Error; err != nil {
+		return nil, nil, err
+	}
+	return hours, breaks, nil
+}
+
+// ReplaceDoctorSchedule swaps the whole weekly template in one transaction
+func ReplaceDoctorSchedule(doctorID uint, hours []models.DoctorAvailability, breaks []models.DoctorBreak) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("doctor_id = ?", doctorID).Delete(&models.DoctorAvailability{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("doctor_id = ?", doctorID).Delete(&models.DoctorBreak{}).Error; err != nil {
+			return err
+		}
+		for i := range hours {
+			hours[i].ID = 0
+			hours[i].DoctorID = doctorID
+		}
+		for i := range breaks {
+			breaks[i].ID = 0
+			breaks[i].DoctorID = doctorID
+		}
+		if len(hours) > 0 {
+			if err := tx.Create(&hours).Error; err != nil {
+				return err
+			}
+		}
+		if len(breaks) > 0 {
+			if err := tx.Create(&breaks).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetDoctorLeaves returns leaves that end on or after the given day
+func GetDoctorLeaves(doctorID uint, from time.Time) ([]models.DoctorLeave, error) {
+	var leaves []models.DoctorLeave
+	err := config.DB.Where("doctor_id = ? AND end_date >= ?", doctorID, from.Format("2006-01-02")).
+		Order("start_date").Find(&leaves).Error
+	return leaves, err
+}
+
+func CreateDoctorLeave(l *models.DoctorLeave) error {
+	return config.DB.Create(l).Error
+}
+
+func DeleteDoctorLeave(doctorID uint, leaveID int) error {
+	result := config.DB.Where("doctor_id = ?", doctorID).Delete(&models.DoctorLeave{}, leaveID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+// careTeamCondition matches the patients userID is assigned to: as primary
+// doctor, as a care team member, or through an appointment that wasn't
+// cancelled
+func careTeamCondition(userID uint) (string, []interface{}) {
+	return "primary_doctor_id = ? OR id IN (?) OR id IN (?)", []interface{}{
+		userID,
+		config.DB.Model(&models.CareTeamMember{}).Select("patient_id").Where("user_id = ?", userID),
+		config.DB.Model(&models.Appointment{}).Select("patient_id").
+			Where("doctor_id = ? AND status <> ?", userID, models.AppointmentCancelled),
+	}
+}
+
+// assignedPatients selects the IDs of the patients userID may open: their
+// care team's patients plus those they have an unexpired break-glass grant for
+func assignedPatients(userID uint) *gorm.DB {
+	cond, args := careTeamCondition(userID)
+	return config.DB.Unscoped().Model(&models.Patient{}).Select("id").
+		Where(config.DB.Where(cond, args...).Or("id IN (?)",
+			config.DB.Model(&models.BreakGlassAccess{}).Select("patient_id").
+				Where("user_id = ? AND expires_at > ?", userID, time.Now())))
+}
+
+// IsOnCareTeam reports whether userID is assigned to the patient.
+// Break-glass grants don't count.
+func IsOnCareTeam(userID uint, patientID int) (bool, error) {
+	cond, args := careTeamCondition(userID)
+	var count int64
+	err := config.DB.Unscoped().Model(&models.Patient{}).
+		Where("id = ?", patientID).Where(cond, args...).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FilterAssignedPatients keeps the patients in ids that userID may open
+func FilterAssignedPatients(userID uint, ids []uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var allowed []uint
+	err := config.DB.Unscoped().Model(&models.Patient{}).
+		Where("id IN ? AND id IN (?)", ids, assignedPatients(userID)).
+		Pluck("id", &allowed).Error
+	return allowed, err
+}
+
+// GetCareTeam lists the patient's care team members with their accounts
+func GetCareTeam(patientID int) ([]models.CareTeamMember, error) {
+	return getCareTeam(config.DB, patientID)
+}
+
+func getCareTeam(db *gorm.DB, patientID int) ([]models.CareTeamMember, error) {
+	var members []models.CareTeamMember
+	err := db.Preload("User").Where("patient_id = ?", patientID).
+		Order("created_at").Find(&members).Error
+	return members, err
+}
+
+// SetPrimaryDoctor assigns the patient's primary doctor; nil clears it
+func SetPrimaryDoctor(patientID int, doctorID *uint) error {
+	return setPrimaryDoctor(config.DB, patientID, doctorID)
+}
+
+func setPrimaryDoctor(db *gorm.DB, patientID int, doctorID *uint) error {
+	res := db.Model(&models.Patient{}).Where("id = ?", patientID).Updates(map[string]interface{}{
+		"primary_doctor_id": doctorID,
+		"version":           gorm.Expr("version + 1"),
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AddCareTeamMember adds a member, or updates the note of an existing one
+func AddCareTeamMember(member *models.CareTeamMember) error {
+	return addCareTeamMember(config.DB, member)
+}
+
+func addCareTeamMember(db *gorm.DB, member *models.CareTeamMember) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "patient_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"note"}),
+	}).Create(member).Error
+}
+
+func RemoveCareTeamMember(patientID int, userID uint) error {
+	return removeCareTeamMember(config.DB, patientID, userID)
+}
+
+func removeCareTeamMember(db *gorm.DB, patientID int, userID uint) error {
+	res := db.Where("patient_id = ? AND user_id = ?", patientID, userID).Delete(&models.CareTeamMember{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func CreateBreakGlassAccess(grant *models.BreakGlassAccess) error {
+	return config.DB.Create(grant).Error
+}
+
+// GetActiveBreakGlass returns the caller's latest unexpired grant for a patient
+func GetActiveBreakGlass(userID uint, patientID int) (models.BreakGlassAccess, error) {
+	var grant models.BreakGlassAccess
+	err := config.DB.Where("user_id = ? AND patient_id = ? AND expires_at > ?", userID, patientID, time.Now()).
+		Order("expires_at DESC").First(&grant).Error
+	return grant, err
+}
+
+// BreakGlassQuery filters the break-glass review list; zero values match all
+type BreakGlassQuery struct {
+	UserID     uint
+	PatientID  uint
+	From       time.Time
+	To         time.Time
+	ActiveOnly bool
+	Limit      int
+}
+
+// ListBreakGlassAccess returns grants newest first, for review
+func ListBreakGlassAccess(q BreakGlassQuery) ([]models.BreakGlassAccess, error) {
+	db := config.DB.Preload("User")
+	if q.UserID != 0 {
+		db = db.Where("user_id = ?", q.UserID)
+	}
+	if q.PatientID != 0 {
+		db = db.Where("patient_id = ?", q.PatientID)
+	}
+	if !q.From.IsZero() {
+		db = db.Where("created_at >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		db = db.Where("created_at < ?", q.To)
+	}
+	if q.ActiveOnly {
+		db = db.Where("expires_at > ?", time.Now())
+	}
+	if q.Limit > 0 {
+		db = db.Limit(q.Limit)
+	}
+	var grants []models.BreakGlassAccess
+	err := db.Order("created_at DESC").Find(&grants).Error
+	return grants, err
+}
+
+// SeedRolePatientScopes adds a row for roles that have none; existing rows
+// are left alone
+func SeedRolePatientScopes(roles []string, defaults map[string]bool) error {
+	rows := make([]models.RolePatientScope, 0, len(roles))
+	for _, role := range roles {
+		rows = append(rows, models.RolePatientScope{Role: role, AssignedOnly: defaults[role]})
+	}
+	return config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+func GetRolePatientScopes() ([]models.RolePatientScope, error) {
+	var rows []models.RolePatientScope
+	err := config.DB.Order("role").Find(&rows).Error
+	return rows, err
+}
+
+func RoleAssignedOnly(role string) (bool, error) {
+	return roleAssignedOnly(config.DB, role)
+}
+
+func roleAssignedOnly(db *gorm.DB, role string) (bool, error) {
+	var s models.RolePatientScope
+	err := db.First(&s, "role = ?", role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return s.AssignedOnly, err
+}
+
+// AssignPrimaryDoctorsFromVisits gives unassigned patients the doctor who
+// recorded their latest visit. Run once, when primary doctors are introduced,
+// so doctors keep seeing the patients they have been treating.
+func AssignPrimaryDoctorsFromVisits() error {
+	return config.DB.Exec(`UPDATE patients p
+		SET primary_doctor_id = (
+			SELECT h.doctor_id FROM medical_histories h
+			WHERE h.patient_id = p.id AND h.doctor_id IS NOT NULL
+			ORDER BY h.visit_date DESC LIMIT 1)
+		WHERE p.primary_doctor_id IS NULL`).Error
+}
+
+func SetRoleAssignedOnly(role string, assignedOnly bool) (models.RolePatientScope, error) {
+	var s models.RolePatientScope
+	err := Atomically(func(uow UnitOfWork) error {
+		var err error
+		s, err = uow.SetRoleAssignedOnly(role, assignedOnly)
+		return err
+	})
+	return s, err
+}
+
+func setRoleAssignedOnly(tx *gorm.DB, role string, assignedOnly bool) (models.RolePatientScope, error) {
+	s := models.RolePatientScope{Role: role, AssignedOnly: assignedOnly}
+	err := tx.Save(&s).Error
+	return s, err
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+// CreateEncounter records a visit for e.PatientID by doctorID, snapshotting
+// the patient's demographics and the doctor's name, and makes it the
+// patient's latest diagnosis, notes and prescriptions.
+func CreateEncounter(e *models.MedicalHistory, doctorID uint) error {
+	return Atomically(func(uow UnitOfWork) error {
+		return uow.CreateEncounter(e, doctorID)
+	})
+}
+
+func createEncounter(tx *gorm.DB, e *models.MedicalHistory, doctorID uint) error {
+	var patient models.Patient
+	if err := tx.First(&patient, e.PatientID).Error; err != nil {
+		return err
+	}
+	var doctor models.User
+	if err := tx.First(&doctor, doctorID).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	e.PatientName = patient.Name
+	e.PhoneNumber = patient.PhoneNumber
+	e.Relationship = patient.Relationship
+	e.DateOfBirth = patient.DateOfBirth
+	e.Gender = patient.Gender
+	e.DoctorID = &doctor.ID
+	e.DoctorName = doctor.Name
+	e.VisitDate = now
+	e.CreatedAt = now
+	if err := tx.Create(e).Error; err != nil {
+		return err
+	}
+	e.Age = e.DateOfBirth.AgeOn(e.VisitDate)
+
+	return tx.Model(&patient).Updates(map[string]interface{}{
+		"diagnosis":     e.Diagnosis,
+		"medical_notes": e.MedicalNotes,
+		"prescriptions": e.Prescriptions,
+		"updated_at":    now,
+		"version":       gorm.Expr("version + 1"),
+	}).Error
+}
+
+// MigrateClinicalSnapshots records the diagnosis, notes and prescriptions
+// held on patients as an encounter wherever no visit has those values, e.g.
+// ones entered at registration. Once every patient's clinical fields match a
+// visit this does nothing, so it is safe to run on every start.
+func MigrateClinicalSnapshots() error {
+	return config.DB.Exec(`INSERT INTO medical_histories
+		(patient_id, patient_name, phone_number, relationship, date_of_birth, gender, doctor_name,
+		 visit_date, diagnosis, medical_notes, prescriptions, created_at, deleted_at, deleted_by, deletion_reason)
+		SELECT p.id, p.name, p.phone_number, p.relationship, p.date_of_birth, p.gender, '',
+		       p.updated_at, p.diagnosis, p.medical_notes, p.prescriptions, NOW(), p.deleted_at, p.deleted_by, p.deletion_reason
+		FROM patients p
+		WHERE (p.diagnosis <> '' OR p.medical_notes <> '' OR p.prescriptions <> '')
+		  AND NOT EXISTS (
+		      SELECT 1 FROM medical_histories h
+		      WHERE h.patient_id = p.id AND h.diagnosis = p.diagnosis
+		        AND h.medical_notes = p.medical_notes AND h.prescriptions = p.prescriptions)`).Error
+}
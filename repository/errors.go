@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of failure. The errors this package defines wrap one of them, so
+// callers can decide how to answer (404, 409, 400) without knowing each error.
+// Invalid tokens and sign-in challenges are the exception: they are
+// authentication failures and are checked for individually.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("invalid")
+)
+
+// Error is a failure of a known kind with a message meant for clients
+type Error struct {
+	Kind error
+	// Input field at fault, for validation errors
+	Field   string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Kind }
+
+func notFoundError(msg string) error { return &Error{Kind: ErrNotFound, Message: msg} }
+
+func conflictError(msg string) error { return &Error{Kind: ErrConflict, Message: msg} }
+
+func validationError(field, msg string) error {
+	return &Error{Kind: ErrValidation, Field: field, Message: msg}
+}
+
+// KindOf reports whether err is ErrNotFound, ErrConflict or ErrValidation,
+// counting gorm's errors for missing rows and violated constraints. Anything
+// else (a lost connection, a bug) is nil.
+func KindOf(err error) error {
+	var e *Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &e):
+		return e.Kind
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey), errors.Is(err, gorm.ErrForeignKeyViolated):
+		return ErrConflict
+	case errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return ErrValidation
+	}
+	return nil
+}
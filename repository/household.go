@@ -0,0 +1,321 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+var (
+	ErrAlreadyInHousehold = conflictError("patient already belongs to a household")
+	ErrHouseholdHead      = conflictError("the head of household cannot be removed; choose a new head first")
+	ErrNotHouseholdMember = notFoundError("patient is not a member of this household")
+)
+
+type RelationshipCount struct {
+	Relationship string `json:"relationship"`
+	Count        int64  `json:"count"`
+}
+
+type HouseholdSummary struct {
+	TotalVisits        int64               `json:"total_visits"`
+	UniqueMembers      int64               `json:"unique_members"`
+	RelationshipCounts []RelationshipCount `json:"relationship_counts"`
+	HasHistory         bool                `json:"has_history"`
+}
+
+// CreateHousehold creates the household with headPatientID as its "self" member
+func CreateHousehold(h *models.Household, headPatientID uint) error {
+	return Atomically(func(uow UnitOfWork) error {
+		return uow.CreateHousehold(h, headPatientID)
+	})
+}
+
+func createHousehold(tx *gorm.DB, h *models.Household, headPatientID uint) error {
+	if err := ensureNotInHousehold(tx, headPatientID); err != nil {
+		return err
+	}
+	h.HeadPatientID = &headPatientID
+	if err := tx.Omit("Members").Create(h).Error; err != nil {
+		return err
+	}
+	return addMember(tx, h.ID, headPatientID, "self")
+}
+
+func GetHousehold(id int) (models.Household, error) {
+	var h models.Household
+	err := config.DB.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Select("household_members.*").
+			Joins("JOIN patients ON patients.id = household_members.patient_id AND patients.deleted_at IS NULL").
+			Order("household_members.created_at")
+	}).Preload("Members.Patient").First(&h, id).Error
+	return h, err
+}
+
+// lockHouseholdMembers returns every member row of the household, archived
+// patients included, and locks them until the transaction ends
+func lockHouseholdMembers(tx *gorm.DB, householdID uint) ([]models.HouseholdMember, error) {
+	var members []models.HouseholdMember
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("household_id = ?", householdID).Order("patient_id").Find(&members).Error
+	return members, err
+}
+
+func GetHouseholdByPatient(patientID int) (models.Household, error) {
+	var member models.HouseholdMember
+	if err := config.DB.Where("patient_id = ?", patientID).First(&member).Error; err != nil {
+		return models.Household{}, err
+	}
+	return GetHousehold(int(member.HouseholdID))
+}
+
+func AddHouseholdMember(householdID, patientID uint, relationship string) error {
+	return Atomically(func(uow UnitOfWork) error {
+		return uow.AddHouseholdMember(householdID, patientID, relationship)
+	})
+}
+
+func addHouseholdMember(tx *gorm.DB, householdID, patientID uint, relationship string) error {
+	if err := tx.First(&models.Household{}, householdID).Error; err != nil {
+		return err
+	}
+	if err := ensureNotInHousehold(tx, patientID); err != nil {
+		return err
+	}
+	return addMember(tx, householdID, patientID, relationship)
+}
+
+func UpdateHouseholdMember(householdID, patientID uint, relationship string) error {
+	return Atomically(func(uow UnitOfWork) error {
+		return uow.UpdateHouseholdMember(householdID, patientID, relationship)
+	})
+}
+
+func updateHouseholdMember(tx *gorm.DB, householdID, patientID uint, relationship string) error {
+	result := tx.Model(&models.HouseholdMember{}).
+		Where("household_id = ? AND patient_id = ?", householdID, patientID).
+		Update("relationship", relationship)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotHouseholdMember
+	}
+	return syncPatientRelationship(tx, patientID, relationship)
+}
+
+func RemoveHouseholdMember(householdID, patientID uint) error {
+	return Atomically(func(uow UnitOfWork) error {
+		return uow.RemoveHouseholdMember(householdID, patientID)
+	})
+}
+
+func removeHouseholdMember(tx *gorm.DB, householdID, patientID uint) error {
+	var h models.Household
+	if err := tx.First(&h, householdID).Error; err != nil {
+		return err
+	}
+	if h.HeadPatientID != nil && *h.HeadPatientID == patientID {
+		return ErrHouseholdHead
+	}
+	result := tx.Where("household_id = ? AND patient_id = ?", householdID, patientID).Delete(&models.HouseholdMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotHouseholdMember
+	}
+	return nil
+}
+
+// SetHouseholdHead makes an existing member the head. The previous head
+// becomes "other" until their relationship is updated.
+func SetHouseholdHead(householdID, patientID uint) error {
+	return Atomically(func(uow UnitOfWork) error {
+		return uow.SetHouseholdHead(householdID, patientID)
+	})
+}
+
+func setHouseholdHead(tx *gorm.DB, householdID, patientID uint) error {
+	var h models.Household
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&h, householdID).Error; err != nil {
+		return err
+	}
+	var member models.HouseholdMember
+	if err := tx.Where("household_id = ? AND patient_id = ?", householdID, patientID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotHouseholdMember
+		}
+		return err
+	}
+
+	if h.HeadPatientID != nil && *h.HeadPatientID != patientID {
+		if err := tx.Model(&models.HouseholdMember{}).
+			Where("household_id = ? AND patient_id = ?", householdID, *h.HeadPatientID).
+			Update("relationship", "other").Error; err != nil {
+			return err
+		}
+		if err := syncPatientRelationship(tx, *h.HeadPatientID, "other"); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&member).Update("relationship", "self").Error; err != nil {
+		return err
+	}
+	if err := syncPatientRelationship(tx, patientID, "self"); err != nil {
+		return err
+	}
+	return tx.Model(&h).Update("head_patient_id", patientID).Error
+}
+
+// GetHouseholdHistory returns every visit of every current member, newest first
+func GetHouseholdHistory(householdID uint) ([]models.MedicalHistory, error) {
+	var history []models.MedicalHistory
+	err := config.DB.Where("patient_id IN (?)", memberIDs(householdID)).
+		Order("visit_date DESC").Find(&history).Error
+	return history, err
+}
+
+// GetHouseholdVisitSummary summarises the visits of the given members only,
+// so it says nothing about members the caller may not see
+func GetHouseholdVisitSummary(householdID uint, patientIDs []uint) (HouseholdSummary, error) {
+	summary := HouseholdSummary{RelationshipCounts: []RelationshipCount{}}
+	if len(patientIDs) == 0 {
+		return summary, nil
+	}
+
+	if err := config.DB.Model(&models.MedicalHistory{}).
+		Where("patient_id IN (?) AND patient_id IN ?", memberIDs(householdID), patientIDs).
+		Count(&summary.TotalVisits).Error; err != nil {
+		return summary, err
+	}
+
+	if err := config.DB.Model(&models.HouseholdMember{}).
+		Select("household_members.relationship, count(*) as count").
+		Joins("JOIN patients ON patients.id = household_members.patient_id AND patients.deleted_at IS NULL").
+		Where("household_members.household_id = ? AND household_members.patient_id IN ?", householdID, patientIDs).
+		Group("household_members.relationship").
+		Scan(&summary.RelationshipCounts).Error; err != nil {
+		return summary, err
+	}
+
+	for _, rc := range summary.RelationshipCounts {
+		summary.UniqueMembers += rc.Count
+	}
+	summary.HasHistory = summary.TotalVisits > 0
+	return summary, nil
+}
+
+// memberIDs is a subquery selecting the household's non-archived patients
+func memberIDs(householdID uint) *gorm.DB {
+	return config.DB.Model(&models.HouseholdMember{}).
+		Select("household_members.patient_id").
+		Joins("JOIN patients ON patients.id = household_members.patient_id AND patients.deleted_at IS NULL").
+		Where("household_members.household_id = ?", householdID)
+}
+
+func ensureNotInHousehold(tx *gorm.DB, patientID uint) error {
+	if err := tx.First(&models.Patient{}, patientID).Error; err != nil {
+		return err
+	}
+	var existing int64
+	if err := tx.Model(&models.HouseholdMember{}).Where("patient_id = ?", patientID).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return ErrAlreadyInHousehold
+	}
+	return nil
+}
+
+func addMember(tx *gorm.DB, householdID, patientID uint, relationship string) error {
+	member := models.HouseholdMember{
+		HouseholdID:  householdID,
+		PatientID:    patientID,
+		Relationship: relationship,
+	}
+	if err := tx.Create(&member).Error; err != nil {
+		return err
+	}
+	return syncPatientRelationship(tx, patientID, relationship)
+}
+
+// syncPatientRelationship keeps the legacy Patient.Relationship column in
+// step. It is a patient write like any other, so it bumps the version and
+// clients holding the old ETag have to reload.
+func syncPatientRelationship(tx *gorm.DB, patientID uint, relationship string) error {
+	return tx.Model(&models.Patient{}).Where("id = ?", patientID).Updates(map[string]interface{}{
+		"relationship": relationship,
+		"version":      gorm.Expr("version + 1"),
+	}).Error
+}
+
+// MigratePhoneHouseholds converts the old "same phone number means same
+// family" grouping into households. Patients already in a household are
+// left alone, so this is safe to run on every start. Within a phone group the
+// earliest "self" patient becomes head; further "self" patients are added as
+// "other" so staff can split households that only shared a number.
+func MigratePhoneHouseholds() error {
+	var phones []string
+	err := config.DB.Unscoped().Model(&models.Patient{}).
+		Where("phone_number <> '' AND id NOT IN (?)", config.DB.Model(&models.HouseholdMember{}).Select("patient_id")).
+		Distinct().Pluck("phone_number", &phones).Error
+	if err != nil {
+		return err
+	}
+
+	for _, phone := range phones {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var patients []models.Patient
+			if err := tx.Unscoped().
+				Where("phone_number = ? AND id NOT IN (?)", phone, tx.Model(&models.HouseholdMember{}).Select("patient_id")).
+				Order("created_at, id").Find(&patients).Error; err != nil {
+				return err
+			}
+			if len(patients) == 0 {
+				return nil
+			}
+
+			head := patients[0]
+			for _, p := range patients {
+				if p.Relationship == "self" {
+					head = p
+					break
+				}
+			}
+
+			household := models.Household{
+				Name:          head.Name + " household",
+				PhoneNumber:   phone,
+				HeadPatientID: &head.ID,
+			}
+			if err := tx.Omit("Members").Create(&household).Error; err != nil {
+				return err
+			}
+
+			for _, p := range patients {
+				relationship := p.Relationship
+				switch {
+				case p.ID == head.ID:
+					relationship = "self"
+				case relationship == "self" || !models.ValidRelationship(relationship):
+					relationship = "other"
+				}
+				member := models.HouseholdMember{HouseholdID: household.ID, PatientID: p.ID, Relationship: relationship}
+				if err := tx.Create(&member).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
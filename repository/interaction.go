@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+// FindInteractionRules returns every rule whose two drugs are both in names.
+// Names must already be normalized.
+func FindInteractionRules(names []string) ([]models.InteractionRule, error) {
+	var rules []models.InteractionRule
+	if len(names) < 2 {
+		return rules, nil
+	}
+	err := config.DB.Where("drug_a IN ? AND drug_b IN ?", names, names).Find(&rules).Error
+	return rules, err
+}
+
+// InteractionDrugNames lists every drug that appears in a rule
+func InteractionDrugNames() ([]string, error) {
+	var names []string
+	err := config.DB.Raw("SELECT drug_a FROM interaction_rules UNION SELECT drug_b FROM interaction_rules").
+		Scan(&names).Error
+	return names, err
+}
+
+// ImportInteractionRules upserts rules keyed by drug pair. With overwrite
+// unset, existing pairs are kept, which is how the bundled rules are seeded
+// without undoing an admin's edits.
+func ImportInteractionRules(rules []models.InteractionRule, overwrite bool) (int64, error) {
+	conflict := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "drug_a"}, {Name: "drug_b"}},
+		DoNothing: true,
+	}
+	if overwrite {
+		conflict.DoNothing = false
+		conflict.DoUpdates = clause.AssignmentColumns([]string{"severity", "description", "updated_at"})
+	}
+
+	var affected int64
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(conflict).CreateInBatches(rules, 500)
+		affected = result.RowsAffected
+		return result.Error
+	})
+	return affected, err
+}
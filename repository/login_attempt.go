@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+func GetLoginAttempt(key string) (models.LoginAttempt, error) {
+	var a models.LoginAttempt
+	err := config.DB.First(&a, "key = ?", key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.LoginAttempt{Key: key}, nil
+	}
+	return a, err
+}
+
+// RecordLoginFailure adds one failure for key. The count starts over when
+// the last failure is older than window; reaching lockAt locks the key
+// until lockUntil. The row is locked so instances don't lose updates.
+func RecordLoginFailure(key string, now time.Time, window time.Duration, lockAt int, lockUntil time.Time) (models.LoginAttempt, error) {
+	var a models.LoginAttempt
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.LoginAttempt{Key: key, LastFailureAt: now}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&a, "key = ?", key).Error; err != nil {
+			return err
+		}
+
+		if now.Sub(a.LastFailureAt) > window {
+			a.Failures = 0
+		}
+		a.Failures++
+		a.LastFailureAt = now
+		if lockAt > 0 && a.Failures >= lockAt {
+			a.LockedUntil = &lockUntil
+		}
+		return tx.Model(&a).Updates(map[string]interface{}{
+			"failures":        a.Failures,
+			"last_failure_at": a.LastFailureAt,
+			"locked_until":    a.LockedUntil,
+		}).Error
+	})
+	return a, err
+}
+
+func ClearLoginAttempts(key string) error {
+	return config.DB.Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
+
+// PruneLoginAttempts deletes counts with no failure since cutoff and no
+// lock still running
+func PruneLoginAttempts(cutoff time.Time) error {
+	return config.DB.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", cutoff, time.Now()).
+		Delete(&models.LoginAttempt{}).Error
+}
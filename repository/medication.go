@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+// SearchMedications matches the catalog by brand or generic name prefix.
+// Inactive entries are only returned when includeInactive is set.
+func SearchMedications(query string, includeInactive bool, limit int) ([]models.Medication, error) {
+	var meds []models.Medication
+	q := config.DB.Model(&models.Medication{})
+	if query = strings.TrimSpace(query); query != "" {
+		pattern := escapeLike(query) + "%"
+		q = q.Where("name ILIKE ? OR generic_name ILIKE ? OR code = ?", pattern, pattern, query)
+	}
+	if !includeInactive {
+		q = q.Where("active = ?", true)
+	}
+	err := q.Order("name, strength, form").Limit(limit).Find(&meds).Error
+	return meds, err
+}
+
+func GetMedicationByID(id int) (models.Medication, error) {
+	var m models.Medication
+	err := config.DB.First(&m, id).Error
+	return m, err
+}
+
+// ImportMedications upserts catalog rows keyed by name, strength and form.
+// It runs in one transaction so a bad file leaves the catalog untouched.
+func ImportMedications(meds []models.Medication) (int64, error) {
+	var affected int64
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}, {Name: "strength"}, {Name: "form"}},
+			DoUpdates: clause.AssignmentColumns([]string{"generic_name", "route", "code", "active", "updated_at"}),
+		}).CreateInBatches(meds, 500)
+		affected = result.RowsAffected
+		return result.Error
+	})
+	return affected, err
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+var ErrMFAChallengeInvalid = errors.New("sign-in challenge is invalid or expired, please log in again")
+
+func GetUserMFA(userID uint) (models.UserMFA, error) {
+	var m models.UserMFA
+	err := config.DB.First(&m, "user_id = ?", userID).Error
+	return m, err
+}
+
+// SavePendingMFA stores a new, unconfirmed secret. An enabled authenticator
+// is never replaced this way.
+func SavePendingMFA(userID uint, secret string) error {
+	m := models.UserMFA{UserID: userID, Secret: secret}
+	return config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"secret": secret, "last_step": 0, "updated_at": time.Now()}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "user_mfas.confirmed_at IS NULL"}}},
+	}).Create(&m).Error
+}
+
+// ConfirmMFA enables the authenticator and installs its recovery codes
+func ConfirmMFA(userID uint, step int64, codeHashes []string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.UserMFA{}).Where("user_id = ? AND confirmed_at IS NULL", userID).
+			Updates(map[string]interface{}{"confirmed_at": time.Now(), "last_step": step})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// AcceptTOTPStep records a used code's time step. It fails when that step, or
+// a later one, was already used.
+func AcceptTOTPStep(userID uint, step int64) (bool, error) {
+	res := config.DB.Model(&models.UserMFA{}).Where("user_id = ? AND last_step < ?", userID, step).
+		Update("last_step", step)
+	return res.RowsAffected == 1, res.Error
+}
+
+// UseRecoveryCode spends a recovery code; false if it was unknown or used
+func UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	res := config.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+func CountRecoveryCodes(userID uint) (int64, error) {
+	var n int64
+	err := config.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
+
+func ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	rows := make([]models.RecoveryCode, 0, len(codeHashes))
+	for _, h := range codeHashes {
+		rows = append(rows, models.RecoveryCode{UserID: userID, CodeHash: h})
+	}
+	return tx.Create(&rows).Error
+}
+
+// DeleteUserMFA removes the authenticator and recovery codes
+func DeleteUserMFA(userID uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserMFA{}).Error
+	})
+}
+
+// CreateMFAChallenge stores a new challenge and retires the user's oldest
+// open ones so no more than maxOpen can be answered at a time
+func CreateMFAChallenge(ch *models.MFAChallenge, maxOpen int) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		// Concurrent logins for the same user take turns
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.User{}, ch.UserID).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		open := tx.Model(&models.MFAChallenge{}).Select("id").
+			Where("user_id = ? AND used_at IS NULL AND expires_at > ?", ch.UserID, now).
+			Order("id DESC").Limit(maxOpen - 1)
+		if err := tx.Model(&models.MFAChallenge{}).
+			Where("user_id = ? AND used_at IS NULL AND expires_at > ? AND id NOT IN (?)", ch.UserID, now, open).
+			Update("expires_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(ch).Error
+	})
+}
+
+// GetMFAChallenge finds a challenge that can still be answered
+func GetMFAChallenge(tokenHash string, maxAttempts int) (models.MFAChallenge, error) {
+	var ch models.MFAChallenge
+	err := config.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ? AND attempts < ?",
+		tokenHash, time.Now(), maxAttempts).First(&ch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ch, ErrMFAChallengeInvalid
+	}
+	return ch, err
+}
+
+// CountMFAAttempt uses up one attempt before the code is checked, so
+// parallel guesses can't exceed maxAttempts
+func CountMFAAttempt(id uint, maxAttempts int) error {
+	res := config.DB.Model(&models.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL AND attempts < ?", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrMFAChallengeInvalid
+	}
+	return nil
+}
+
+// UseMFAChallenge marks the challenge answered; it can't be used twice
+func UseMFAChallenge(id uint) error {
+	res := config.DB.Model(&models.MFAChallenge{}).Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrMFAChallengeInvalid
+	}
+	return nil
+}
+
+// PruneMFAChallenges deletes challenges that expired before cutoff
+func PruneMFAChallenges(cutoff time.Time) error {
+	return config.DB.Where("expires_at < ?", cutoff).Delete(&models.MFAChallenge{}).Error
+}
+
+// SeedRoleMFAPolicies adds a row for roles that have none; existing rows,
+// including ones an admin switched off, are left alone
+func SeedRoleMFAPolicies(roles []string, defaults map[string]bool) error {
+	rows := make([]models.RoleMFAPolicy, 0, len(roles))
+	for _, role := range roles {
+		rows = append(rows, models.RoleMFAPolicy{Role: role, Required: defaults[role]})
+	}
+	return config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+func GetRoleMFAPolicies() ([]models.RoleMFAPolicy, error) {
+	var rows []models.RoleMFAPolicy
+	err := config.DB.Order("role").Find(&rows).Error
+	return rows, err
+}
+
+func RoleMFARequired(role string) (bool, error) {
+	var p models.RoleMFAPolicy
+	err := config.DB.First(&p, "role = ?", role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return p.Required, err
+}
+
+// SetRoleMFARequired saves the policy. Turning it on ends the sessions of
+// members without MFA so they have to enroll at their next sign-in.
+func SetRoleMFARequired(role string, required bool) (models.RoleMFAPolicy, error) {
+	p := models.RoleMFAPolicy{Role: role, Required: required}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&p).Error; err != nil {
+			return err
+		}
+		if !required {
+			return nil
+		}
+		withoutMFA := tx.Model(&models.User{}).Select("id").
+			Where("role = ? AND id NOT IN (?)", role,
+				tx.Model(&models.UserMFA{}).Select("user_id").Where("confirmed_at IS NOT NULL"))
+		return tx.Model(&models.Session{}).Where("user_id IN (?) AND revoked_at IS NULL", withoutMFA).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": models.SessionMFARequired}).Error
+	})
+	return p, err
+}
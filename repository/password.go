@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+var ErrResetTokenInvalid = errors.New("password reset link is invalid, expired or already used")
+
+// GetPasswordHistory returns the hashes of the user's most recent previous
+// passwords, newest first
+func GetPasswordHistory(userID uint, limit int) ([]string, error) {
+	var hashes []string
+	if limit <= 0 {
+		return hashes, nil
+	}
+	err := config.DB.Model(&models.PasswordHistory{}).Where("user_id = ?", userID).
+		Order("id DESC").Limit(limit).Pluck("hash", &hashes).Error
+	return hashes, err
+}
+
+// ChangePassword stores a new password hash. The old hash moves to the
+// history, which is trimmed to historyLimit entries. Every session of the
+// user except keepSessionID ends with the change; pass 0 to end them all.
+func ChangePassword(userID uint, hash string, historyLimit int, mustChange bool, keepSessionID uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return changePassword(tx, userID, hash, historyLimit, mustChange, keepSessionID)
+	})
+}
+
+func changePassword(tx *gorm.DB, userID uint, hash string, historyLimit int, mustChange bool, keepSessionID uint) error {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	if historyLimit > 0 {
+		if err := tx.Create(&models.PasswordHistory{UserID: userID, Hash: user.Password}).Error; err != nil {
+			return err
+		}
+		keep := tx.Model(&models.PasswordHistory{}).Select("id").Where("user_id = ?", userID).
+			Order("id DESC").Limit(historyLimit)
+		if err := tx.Where("user_id = ? AND id NOT IN (?)", userID, keep).Delete(&models.PasswordHistory{}).Error; err != nil {
+			return err
+		}
+	} else if err := tx.Where("user_id = ?", userID).Delete(&models.PasswordHistory{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&user).Updates(map[string]interface{}{
+		"password":             hash,
+		"password_changed_at":  time.Now(),
+		"must_change_password": mustChange,
+	}).Error; err != nil {
+		return err
+	}
+	return revokeOtherSessions(tx, userID, keepSessionID, models.SessionPasswordSet)
+}
+
+// SetMustChangePassword flags the account so the user has to pick a new
+// password before using the API again
+func SetMustChangePassword(id uint, mustChange bool) (models.User, error) {
+	result := config.DB.Model(&models.User{}).Where("id = ?", id).Update("must_change_password", mustChange)
+	if result.Error != nil {
+		return models.User{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.User{}, gorm.ErrRecordNotFound
+	}
+	return GetUserByID(id)
+}
+
+// CreatePasswordResetToken stores a reset link, cancelling any earlier
+// unused link for the same user
+func CreatePasswordResetToken(t *models.PasswordResetToken) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", t.UserID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(t).Error
+	})
+}
+
+// GetPasswordResetToken finds a reset link that can still be used
+func GetPasswordResetToken(tokenHash string) (models.PasswordResetToken, error) {
+	var t models.PasswordResetToken
+	err := config.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, time.Now()).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return t, ErrResetTokenInvalid
+	}
+	return t, err
+}
+
+// RedeemPasswordReset spends the link and sets the new password together,
+// so a link can't be used twice
+func RedeemPasswordReset(tokenID, userID uint, hash string, historyLimit int) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND user_id = ? AND used_at IS NULL AND expires_at > ?", tokenID, userID, time.Now()).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrResetTokenInvalid
+		}
+		return changePassword(tx, userID, hash, historyLimit, false, 0)
+	})
+}
+
+// PrunePasswordResetTokens deletes links that expired before cutoff
+func PrunePasswordResetTokens(cutoff time.Time) error {
+	return config.DB.Where("expires_at < ?", cutoff).Delete(&models.PasswordResetToken{}).Error
+}
@@ -2,140 +2,284 @@ package repository
 
 import (
     "time"
+    "gorm.io/gorm"
     "github.com/Sathwik-145/hospital-portal/models"
     "github.com/Sathwik-145/hospital-portal/config"
 )
 
-func CreatePatient(p models.Patient) error {
-    return config.DB.Create(&p).Error
+// CreatePatient inserts p and fills in its ID
+func CreatePatient(p *models.Patient) error {
+    return config.DB.Create(p).Error
 }
 
-func GetAllPatients() ([]models.Patient, error) {
-    var patients []models.Patient
-    err := config.DB.Preload("MedicalHistory").Find(&patients).Error
-    return patients, err
+// UpdatePatient saves demographic changes. Clinical fields follow the
+// latest encounter and are changed by CreateEncounter only. A non-zero
+// version must match the stored one; either way the update fails with
+// ErrVersionConflict if the patient changes while it is being saved.
+func UpdatePatient(id int, updated models.Patient, version uint) error {
+    return Atomically(func(uow UnitOfWork) error {
+        return uow.UpdatePatient(id, updated, version)
+    })
 }
 
-func UpdatePatient(id int, updated models.Patient) error {
+func updatePatient(tx *gorm.DB, id int, updated models.Patient, version uint) error {
     var patient models.Patient
     
     // Get existing patient
-    if err := config.DB.First(&patient, id).Error; err != nil {
+    if err := tx.First(&patient, id).Error; err != nil {
         return err
     }
+    if version != 0 && patient.Version != version {
+        return ErrVersionConflict
+    }
 
-    // Store previous state as medical history if there are medical changes
-    if (patient.Diagnosis != updated.Diagnosis && updated.Diagnosis != "") || 
-       (patient.MedicalNotes != updated.MedicalNotes && updated.MedicalNotes != "") || 
-       (patient.Prescriptions != updated.Prescriptions && updated.Prescriptions != "") {
-        
-        // Create medical history entry with complete patient info
-        history := models.MedicalHistory{
-            PatientID:     patient.ID,
-            PatientName:   patient.Name,
-            PhoneNumber:   patient.PhoneNumber,
-            Relationship:  patient.Relationship,
-            Age:           patient.Age,
-            Gender:        patient.Gender,
-            DoctorName:    "Dr. Current", // You can get this from JWT token later
-            VisitDate:     time.Now(),
-            Diagnosis:     updated.Diagnosis,
-            MedicalNotes:  updated.MedicalNotes,
-            Prescriptions: updated.Prescriptions,
-            CreatedAt:     time.Now(),
-        }
-        
-        // Save to medical history
-        config.DB.Create(&history)
+    // Update patient fields. LastCheckup and NextAppointment are derived from
+    // appointments, Relationship from the patient's household.
+    changes := map[string]interface{}{
+        "name":         updated.Name,
+        "gender":       updated.Gender,
+        "phone_number": updated.PhoneNumber,
+        "updated_at":   time.Now(),
+        "version":      gorm.Expr("version + 1"),
+    }
+    if !updated.DateOfBirth.IsZero() {
+        changes["date_of_birth"] = updated.DateOfBirth
+        changes["dob_estimated"] = false
     }
 
-    // Update patient fields (including relationship if changed)
-    patient.Name = updated.Name
-    patient.Age = updated.Age
-    patient.Gender = updated.Gender
-    patient.PhoneNumber = updated.PhoneNumber
-    patient.Relationship = updated.Relationship
-    patient.Diagnosis = updated.Diagnosis
-    patient.MedicalNotes = updated.MedicalNotes
-    patient.Prescriptions = updated.Prescriptions
-    patient.LastCheckup = updated.LastCheckup
-    patient.NextAppointment = updated.NextAppointment
-    patient.UpdatedAt = time.Now()
+    res := tx.Model(&patient).Where("version = ?", patient.Version).Updates(changes)
+    if res.Error != nil {
+        return res.Error
+    }
+    if res.RowsAffected == 0 {
+        return ErrVersionConflict
+    }
+    return nil
+}
 
-    return config.DB.Save(&patient).Error
+var (
+    ErrPatientNotArchived = conflictError("patient is not archived")
+    ErrRetentionPeriod    = conflictError("patient records are still within the retention period")
+    ErrVersionConflict    = conflictError("patient was changed by someone else; reload and try again")
+)
+
+// DeletePatient archives the patient and their visits; nothing is removed
+func DeletePatient(id int, deletedBy uint, reason string) error {
+    return Atomically(func(uow UnitOfWork) error {
+        return uow.DeletePatient(id, deletedBy, reason)
+    })
 }
 
-func DeletePatient(id int) error {
-    // First delete all medical history records
-    config.DB.Where("patient_id = ?", id).Delete(&models.MedicalHistory{})
-    // Then delete the patient
-    return config.DB.Delete(&models.Patient{}, id).Error
+func deletePatient(tx *gorm.DB, id int, deletedBy uint, reason string) error {
+    var patient models.Patient
+    if err := tx.First(&patient, id).Error; err != nil {
+        return err
+    }
+
+    archived := map[string]interface{}{
+        "deleted_at":      time.Now(),
+        "deleted_by":      deletedBy,
+        "deletion_reason": reason,
+    }
+    if err := tx.Model(&models.MedicalHistory{}).Where("patient_id = ?", id).Updates(archived).Error; err != nil {
+        return err
+    }
+    return tx.Model(&patient).Updates(archived).Error
 }
 
-func GetPatientByNameAndAge(name string, age int) (models.Patient, error) {
+// RestorePatient un-archives a patient along with the visits archived with them
+func RestorePatient(id int) error {
+    return Atomically(func(uow UnitOfWork) error {
+        return uow.RestorePatient(id)
+    })
+}
+
+func restorePatient(tx *gorm.DB, id int) error {
+    var patient models.Patient
+    if err := tx.Unscoped().First(&patient, id).Error; err != nil {
+        return err
+    }
+    if !patient.DeletedAt.Valid {
+        return ErrPatientNotArchived
+    }
+
+    restored := map[string]interface{}{
+        "deleted_at":      nil,
+        "deleted_by":      nil,
+        "deletion_reason": "",
+    }
+    if err := tx.Unscoped().Model(&models.MedicalHistory{}).
+        Where("patient_id = ? AND deleted_at = ?", id, patient.DeletedAt.Time).
+        Updates(restored).Error; err != nil {
+        return err
+    }
+    return tx.Unscoped().Model(&patient).Updates(restored).Error
+}
+
+// GetArchivedPatients lists archived patients, newest first; a non-zero
+// assignedTo limits them to that user's patients
+func GetArchivedPatients(assignedTo uint) ([]models.Patient, error) {
+    var patients []models.Patient
+    db := config.DB.Unscoped().
+        Preload("MedicalHistory", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+        Where("deleted_at IS NOT NULL")
+    if assignedTo != 0 {
+        db = db.Where("id IN (?)", assignedPatients(assignedTo))
+    }
+    err := db.Order("deleted_at DESC").Find(&patients).Error
+    return patients, err
+}
+
+// PurgePatient permanently removes an archived patient. Both the archive date
+// and the most recent visit must be older than the retention period.
+func PurgePatient(id int, retention time.Duration) error {
+    return Atomically(func(uow UnitOfWork) error {
+        return uow.PurgePatient(id, retention)
+    })
+}
+
+func purgePatient(tx *gorm.DB, id int, retention time.Duration) error {
+    var patient models.Patient
+    if err := tx.Unscoped().First(&patient, id).Error; err != nil {
+        return err
+    }
+    if !patient.DeletedAt.Valid {
+        return ErrPatientNotArchived
+    }
+
+    cutoff := time.Now().Add(-retention)
+    if patient.DeletedAt.Time.After(cutoff) {
+        return ErrRetentionPeriod
+    }
+    var recentVisits int64
+    if err := tx.Unscoped().Model(&models.MedicalHistory{}).
+        Where("patient_id = ? AND visit_date > ?", id, cutoff).
+        Count(&recentVisits).Error; err != nil {
+        return err
+    }
+    if recentVisits > 0 {
+        return ErrRetentionPeriod
+    }
+
+    if err := tx.Where("patient_id = ?", id).Delete(&models.Appointment{}).Error; err != nil {
+        return err
+    }
+    if err := tx.Where("patient_id = ?", id).Delete(&models.Prescription{}).Error; err != nil {
+        return err
+    }
+    if err := tx.Where("patient_id = ?", id).Delete(&models.PatientAllergy{}).Error; err != nil {
+        return err
+    }
+    if err := tx.Where("patient_id = ?", id).Delete(&models.Vitals{}).Error; err != nil {
+        return err
+    }
+    if err := tx.Where("patient_id = ?", id).Delete(&models.HouseholdMember{}).Error; err != nil {
+        return err
+    }
+    if err := tx.Where("patient_id = ?", id).Delete(&models.CareTeamMember{}).Error; err != nil {
+        return err
+    }
+    if err := tx.Model(&models.Household{}).Where("head_patient_id = ?", id).Update("head_patient_id", nil).Error; err != nil {
+        return err
+    }
+    if err := tx.Unscoped().Where("patient_id = ?", id).Delete(&models.MedicalHistory{}).Error; err != nil {
+        return err
+    }
+    return tx.Unscoped().Delete(&patient).Error
+}
+
+func GetPatientByID(id int) (models.Patient, error) {
+    return getPatientByID(config.DB, id)
+}
+
+func getPatientByID(db *gorm.DB, id int) (models.Patient, error) {
     var p models.Patient
-    err := config.DB.Preload("MedicalHistory").Where("name = ? AND age = ?", name, age).Order("id DESC").First(&p).Error
+    err := db.Preload("MedicalHistory").First(&p, id).Error
     return p, err
 }
 
-func GetPatientByID(id int) (models.Patient, error) {
+// GetPatientIncludingArchived also finds archived patients, with all their visits
+func GetPatientIncludingArchived(id int) (models.Patient, error) {
+    return getPatientIncludingArchived(config.DB, id)
+}
+
+func getPatientIncludingArchived(db *gorm.DB, id int) (models.Patient, error) {
     var p models.Patient
-    err := config.DB.Preload("MedicalHistory").First(&p, id).Error
+    err := db.Unscoped().
+        Preload("MedicalHistory", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+        First(&p, id).Error
     return p, err
 }
 
-func GetPatientWithHistory(id int) (models.Patient, error) {
+// GetPatientWithHistory preloads visits, newest first, optionally only those recorded by doctorID
+func GetPatientWithHistory(id int, doctorID uint) (models.Patient, error) {
     var patient models.Patient
-    err := config.DB.Preload("MedicalHistory").First(&patient, id).Error
+    err := config.DB.Preload("MedicalHistory", func(db *gorm.DB) *gorm.DB {
+        if doctorID != 0 {
+            db = db.Where("doctor_id = ?", doctorID)
+        }
+        return db.Order("visit_date DESC")
+    }).First(&patient, id).Error
     return patient, err
 }
 
-// Enhanced function: Get complete family medical history by phone number
-func GetFamilyHistoryByPhone(phoneNumber string) ([]models.MedicalHistory, error) {
-    var history []models.MedicalHistory
-    err := config.DB.Where("phone_number = ?", phoneNumber).Order("visit_date DESC").Find(&history).Error
-    return history, err
+// GetVisit returns one history entry with the doctor who recorded it
+func GetVisit(visitID int) (models.MedicalHistory, error) {
+    var visit models.MedicalHistory
+    err := config.DB.Preload("Doctor").First(&visit, visitID).Error
+    return visit, err
 }
 
-// Enhanced function: Get all family members with same phone number
-func GetFamilyMembersByPhone(phoneNumber string) ([]models.Patient, error) {
-    var patients []models.Patient
-    err := config.DB.Where("phone_number = ?", phoneNumber).Order("created_at DESC").Find(&patients).Error
-    return patients, err
+// GetPatientVisit is GetVisit limited to one patient's history
+func GetPatientVisit(patientID, visitID int) (models.MedicalHistory, error) {
+    var visit models.MedicalHistory
+    err := config.DB.Preload("Doctor").Where("patient_id = ?", patientID).First(&visit, visitID).Error
+    return visit, err
 }
 
-// Enhanced function: Check family visit history
-func GetFamilyVisitSummary(phoneNumber string) (map[string]interface{}, error) {
-    var totalVisits int64
-    var uniqueMembers int64
-    
-    // Count total visits
-    config.DB.Model(&models.MedicalHistory{}).Where("phone_number = ?", phoneNumber).Count(&totalVisits)
-    
-    // Count unique family members
-    config.DB.Model(&models.Patient{}).Where("phone_number = ?", phoneNumber).Count(&uniqueMembers)
-    
-    // Get relationship breakdown
-    var relationshipCounts []struct {
-        Relationship string
-        Count        int64
-    }
-    config.DB.Model(&models.Patient{}).
-        Select("relationship, count(*) as count").
-        Where("phone_number = ?", phoneNumber).
-        Group("relationship").
-        Scan(&relationshipCounts)
-    
-    return map[string]interface{}{
-        "total_visits":        totalVisits,
-        "unique_members":      uniqueMembers,
-        "relationship_counts": relationshipCounts,
-        "has_history":         totalVisits > 0,
-    }, nil
+// GetHistoryByDoctor lists visits recorded by a doctor, optionally within [from, to)
+func GetHistoryByDoctor(doctorID uint, from, to time.Time) ([]models.MedicalHistory, error) {
+    var history []models.MedicalHistory
+    q := config.DB.Where("doctor_id = ?", doctorID)
+    if !from.IsZero() {
+        q = q.Where("visit_date >= ?", from)
+    }
+    if !to.IsZero() {
+        q = q.Where("visit_date < ?", to)
+    }
+    err := q.Order("visit_date DESC").Find(&history).Error
+    return history, err
 }
 
 func GetAllPatientsWithHistory() ([]models.Patient, error) {
     var patients []models.Patient
     err := config.DB.Preload("MedicalHistory").Find(&patients).Error
     return patients, err
-}
\ No newline at end of file
+}
+
+// MigrateDateOfBirth estimates a date of birth for patients registered with
+// only the old static age column: half a year before the age's birthday at
+// registration, flagged as estimated. History rows get a snapshot derived
+// from the age recorded at the visit. Rows that already have a date of
+// birth are left alone, so this is safe to run on every start.
+func MigrateDateOfBirth() error {
+    migrator := config.DB.Migrator()
+    return config.DB.Transaction(func(tx *gorm.DB) error {
+        if migrator.HasColumn(&models.Patient{}, "age") {
+            if err := tx.Exec(`UPDATE patients
+                SET date_of_birth = (created_at - make_interval(years => age::int) - interval '6 months')::date,
+                    dob_estimated = true
+                WHERE date_of_birth IS NULL AND age IS NOT NULL AND age >= 0`).Error; err != nil {
+                return err
+            }
+        }
+        if migrator.HasColumn(&models.MedicalHistory{}, "age") {
+            if err := tx.Exec(`UPDATE medical_histories
+                SET date_of_birth = (visit_date - make_interval(years => age::int) - interval '6 months')::date
+                WHERE date_of_birth IS NULL AND age IS NOT NULL AND age >= 0`).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+var ErrInvalidCursor = validationError("cursor", "invalid cursor")
+
+// Sortable columns for the patient list
+var patientSortColumns = map[string]bool{
+	"name":       true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// PatientQuery describes one page of GET /api/patients
+type PatientQuery struct {
+	Limit          int
+	Cursor         string
+	Sort           string
+	Desc           bool
+	Gender         string
+	Relationship   string
+	MinAge         *int
+	MaxAge         *int
+	Diagnosis      string
+	AppointmentOn  time.Time
+	IncludeHistory bool
+	// Only patients this user is assigned to; 0 lists everyone
+	AssignedTo uint
+}
+
+type PatientPage struct {
+	Data       []models.Patient `json:"data"`
+	Total      int64            `json:"total"`
+	Limit      int              `json:"limit"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// patientCursor is the keyset position after the last row of a page
+type patientCursor struct {
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func ValidPatientSort(column string) bool {
+	return patientSortColumns[column]
+}
+
+// ListPatients returns one keyset-paginated page. Total counts every match,
+// not just the rows after the cursor.
+func ListPatients(q PatientQuery) (PatientPage, error) {
+	page := PatientPage{Limit: q.Limit}
+	if !patientSortColumns[q.Sort] {
+		q.Sort = "created_at"
+	}
+
+	filtered := applyPatientFilters(config.DB.Model(&models.Patient{}), q)
+	if err := filtered.Count(&page.Total).Error; err != nil {
+		return page, err
+	}
+
+	db := applyPatientFilters(config.DB, q)
+	if q.IncludeHistory {
+		db = db.Preload("MedicalHistory", func(tx *gorm.DB) *gorm.DB { return tx.Order("visit_date DESC") })
+	}
+
+	if q.Cursor != "" {
+		cursor, err := decodePatientCursor(q.Cursor)
+		if err != nil {
+			return page, err
+		}
+		value, err := cursorValue(q.Sort, cursor.Value)
+		if err != nil {
+			return page, err
+		}
+		op := ">"
+		if q.Desc {
+			op = "<"
+		}
+		db = db.Where("("+q.Sort+" "+op+" ?) OR ("+q.Sort+" = ? AND id "+op+" ?)", value, value, cursor.ID)
+	}
+
+	direction := " ASC"
+	if q.Desc {
+		direction = " DESC"
+	}
+	// Fetch one extra row to know whether another page exists
+	var patients []models.Patient
+	if err := db.Order(q.Sort + direction).Order("id" + direction).Limit(q.Limit + 1).Find(&patients).Error; err != nil {
+		return page, err
+	}
+
+	if len(patients) > q.Limit {
+		patients = patients[:q.Limit]
+		page.NextCursor = encodePatientCursor(q.Sort, patients[len(patients)-1])
+	}
+	page.Data = patients
+	return page, nil
+}
+
+func applyPatientFilters(db *gorm.DB, q PatientQuery) *gorm.DB {
+	if q.AssignedTo != 0 {
+		db = db.Where("id IN (?)", assignedPatients(q.AssignedTo))
+	}
+	if q.Gender != "" {
+		db = db.Where("LOWER(gender) = LOWER(?)", q.Gender)
+	}
+	if q.Relationship != "" {
+		db = db.Where("LOWER(relationship) = LOWER(?)", q.Relationship)
+	}
+	// Ages are computed, so filter on the matching date of birth range
+	today := models.NewDate(time.Now())
+	if q.MinAge != nil {
+		db = db.Where("date_of_birth <= ?", today.AddDate(-*q.MinAge, 0, 0))
+	}
+	if q.MaxAge != nil {
+		db = db.Where("date_of_birth > ?", today.AddDate(-*q.MaxAge-1, 0, 0))
+	}
+	if q.Diagnosis != "" {
+		db = db.Where("diagnosis ILIKE ?", "%"+escapeLike(q.Diagnosis)+"%")
+	}
+	if !q.AppointmentOn.IsZero() {
+		day := time.Date(q.AppointmentOn.Year(), q.AppointmentOn.Month(), q.AppointmentOn.Day(), 0, 0, 0, 0, q.AppointmentOn.Location())
+		db = db.Where("id IN (?)", config.DB.Model(&models.Appointment{}).
+			Select("patient_id").
+			Where("start_time >= ? AND start_time < ? AND status <> ?", day, day.AddDate(0, 0, 1), models.AppointmentCancelled))
+	}
+	return db
+}
+
+func encodePatientCursor(sort string, p models.Patient) string {
+	c := patientCursor{ID: p.ID}
+	switch sort {
+	case "name":
+		c.Value = p.Name
+	case "updated_at":
+		c.Value = p.UpdatedAt.Format(time.RFC3339Nano)
+	default:
+		c.Value = p.CreatedAt.Format(time.RFC3339Nano)
+	}
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodePatientCursor(s string) (patientCursor, error) {
+	var c patientCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func cursorValue(sort, v string) (interface{}, error) {
+	if sort == "name" {
+		return v, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+// escapeLike escapes LIKE wildcards in user input
+func escapeLike(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		if r == '%' || r == '_' || r == '\\' {
+			out = append(out, '\\')
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
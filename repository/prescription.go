@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+var (
+	ErrPrescriptionNotActive = conflictError("prescription is not active")
+	ErrVisitNotForPatient    = validationError("visit_id", "visit does not belong to this patient")
+	ErrMedicationInactive    = validationError("medication_id", "medication is not active in the catalog")
+)
+
+// CreatePrescription validates the visit and catalog entry, fills the drug
+// details from the catalog where the prescriber left them blank, and saves p.
+func CreatePrescription(p *models.Prescription) error {
+	return Atomically(func(uow UnitOfWork) error {
+		return uow.CreatePrescription(p)
+	})
+}
+
+func createPrescription(tx *gorm.DB, p *models.Prescription) error {
+	if err := tx.First(&models.Patient{}, p.PatientID).Error; err != nil {
+		return err
+	}
+
+	if p.VisitID != nil {
+		var visit models.MedicalHistory
+		if err := tx.First(&visit, *p.VisitID).Error; err != nil {
+			return err
+		}
+		if visit.PatientID != p.PatientID {
+			return ErrVisitNotForPatient
+		}
+	}
+
+	if p.MedicationID != nil {
+		var med models.Medication
+		if err := tx.First(&med, *p.MedicationID).Error; err != nil {
+			return err
+		}
+		if !med.Active {
+			return ErrMedicationInactive
+		}
+		p.Drug = med.Name
+		if p.Strength == "" {
+			p.Strength = med.Strength
+		}
+		if p.Form == "" {
+			p.Form = med.Form
+		}
+		if p.Route == "" {
+			p.Route = med.Route
+		}
+	}
+
+	if p.StartDate.IsZero() {
+		p.StartDate = models.NewDate(time.Now())
+	}
+	if p.DurationDays > 0 {
+		p.EndDate = models.Date{Time: p.StartDate.AddDate(0, 0, p.DurationDays-1)}
+	}
+	p.Status = models.PrescriptionActive
+	return tx.Omit("Medication", "Prescriber").Create(p).Error
+}
+
+func GetPrescriptionByID(id int) (models.Prescription, error) {
+	var p models.Prescription
+	err := config.DB.Preload("Medication").First(&p, id).Error
+	return p, err
+}
+
+// GetPatientPrescriptions lists a patient's prescriptions, newest first. With
+// activeOnly, discontinued and finished courses are left out.
+func GetPatientPrescriptions(patientID int, activeOnly bool) ([]models.Prescription, error) {
+	var prescriptions []models.Prescription
+	q := config.DB.Preload("Medication").Where("patient_id = ?", patientID)
+	if activeOnly {
+		q = q.Where("status = ? AND (end_date IS NULL OR end_date >= ?)",
+			models.PrescriptionActive, models.NewDate(time.Now()))
+	}
+	err := q.Order("start_date DESC, id DESC").Find(&prescriptions).Error
+	return prescriptions, err
+}
+
+func GetVisitPrescriptions(visitID uint) ([]models.Prescription, error) {
+	var prescriptions []models.Prescription
+	err := config.DB.Preload("Medication").Where("visit_id = ?", visitID).Order("id").Find(&prescriptions).Error
+	return prescriptions, err
+}
+
+// DiscontinuePrescription stops an active prescription and returns it
+func DiscontinuePrescription(id int, userID uint, reason string) (models.Prescription, error) {
+	var p models.Prescription
+	err := Atomically(func(uow UnitOfWork) error {
+		var err error
+		p, err = uow.DiscontinuePrescription(id, userID, reason)
+		return err
+	})
+	return p, err
+}
+
+func discontinuePrescription(tx *gorm.DB, id int, userID uint, reason string) (models.Prescription, error) {
+	var p models.Prescription
+	if err := tx.First(&p, id).Error; err != nil {
+		return p, err
+	}
+	if p.Status != models.PrescriptionActive {
+		return p, ErrPrescriptionNotActive
+	}
+	now := time.Now()
+	p.Status = models.PrescriptionDiscontinued
+	p.DiscontinuedAt = &now
+	p.DiscontinuedByID = &userID
+	p.DiscontinueReason = reason
+	err := tx.Omit("Medication", "Prescriber").Save(&p).Error
+	return p, err
+}
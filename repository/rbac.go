@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+// GetRolePermissions returns the whole matrix keyed by role
+func GetRolePermissions() (map[string][]string, error) {
+	var rows []models.RolePermission
+	if err := config.DB.Order("role, permission").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	matrix := map[string][]string{}
+	for _, r := range rows {
+		matrix[r.Role] = append(matrix[r.Role], r.Permission)
+	}
+	return matrix, nil
+}
+
+// SetRolePermissions replaces the permissions granted to one role
+func SetRolePermissions(role string, permissions []string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return setRolePermissions(tx, role, permissions)
+	})
+}
+
+func setRolePermissions(tx *gorm.DB, role string, permissions []string) error {
+	if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+		return err
+	}
+	return grantPermissions(tx, role, permissions)
+}
+
+func grantPermissions(tx *gorm.DB, role string, permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+	rows := make([]models.RolePermission, 0, len(permissions))
+	for _, p := range permissions {
+		rows = append(rows, models.RolePermission{Role: role, Permission: p})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// SeedRolePermissions installs the default matrix only when the table is
+// empty, so an admin's edits survive restarts. Installs seeded earlier get
+// the changes made to the defaults since, each exactly once.
+func SeedRolePermissions(defaults map[string][]string, changes []models.PermissionChange) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.RolePermission{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			for role, permissions := range defaults {
+				if err := setRolePermissions(tx, role, permissions); err != nil {
+					return err
+				}
+			}
+		}
+
+		var applied []string
+		if err := tx.Model(&models.AppliedPermissionChange{}).Pluck("id", &applied).Error; err != nil {
+			return err
+		}
+		done := make(map[string]bool, len(applied))
+		for _, id := range applied {
+			done[id] = true
+		}
+		for _, change := range changes {
+			if done[change.ID] {
+				continue
+			}
+			// A fresh install already has every change in its defaults
+			if count > 0 {
+				if err := applyPermissionChange(tx, change); err != nil {
+					return err
+				}
+			}
+			record := models.AppliedPermissionChange{ID: change.ID, AppliedAt: time.Now()}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func applyPermissionChange(tx *gorm.DB, change models.PermissionChange) error {
+	for role, permissions := range change.Grant {
+		if err := grantPermissions(tx, role, permissions); err != nil {
+			return err
+		}
+	}
+	for role, permissions := range change.Revoke {
+		if err := tx.Where("role = ? AND permission IN ?", role, permissions).
+			Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+)
+
+// Search snippets mark matched words with ** rather than HTML so the
+// frontend can't be tricked into rendering markup from patient data.
+const headlineOptions = "StartSel=**, StopSel=**, MaxWords=20, MinWords=5, MaxFragments=2, FragmentDelimiter=\" ... \""
+
+// Indexed expressions; the index and the queries share these so the planner
+// can use the index. col qualifies a column, e.g. "p." in a query, "" in DDL.
+func patientDemographicDoc(col string) string {
+	return "to_tsvector('simple', coalesce(" + col + "name, '') || ' ' || coalesce(" + col + "phone_number, ''))"
+}
+
+func patientClinicalDoc(col string) string {
+	return "to_tsvector('english', coalesce(" + col + "name, '') || ' ' || coalesce(" + col + "diagnosis, '') || ' ' || coalesce(" +
+		col + "medical_notes, '') || ' ' || coalesce(" + col + "prescriptions, ''))"
+}
+
+func historyClinicalDoc(col string) string {
+	return "to_tsvector('english', coalesce(" + col + "diagnosis, '') || ' ' || coalesce(" + col + "medical_notes, '') || ' ' || coalesce(" +
+		col + "prescriptions, ''))"
+}
+
+type SearchSnippet struct {
+	Source  string `json:"source"` // "patient" or "history"
+	VisitID uint   `json:"visit_id,omitempty"`
+	Text    string `json:"text"`
+}
+
+type PatientSearchResult struct {
+	PatientID   uint            `json:"patient_id"`
+	Name        string          `json:"name"`
+	PhoneNumber string          `json:"phone_number"`
+	Rank        float64         `json:"rank"`
+	Snippets    []SearchSnippet `json:"snippets"`
+}
+
+type searchRow struct {
+	PatientID   uint
+	VisitID     uint
+	Name        string
+	PhoneNumber string
+	Rank        float64
+	Snippet     string
+}
+
+// SearchPatients ranks archived-excluded patients by full-text match plus
+// trigram similarity, so small typos still find the record. Clinical fields
+// and visit history are only searched when includeClinical is set. A
+// non-zero assignedTo limits results to that user's patients.
+func SearchPatients(query string, includeClinical bool, assignedTo uint, limit int) ([]PatientSearchResult, error) {
+	args := map[string]interface{}{
+		"q":     query,
+		"phone": "%" + digitsOnly(query) + "%",
+		"opts":  headlineOptions,
+		"limit": limit,
+	}
+	scope := ""
+	if assignedTo != 0 {
+		scope = "\n  AND p.id IN (@assigned)"
+		args["assigned"] = assignedPatients(assignedTo)
+	}
+	// Only match phone numbers when the query has enough digits to mean it
+	phoneMatch := "FALSE"
+	if len(digitsOnly(query)) >= 3 {
+		phoneMatch = "regexp_replace(p.phone_number, '[^0-9]', '', 'g') LIKE @phone"
+	}
+
+	var patientSQL string
+	if includeClinical {
+		patientSQL = `
+SELECT p.id AS patient_id, p.name, p.phone_number,
+       ts_rank(` + patientClinicalDoc("p.") + `, websearch_to_tsquery('english', @q))
+         + similarity(p.name, @q)
+         + GREATEST(word_similarity(@q, p.diagnosis), word_similarity(@q, p.medical_notes), word_similarity(@q, p.prescriptions)) / 2 AS rank,
+       ts_headline('english',
+         coalesce(p.name, '') || ' — ' || coalesce(p.diagnosis, '') || ' ' || coalesce(p.medical_notes, '') || ' ' || coalesce(p.prescriptions, ''),
+         websearch_to_tsquery('english', @q), @opts) AS snippet
+FROM patients p
+WHERE p.deleted_at IS NULL` + scope + `
+  AND (` + patientClinicalDoc("p.") + ` @@ websearch_to_tsquery('english', @q)
+       OR p.name % @q
+       OR @q <% p.diagnosis OR @q <% p.medical_notes OR @q <% p.prescriptions
+       OR ` + phoneMatch + `)
+ORDER BY rank DESC
+LIMIT @limit`
+	} else {
+		patientSQL = `
+SELECT p.id AS patient_id, p.name, p.phone_number,
+       ts_rank(` + patientDemographicDoc("p.") + `, websearch_to_tsquery('simple', @q)) + similarity(p.name, @q) AS rank,
+       ts_headline('simple', coalesce(p.name, ''), websearch_to_tsquery('simple', @q), @opts) AS snippet
+FROM patients p
+WHERE p.deleted_at IS NULL` + scope + `
+  AND (` + patientDemographicDoc("p.") + ` @@ websearch_to_tsquery('simple', @q)
+       OR p.name % @q
+       OR ` + phoneMatch + `)
+ORDER BY rank DESC
+LIMIT @limit`
+	}
+
+	var rows []searchRow
+	if err := config.DB.Raw(patientSQL, args).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	if includeClinical {
+		historySQL := `
+SELECT h.patient_id, h.id AS visit_id, p.name, p.phone_number,
+       ts_rank(` + historyClinicalDoc("h.") + `, websearch_to_tsquery('english', @q))
+         + GREATEST(word_similarity(@q, h.diagnosis), word_similarity(@q, h.medical_notes), word_similarity(@q, h.prescriptions)) / 2 AS rank,
+       ts_headline('english',
+         coalesce(h.diagnosis, '') || ' ' || coalesce(h.medical_notes, '') || ' ' || coalesce(h.prescriptions, ''),
+         websearch_to_tsquery('english', @q), @opts) AS snippet
+FROM medical_histories h
+JOIN patients p ON p.id = h.patient_id AND p.deleted_at IS NULL
+WHERE h.deleted_at IS NULL` + scope + `
+  AND (` + historyClinicalDoc("h.") + ` @@ websearch_to_tsquery('english', @q)
+       OR @q <% h.diagnosis OR @q <% h.medical_notes OR @q <% h.prescriptions)
+ORDER BY rank DESC
+LIMIT @limit`
+		var historyRows []searchRow
+		if err := config.DB.Raw(historySQL, args).Scan(&historyRows).Error; err != nil {
+			return nil, err
+		}
+		rows = append(rows, historyRows...)
+	}
+
+	return mergeSearchRows(rows, limit), nil
+}
+
+// mergeSearchRows folds patient and visit hits into one result per patient,
+// keeping the best rank and every snippet.
+func mergeSearchRows(rows []searchRow, limit int) []PatientSearchResult {
+	byPatient := map[uint]*PatientSearchResult{}
+	var order []uint
+	for _, r := range rows {
+		result, ok := byPatient[r.PatientID]
+		if !ok {
+			result = &PatientSearchResult{PatientID: r.PatientID, Name: r.Name, PhoneNumber: r.PhoneNumber}
+			byPatient[r.PatientID] = result
+			order = append(order, r.PatientID)
+		}
+		if r.Rank > result.Rank {
+			result.Rank = r.Rank
+		}
+		snippet := SearchSnippet{Source: "patient", Text: r.Snippet}
+		if r.VisitID != 0 {
+			snippet.Source = "history"
+			snippet.VisitID = r.VisitID
+		}
+		if strings.TrimSpace(snippet.Text) != "" {
+			result.Snippets = append(result.Snippets, snippet)
+		}
+	}
+
+	results := make([]PatientSearchResult, 0, len(order))
+	for _, id := range order {
+		results = append(results, *byPatient[id])
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank > results[j].Rank })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func digitsOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// EnsureSearchIndexes enables pg_trgm and builds the GIN indexes used by SearchPatients
+func EnsureSearchIndexes() error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE INDEX IF NOT EXISTS idx_patients_demographic_fts ON patients USING GIN (` + patientDemographicDoc("") + `)`,
+		`CREATE INDEX IF NOT EXISTS idx_patients_clinical_fts ON patients USING GIN (` + patientClinicalDoc("") + `)`,
+		`CREATE INDEX IF NOT EXISTS idx_patients_name_trgm ON patients USING GIN (name gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_patients_diagnosis_trgm ON patients USING GIN (diagnosis gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_medical_histories_fts ON medical_histories USING GIN (` + historyClinicalDoc("") + `)`,
+		`CREATE INDEX IF NOT EXISTS idx_medical_histories_diagnosis_trgm ON medical_histories USING GIN (diagnosis gin_trgm_ops)`,
+	}
+	for _, stmt := range statements {
+		if err := config.DB.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used; the session has been revoked")
+)
+
+// CreateSession stores a new sign-in with its first refresh token
+func CreateSession(s *models.Session, tokenHash string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(s).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.RefreshToken{SessionID: s.ID, TokenHash: tokenHash}).Error
+	})
+}
+
+func GetSession(id uint) (models.Session, error) {
+	var s models.Session
+	err := config.DB.First(&s, id).Error
+	return s, err
+}
+
+// GetUserSessions lists a user's sessions that are still usable, newest first
+func GetUserSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := config.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// RotateRefreshToken spends the refresh token and stores its replacement.
+// A token that was already spent revokes the session it belongs to.
+func RotateRefreshToken(oldHash, newHash string) (models.Session, error) {
+	var session models.Session
+	reused := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var token models.RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", oldHash).First(&token).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRefreshTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&session, token.SessionID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if !session.Active(now) {
+			return ErrRefreshTokenInvalid
+		}
+		if token.UsedAt != nil {
+			reused = true
+			return revokeSessions(tx, "id", session.ID, models.SessionReuse)
+		}
+
+		if err := tx.Model(&token).Update("used_at", now).Error; err != nil {
+			return err
+		}
+		session.LastUsedAt = now
+		if err := tx.Model(&session).Update("last_used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.RefreshToken{SessionID: session.ID, TokenHash: newHash}).Error
+	})
+	if err == nil && reused {
+		err = ErrRefreshTokenReused
+	}
+	return session, err
+}
+
+// SessionForRefreshToken finds the session a refresh token belongs to
+func SessionForRefreshToken(tokenHash string) (models.Session, error) {
+	var token models.RefreshToken
+	if err := config.DB.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return models.Session{}, err
+	}
+	return GetSession(token.SessionID)
+}
+
+// RevokeSession ends one session. With userID set, only that user's session matches.
+func RevokeSession(id, userID uint, reason string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		q := tx.Where("id = ?", id)
+		if userID != 0 {
+			q = q.Where("user_id = ?", userID)
+		}
+		var session models.Session
+		if err := q.First(&session).Error; err != nil {
+			return err
+		}
+		return revokeSessions(tx, "id", session.ID, reason)
+	})
+}
+
+// RevokeUserSessions ends every session of a user, e.g. when a device is lost
+func RevokeUserSessions(userID uint, reason string) error {
+	return revokeSessions(config.DB, "user_id", userID, reason)
+}
+
+// revokeOtherSessions ends every session of a user except the one in use,
+// e.g. after a password change
+func revokeOtherSessions(tx *gorm.DB, userID, keepID uint, reason string) error {
+	return tx.Model(&models.Session{}).Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason}).Error
+}
+
+func revokeSessions(tx *gorm.DB, column string, value uint, reason string) error {
+	return tx.Model(&models.Session{}).Where(column+" = ? AND revoked_at IS NULL", value).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason}).Error
+}
+
+// PruneSessions deletes sessions, and their refresh tokens, that ended
+// before cutoff
+func PruneSessions(cutoff time.Time) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		ended := tx.Model(&models.Session{}).Select("id").
+			Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff)
+		if err := tx.Where("session_id IN (?)", ended).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).Delete(&models.Session{}).Error
+	})
+}
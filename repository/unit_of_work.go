@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+// UnitOfWork makes repository changes inside one transaction, so callers can
+// combine steps that must succeed or fail together, e.g. registering a
+// patient and adding them to a household. Get one from Atomically.
+type UnitOfWork struct {
+	tx *gorm.DB
+}
+
+// Atomically runs fn in a transaction: everything done through uow is
+// committed when fn returns nil and rolled back when it returns an error or
+// panics. The error fn returns is passed through unchanged.
+func Atomically(fn func(uow UnitOfWork) error) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(UnitOfWork{tx: tx})
+	})
+}
+
+// CreatePatient inserts p and fills in its ID
+func (u UnitOfWork) CreatePatient(p *models.Patient) error {
+	return u.tx.Create(p).Error
+}
+
+// CreateEncounter works like the package-level CreateEncounter
+func (u UnitOfWork) CreateEncounter(e *models.MedicalHistory, doctorID uint) error {
+	return createEncounter(u.tx, e, doctorID)
+}
+
+// CreateHousehold works like the package-level CreateHousehold
+func (u UnitOfWork) CreateHousehold(h *models.Household, headPatientID uint) error {
+	return createHousehold(u.tx, h, headPatientID)
+}
+
+// AddHouseholdMember works like the package-level AddHouseholdMember
+func (u UnitOfWork) AddHouseholdMember(householdID, patientID uint, relationship string) error {
+	return addHouseholdMember(u.tx, householdID, patientID, relationship)
+}
+
+// UpdateHouseholdMember works like the package-level UpdateHouseholdMember
+func (u UnitOfWork) UpdateHouseholdMember(householdID, patientID uint, relationship string) error {
+	return updateHouseholdMember(u.tx, householdID, patientID, relationship)
+}
+
+// RemoveHouseholdMember works like the package-level RemoveHouseholdMember
+func (u UnitOfWork) RemoveHouseholdMember(householdID, patientID uint) error {
+	return removeHouseholdMember(u.tx, householdID, patientID)
+}
+
+// SetHouseholdHead works like the package-level SetHouseholdHead
+func (u UnitOfWork) SetHouseholdHead(householdID, patientID uint) error {
+	return setHouseholdHead(u.tx, householdID, patientID)
+}
+
+// LockHouseholdMembers returns the household's member rows and keeps them
+// locked until the transaction ends, so they can be compared after a change
+func (u UnitOfWork) LockHouseholdMembers(householdID uint) ([]models.HouseholdMember, error) {
+	return lockHouseholdMembers(u.tx, householdID)
+}
+
+// GetPatientByID reads inside the transaction, so it sees changes made
+// through uow before they are committed
+func (u UnitOfWork) GetPatientByID(id int) (models.Patient, error) {
+	return getPatientByID(u.tx, id)
+}
+
+// GetPatientIncludingArchived works like GetPatientByID but also finds
+// archived patients
+func (u UnitOfWork) GetPatientIncludingArchived(id int) (models.Patient, error) {
+	return getPatientIncludingArchived(u.tx, id)
+}
+
+// UpdatePatient works like the package-level UpdatePatient
+func (u UnitOfWork) UpdatePatient(id int, updated models.Patient, version uint) error {
+	return updatePatient(u.tx, id, updated, version)
+}
+
+// DeletePatient works like the package-level DeletePatient
+func (u UnitOfWork) DeletePatient(id int, deletedBy uint, reason string) error {
+	return deletePatient(u.tx, id, deletedBy, reason)
+}
+
+// RestorePatient works like the package-level RestorePatient
+func (u UnitOfWork) RestorePatient(id int) error {
+	return restorePatient(u.tx, id)
+}
+
+// PurgePatient works like the package-level PurgePatient
+func (u UnitOfWork) PurgePatient(id int, retention time.Duration) error {
+	return purgePatient(u.tx, id, retention)
+}
+
+// CreatePrescription works like the package-level CreatePrescription
+func (u UnitOfWork) CreatePrescription(p *models.Prescription) error {
+	return createPrescription(u.tx, p)
+}
+
+// DiscontinuePrescription works like the package-level DiscontinuePrescription
+func (u UnitOfWork) DiscontinuePrescription(id int, userID uint, reason string) (models.Prescription, error) {
+	return discontinuePrescription(u.tx, id, userID, reason)
+}
+
+// CreatePatientAllergy works like the package-level CreatePatientAllergy
+func (u UnitOfWork) CreatePatientAllergy(a *models.PatientAllergy) error {
+	return createPatientAllergy(u.tx, a)
+}
+
+// DeletePatientAllergy works like the package-level DeletePatientAllergy
+func (u UnitOfWork) DeletePatientAllergy(patientID, allergyID int) (models.PatientAllergy, error) {
+	return deletePatientAllergy(u.tx, patientID, allergyID)
+}
+
+// CreateVitals works like the package-level CreateVitals
+func (u UnitOfWork) CreateVitals(v *models.Vitals) error {
+	return createVitals(u.tx, v)
+}
+
+// GetCareTeam reads inside the transaction, like GetPatientByID
+func (u UnitOfWork) GetCareTeam(patientID int) ([]models.CareTeamMember, error) {
+	return getCareTeam(u.tx, patientID)
+}
+
+// SetPrimaryDoctor works like the package-level SetPrimaryDoctor
+func (u UnitOfWork) SetPrimaryDoctor(patientID int, doctorID *uint) error {
+	return setPrimaryDoctor(u.tx, patientID, doctorID)
+}
+
+// AddCareTeamMember works like the package-level AddCareTeamMember
+func (u UnitOfWork) AddCareTeamMember(member *models.CareTeamMember) error {
+	return addCareTeamMember(u.tx, member)
+}
+
+// RemoveCareTeamMember works like the package-level RemoveCareTeamMember
+func (u UnitOfWork) RemoveCareTeamMember(patientID int, userID uint) error {
+	return removeCareTeamMember(u.tx, patientID, userID)
+}
+
+// RoleAssignedOnly reads inside the transaction, like GetPatientByID
+func (u UnitOfWork) RoleAssignedOnly(role string) (bool, error) {
+	return roleAssignedOnly(u.tx, role)
+}
+
+// SetRoleAssignedOnly works like the package-level SetRoleAssignedOnly
+func (u UnitOfWork) SetRoleAssignedOnly(role string, assignedOnly bool) (models.RolePatientScope, error) {
+	return setRoleAssignedOnly(u.tx, role, assignedOnly)
+}
+
+// CreateAuditEvents saves the events with the rest of the transaction, so a
+// change is never committed without its audit trail
+func (u UnitOfWork) CreateAuditEvents(events []models.AuditEvent) error {
+	return createAuditEvents(u.tx, events)
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+var ErrNotADoctor = notFoundError("user is not a doctor")
+
+func GetUserByID(id uint) (models.User, error) {
+	var u models.User
+	err := config.DB.First(&u, id).Error
+	return u, err
+}
+
+// GetDoctorByID returns the user only if their role is "doctor"
+func GetDoctorByID(id uint) (models.User, error) {
+	u, err := GetUserByID(id)
+	if err != nil {
+		return u, err
+	}
+	if u.Role != models.RoleDoctor {
+		return u, ErrNotADoctor
+	}
+	return u, nil
+}
+
+func GetDoctors() ([]models.User, error) {
+	var doctors []models.User
+	err := config.DB.Where("role = ? AND disabled_at IS NULL", models.RoleDoctor).Order("name").Find(&doctors).Error
+	return doctors, err
+}
+
+var (
+	ErrEmailTaken    = conflictError("a user with this email already exists")
+	ErrLastAdmin     = conflictError("at least one active admin must remain")
+	ErrInviteInvalid = errors.New("invite is invalid, expired or already used")
+)
+
+type UserFilter struct {
+	Role     string
+	Disabled *bool
+	Query    string
+	Limit    int
+	Offset   int
+}
+
+func ListUsers(f UserFilter) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+
+	q := config.DB.Model(&models.User{})
+	if f.Role != "" {
+		q = q.Where("role = ?", f.Role)
+	}
+	if f.Disabled != nil {
+		if *f.Disabled {
+			q = q.Where("disabled_at IS NOT NULL")
+		} else {
+			q = q.Where("disabled_at IS NULL")
+		}
+	}
+	if f.Query != "" {
+		like := "%" + strings.ToLower(f.Query) + "%"
+		q = q.Where("lower(name) LIKE ? OR lower(email) LIKE ?", like, like)
+	}
+
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := q.Order("name, id").Limit(f.Limit).Offset(f.Offset).Find(&users).Error
+	return users, total, err
+}
+
+// CreateUser inserts the user unless the email is already registered
+func CreateUser(u *models.User) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return createUser(tx, u)
+	})
+}
+
+func createUser(tx *gorm.DB, u *models.User) error {
+	var count int64
+	if err := tx.Unscoped().Model(&models.User{}).Where("lower(email) = lower(?)", u.Email).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrEmailTaken
+	}
+	return tx.Create(u).Error
+}
+
+// UpdateUserRole changes a user's role. The last active admin cannot be demoted.
+func UpdateUserRole(id uint, role string) (models.User, error) {
+	var u models.User
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&u, id).Error; err != nil {
+			return err
+		}
+		if u.Role == models.RoleAdmin && role != models.RoleAdmin && !u.Disabled() {
+			if err := ensureOtherAdmin(tx, u.ID); err != nil {
+				return err
+			}
+		}
+		u.Role = role
+		return tx.Model(&u).Update("role", role).Error
+	})
+	return u, err
+}
+
+// SetUserDisabled disables or re-enables an account. The last active admin
+// cannot be disabled.
+func SetUserDisabled(id uint, disabled bool) (models.User, error) {
+	var u models.User
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&u, id).Error; err != nil {
+			return err
+		}
+		if disabled == u.Disabled() {
+			return nil
+		}
+		var disabledAt *time.Time
+		if disabled {
+			if u.Role == models.RoleAdmin {
+				if err := ensureOtherAdmin(tx, u.ID); err != nil {
+					return err
+				}
+			}
+			now := time.Now()
+			disabledAt = &now
+		}
+		u.DisabledAt = disabledAt
+		return tx.Model(&u).Update("disabled_at", disabledAt).Error
+	})
+	return u, err
+}
+
+// ensureOtherAdmin locks the active admins so two concurrent demotions
+// cannot both see the other as the remaining admin
+func ensureOtherAdmin(tx *gorm.DB, exceptID uint) error {
+	var ids []uint
+	err := tx.Model(&models.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ? AND disabled_at IS NULL AND id <> ?", models.RoleAdmin, exceptID).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+func CreateInvite(invite *models.UserInvite) error {
+	var count int64
+	if err := config.DB.Unscoped().Model(&models.User{}).Where("lower(email) = lower(?)", invite.Email).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrEmailTaken
+	}
+	return config.DB.Create(invite).Error
+}
+
+func ListInvites(pendingOnly bool) ([]models.UserInvite, error) {
+	var invites []models.UserInvite
+	q := config.DB.Order("created_at DESC")
+	if pendingOnly {
+		q = q.Where("used_at IS NULL AND expires_at > ?", time.Now())
+	}
+	err := q.Find(&invites).Error
+	return invites, err
+}
+
+// DeleteInvite revokes an invite that has not been used yet
+func DeleteInvite(id uint) error {
+	result := config.DB.Where("id = ? AND used_at IS NULL", id).Delete(&models.UserInvite{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RedeemInvite creates the user described by the invite and marks it used,
+// in one transaction so a token can only be redeemed once
+func RedeemInvite(tokenHash string, u *models.User) (models.UserInvite, error) {
+	var invite models.UserInvite
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", tokenHash).First(&invite).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInviteInvalid
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if !invite.Usable(now) {
+			return ErrInviteInvalid
+		}
+
+		u.Email = invite.Email
+		u.Role = invite.Role
+		if u.RegistrationNumber == "" {
+			u.RegistrationNumber = invite.RegistrationNumber
+		}
+		if err := createUser(tx, u); err != nil {
+			return err
+		}
+		invite.UsedAt = &now
+		invite.UserID = &u.ID
+		return tx.Model(&invite).Updates(map[string]interface{}{"used_at": now, "user_id": u.ID}).Error
+	})
+	return invite, err
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+// CreateVitals saves a set of measurements. When weight is given without
+// height, the patient's last recorded height is used for BMI.
+func CreateVitals(v *models.Vitals) error {
+	return Atomically(func(uow UnitOfWork) error {
+		return uow.CreateVitals(v)
+	})
+}
+
+func createVitals(tx *gorm.DB, v *models.Vitals) error {
+	if err := tx.First(&models.Patient{}, v.PatientID).Error; err != nil {
+		return err
+	}
+	if v.VisitID != nil {
+		var visit models.MedicalHistory
+		if err := tx.First(&visit, *v.VisitID).Error; err != nil {
+			return err
+		}
+		if visit.PatientID != v.PatientID {
+			return ErrVisitNotForPatient
+		}
+	}
+
+	if v.Weight != nil && v.Height == nil {
+		var last models.Vitals
+		err := tx.Where("patient_id = ? AND height IS NOT NULL", v.PatientID).
+			Order("recorded_at DESC").First(&last).Error
+		if err == nil {
+			height := *last.Height
+			v.Height = &height
+			v.ComputeBMI()
+			v.Height = nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	} else {
+		v.ComputeBMI()
+	}
+	return tx.Create(v).Error
+}
+
+// GetPatientVitals lists measurements in [from, to), oldest first when
+// ascending. Zero times leave that end open; limit 0 means no limit.
+func GetPatientVitals(patientID int, from, to time.Time, limit int, ascending bool) ([]models.Vitals, error) {
+	var vitals []models.Vitals
+	q := config.DB.Where("patient_id = ?", patientID)
+	if !from.IsZero() {
+		q = q.Where("recorded_at >= ?", from)
+	}
+	if !to.IsZero() {
+		q = q.Where("recorded_at < ?", to)
+	}
+	if ascending {
+		q = q.Order("recorded_at ASC, id ASC")
+	} else {
+		q = q.Order("recorded_at DESC, id DESC")
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	err := q.Find(&vitals).Error
+	return vitals, err
+}
+
+func GetReferenceRanges() ([]models.VitalReferenceRange, error) {
+	var ranges []models.VitalReferenceRange
+	err := config.DB.Order("measure, gender, min_age").Find(&ranges).Error
+	return ranges, err
+}
+
+// ReplaceReferenceRanges swaps the whole set of ranges in one transaction
+func ReplaceReferenceRanges(ranges []models.VitalReferenceRange) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.VitalReferenceRange{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&ranges).Error
+	})
+}
+
+// SeedReferenceRanges installs ranges only when none are configured yet
+func SeedReferenceRanges(ranges []models.VitalReferenceRange) error {
+	var count int64
+	if err := config.DB.Model(&models.VitalReferenceRange{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return ReplaceReferenceRanges(ranges)
+}
@@ -4,31 +4,148 @@ import (
     "github.com/gin-gonic/gin"
     "github.com/Sathwik-145/hospital-portal/controllers"
     "github.com/Sathwik-145/hospital-portal/middleware"
+    "github.com/Sathwik-145/hospital-portal/models"
 )
 
 func SetupRoutes(router *gin.Engine) {
     // Auth routes (no middleware needed)
     auth := router.Group("/auth")
     {
-        auth.POST("/register", controllers.RegisterUser) // Updated to use RegisterUser
+        auth.POST("/register", controllers.RegisterUser) // Requires an invite token
         auth.POST("/login", controllers.LoginUser)      // Updated to use LoginUser
+        auth.POST("/refresh", controllers.RefreshToken)
+        auth.POST("/logout", controllers.Logout)
+        // Second login step when login answers with mfa_required
+        auth.POST("/mfa/verify", controllers.VerifyMFA)
+        auth.POST("/mfa/setup", controllers.EnrollMFAForChallenge)
+        auth.GET("/password/policy", controllers.GetPasswordPolicy)
+        auth.POST("/password/reset", controllers.ResetPasswordWithToken) // Token from an emailed reset link
     }
 
-    // Protected API routes
+    // The caller's own sessions, authenticator and password. These stay open
+    // to users who must change their password first.
+    sessions := router.Group("/auth")
+    sessions.Use(middleware.AuthMiddleware())
+    {
+        sessions.GET("/sessions", controllers.GetMySessions)
+        sessions.DELETE("/sessions/:id", controllers.RevokeMySession)
+        sessions.POST("/logout-all", controllers.LogoutAll)
+        sessions.GET("/mfa", controllers.GetMyMFA)
+        sessions.POST("/mfa/enroll", controllers.EnrollMFA)
+        sessions.POST("/mfa/confirm", controllers.ConfirmMFA)
+        sessions.POST("/mfa/recovery-codes", controllers.RegenerateRecoveryCodes)
+        sessions.DELETE("/mfa", controllers.DisableMyMFA)
+        sessions.POST("/password", controllers.ChangeMyPassword)
+    }
+
+    // Public keys for verifying our access tokens
+    router.GET("/.well-known/jwks.json", controllers.GetJWKS)
+
+    // Public check of the QR code on printed documents
+    router.GET("/verify/document", controllers.VerifyDocument)
+
+    // Protected API routes. Any signed-in staff member gets through
+    // AuthMiddleware; each route then requires a permission.
     api := router.Group("/api")
-    api.Use(middleware.AuthMiddleware("receptionist", "doctor"))
+    api.Use(middleware.AuthMiddleware(), middleware.PasswordChangeGuard())
     {
         // Patient CRUD routes
-        api.GET("/patients", controllers.GetAllPatients)
-        api.POST("/patients", controllers.CreatePatient)
-        api.PUT("/patients/:id", controllers.UpdatePatient)
-        api.DELETE("/patients/:id", controllers.DeletePatient)
+        api.GET("/patients", middleware.RequirePermission(models.PermPatientRead), controllers.GetAllPatients)
+        api.GET("/patients/search", middleware.RequirePermission(models.PermPatientRead), controllers.SearchPatients)
+        api.POST("/patients", middleware.RequirePermission(models.PermPatientCreate), controllers.CreatePatient)
+        api.PUT("/patients/:id", middleware.RequirePermission(models.PermPatientUpdate), middleware.RequirePatientAccess(), controllers.UpdatePatient)
+        api.PATCH("/patients/:id", middleware.RequirePermission(models.PermPatientUpdate), middleware.RequirePatientAccess(), controllers.PatchPatient)
+        api.DELETE("/patients/:id", middleware.RequirePermission(models.PermPatientDelete), middleware.RequirePatientAccess(), controllers.DeletePatient)
+        api.GET("/patients/archived", middleware.RequirePermission(models.PermPatientRead), controllers.GetArchivedPatients)
+        api.POST("/patients/:id/restore", middleware.RequirePermission(models.PermPatientDelete), middleware.RequirePatientAccess(), controllers.RestorePatient)
         
         // Individual patient routes
-        api.GET("/patients/:id", controllers.GetPatient)
-        api.GET("/patients/:id/history", controllers.GetPatientHistory)
-        
-        // Family history route (by phone number)
-        api.GET("/patients/phone/:phone/family-history", controllers.GetFamilyHistoryByPhone)
+        api.GET("/patients/:id", middleware.RequirePermission(models.PermPatientRead), middleware.RequirePatientAccess(), controllers.GetPatient)
+        api.GET("/patients/:id/history", middleware.RequirePermission(models.PermPatientClinicalRead), middleware.RequirePatientAccess(), controllers.GetPatientHistory)
+        api.POST("/patients/:id/encounters", middleware.RequirePermission(models.PermPatientClinicalWrite), middleware.RequirePatientAccess(), controllers.CreateEncounter)
+        api.GET("/patients/:id/history/:visitId/pdf", middleware.RequirePermission(models.PermPatientClinicalRead), middleware.RequirePatientAccess(), controllers.GetVisitPDF)
+        api.GET("/history", middleware.RequirePermission(models.PermPatientClinicalRead), controllers.GetHistoryByDoctor)
+        api.GET("/patients/:id/household", middleware.RequirePermission(models.PermHouseholdRead), middleware.RequirePatientAccess(), controllers.GetPatientHousehold)
+
+        // Care team and emergency (break-glass) access. Roles limited to
+        // assigned patients need one of these to open a patient.
+        api.GET("/patients/:id/care-team", middleware.RequirePermission(models.PermPatientRead), middleware.RequirePatientAccess(), controllers.GetCareTeam)
+        api.PUT("/patients/:id/primary-doctor", middleware.RequirePermission(models.PermPatientAssign), middleware.RequirePatientAccess(), controllers.SetPrimaryDoctor)
+        api.POST("/patients/:id/care-team", middleware.RequirePermission(models.PermPatientAssign), middleware.RequirePatientAccess(), controllers.AddCareTeamMember)
+        api.DELETE("/patients/:id/care-team/:userId", middleware.RequirePermission(models.PermPatientAssign), middleware.RequirePatientAccess(), controllers.RemoveCareTeamMember)
+        api.POST("/patients/:id/break-glass", middleware.RequirePermission(models.PermPatientRead), controllers.BreakGlass)
+
+        // Prescriptions and the medication catalog
+        api.POST("/patients/:id/prescriptions", middleware.RequirePermission(models.PermPrescriptionWrite), middleware.RequirePatientAccess(), controllers.CreatePrescription)
+        api.POST("/patients/:id/prescriptions/check", middleware.RequirePermission(models.PermPrescriptionWrite), middleware.RequirePatientAccess(), controllers.CheckPrescription)
+        api.GET("/patients/:id/medications", middleware.RequirePermission(models.PermPatientClinicalRead), middleware.RequirePatientAccess(), controllers.GetPatientMedications)
+        api.POST("/prescriptions/:id/discontinue", middleware.RequirePermission(models.PermPrescriptionWrite), controllers.DiscontinuePrescription)
+        api.GET("/medications", middleware.RequirePermission(models.PermMedicationRead), controllers.SearchMedications)
+
+        // Vitals
+        api.POST("/patients/:id/vitals", middleware.RequirePermission(models.PermVitalsWrite), middleware.RequirePatientAccess(), controllers.RecordVitals)
+        api.GET("/patients/:id/vitals", middleware.RequirePermission(models.PermPatientClinicalRead), middleware.RequirePatientAccess(), controllers.GetPatientVitals)
+        api.GET("/patients/:id/vitals/series", middleware.RequirePermission(models.PermPatientClinicalRead), middleware.RequirePatientAccess(), controllers.GetVitalSeries)
+        api.GET("/vitals/ranges", middleware.RequirePermission(models.PermPatientClinicalRead, models.PermCatalogManage), controllers.GetReferenceRanges)
+
+        // Allergies
+        api.GET("/patients/:id/allergies", middleware.RequirePermission(models.PermPatientClinicalRead), middleware.RequirePatientAccess(), controllers.GetPatientAllergies)
+        api.POST("/patients/:id/allergies", middleware.RequirePermission(models.PermPatientClinicalWrite), middleware.RequirePatientAccess(), controllers.AddPatientAllergy)
+        api.DELETE("/patients/:id/allergies/:allergyId", middleware.RequirePermission(models.PermPatientClinicalWrite), middleware.RequirePatientAccess(), controllers.DeletePatientAllergy)
+
+        // Household routes (replace the old phone-number family grouping)
+        api.POST("/households", middleware.RequirePermission(models.PermHouseholdManage), controllers.CreateHousehold)
+        api.GET("/households/:id", middleware.RequirePermission(models.PermHouseholdRead), controllers.GetHousehold)
+        api.GET("/households/:id/history", middleware.RequirePermission(models.PermHouseholdRead), controllers.GetHouseholdHistory)
+        api.POST("/households/:id/members", middleware.RequirePermission(models.PermHouseholdManage), controllers.AddHouseholdMember)
+        api.PUT("/households/:id/members/:patientId", middleware.RequirePermission(models.PermHouseholdManage), controllers.UpdateHouseholdMember)
+        api.DELETE("/households/:id/members/:patientId", middleware.RequirePermission(models.PermHouseholdManage), controllers.RemoveHouseholdMember)
+        api.PUT("/households/:id/head", middleware.RequirePermission(models.PermHouseholdManage), controllers.SetHouseholdHead)
+
+        // Appointment routes
+        api.GET("/appointments", middleware.RequirePermission(models.PermAppointmentRead), controllers.ListAppointments)
+        api.POST("/appointments", middleware.RequirePermission(models.PermAppointmentManage), controllers.CreateAppointment)
+        api.GET("/appointments/mine", middleware.RequirePermission(models.PermAppointmentRead), controllers.GetMyAppointments)
+        api.GET("/appointments/:id", middleware.RequirePermission(models.PermAppointmentRead), controllers.GetAppointment)
+        api.POST("/appointments/:id/cancel", middleware.RequirePermission(models.PermAppointmentManage), controllers.CancelAppointment)
+        api.PUT("/appointments/:id/status", middleware.RequirePermission(models.PermAppointmentManage, models.PermAppointmentComplete), controllers.UpdateAppointmentStatus)
+
+        // Doctor availability and slot search
+        api.GET("/doctors", middleware.RequirePermission(models.PermScheduleRead), controllers.GetDoctors)
+        api.GET("/doctors/:id/availability", middleware.RequirePermission(models.PermScheduleRead), controllers.GetDoctorAvailability)
+        api.PUT("/doctors/:id/availability", middleware.RequirePermission(models.PermScheduleManage, models.PermScheduleManageOwn), controllers.SetDoctorAvailability)
+        api.POST("/doctors/:id/leaves", middleware.RequirePermission(models.PermScheduleManage, models.PermScheduleManageOwn), controllers.AddDoctorLeave)
+        api.DELETE("/doctors/:id/leaves/:leaveId", middleware.RequirePermission(models.PermScheduleManage, models.PermScheduleManageOwn), controllers.DeleteDoctorLeave)
+        api.GET("/slots", middleware.RequirePermission(models.PermScheduleRead), controllers.FindSlots)
+
+        // Administration
+        api.GET("/audit", middleware.RequirePermission(models.PermAuditRead), controllers.GetAuditEvents)
+        api.GET("/admin/break-glass", middleware.RequirePermission(models.PermAuditRead), controllers.ListBreakGlassAccess)
+        api.DELETE("/admin/patients/:id/purge", middleware.RequirePermission(models.PermPatientPurge), controllers.PurgePatient)
+        api.POST("/admin/medications/import", middleware.RequirePermission(models.PermCatalogManage), controllers.ImportMedications)
+        api.POST("/admin/interactions/import", middleware.RequirePermission(models.PermCatalogManage), controllers.ImportInteractionRules)
+        api.PUT("/admin/vitals/ranges", middleware.RequirePermission(models.PermCatalogManage), controllers.ReplaceReferenceRanges)
+        api.GET("/admin/roles", middleware.RequirePermission(models.PermRoleManage), controllers.GetRolePermissions)
+        api.PUT("/admin/roles/:role/permissions", middleware.RequirePermission(models.PermRoleManage), controllers.UpdateRolePermissions)
+        api.PUT("/admin/roles/:role/mfa", middleware.RequirePermission(models.PermRoleManage), controllers.UpdateRoleMFA)
+        api.PUT("/admin/roles/:role/patient-scope", middleware.RequirePermission(models.PermRoleManage), controllers.UpdateRolePatientScope)
+
+        // Staff accounts and invites
+        api.GET("/admin/users", middleware.RequirePermission(models.PermUserManage), controllers.ListUsers)
+        api.POST("/admin/users", middleware.RequirePermission(models.PermUserManage), controllers.CreateUser)
+        api.GET("/admin/users/:id", middleware.RequirePermission(models.PermUserManage), controllers.GetUser)
+        api.PUT("/admin/users/:id/role", middleware.RequirePermission(models.PermUserManage), controllers.UpdateUserRole)
+        api.POST("/admin/users/:id/disable", middleware.RequirePermission(models.PermUserManage), controllers.DisableUser)
+        api.POST("/admin/users/:id/enable", middleware.RequirePermission(models.PermUserManage), controllers.EnableUser)
+        api.POST("/admin/users/:id/password", middleware.RequirePermission(models.PermUserManage), controllers.ResetUserPassword)
+        api.POST("/admin/users/:id/logout-all", middleware.RequirePermission(models.PermUserManage), controllers.RevokeUserSessions)
+        api.DELETE("/admin/users/:id/mfa", middleware.RequirePermission(models.PermUserManage), controllers.ResetUserMFA)
+        api.POST("/admin/users/:id/password-reset", middleware.RequirePermission(models.PermUserManage), controllers.SendPasswordReset)
+        api.POST("/admin/users/:id/require-password-change", middleware.RequirePermission(models.PermUserManage), controllers.RequirePasswordChange)
+        api.POST("/admin/users/:id/unlock", middleware.RequirePermission(models.PermUserManage), controllers.UnlockUser)
+        api.DELETE("/admin/lockouts/ip/:ip", middleware.RequirePermission(models.PermUserManage), controllers.UnlockIP)
+        api.GET("/admin/invites", middleware.RequirePermission(models.PermUserManage), controllers.ListInvites)
+        api.POST("/admin/invites", middleware.RequirePermission(models.PermUserManage), controllers.CreateInvite)
+        api.DELETE("/admin/invites/:id", middleware.RequirePermission(models.PermUserManage), controllers.RevokeInvite)
     }
 }
\ No newline at end of file
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+// AuditActor identifies who is touching a record and from where
+type AuditActor struct {
+	UserID   uint
+	Role     string
+	ClientIP string
+}
+
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Fields that change on every write or are audited separately
+var auditIgnoredFields = map[string]bool{
+	"updated_at":      true,
+	"medical_history": true,
+}
+
+// RecordPatientAccess appends one read event per patient
+func RecordPatientAccess(actor AuditActor, action string, patientIDs ...uint) error {
+	events := make([]models.AuditEvent, 0, len(patientIDs))
+	for _, id := range patientIDs {
+		id := id
+		events = append(events, actor.event(action, &id))
+	}
+	return repository.CreateAuditEvents(events)
+}
+
+// RecordPatientChange appends a write event with before/after snapshots and
+// a field-level diff. Either snapshot may be nil (create, delete).
+func RecordPatientChange(actor AuditActor, action string, patientID uint, before, after interface{}) error {
+	return recordChange(actor, action, &patientID, before, after)
+}
+
+// RecordPatientChangeIn works like RecordPatientChange but saves the event
+// through uow, so it is committed or rolled back with the change it describes
+func RecordPatientChangeIn(uow repository.UnitOfWork, actor AuditActor, action string, patientID uint, before, after interface{}) error {
+	event, err := changeEvent(actor, action, &patientID, before, after)
+	if err != nil {
+		return err
+	}
+	return uow.CreateAuditEvents([]models.AuditEvent{event})
+}
+
+// RecordChange appends a write event that is not about a patient, e.g. a
+// configuration change
+func RecordChange(actor AuditActor, action string, before, after interface{}) error {
+	return recordChange(actor, action, nil, before, after)
+}
+
+// RecordChangeIn works like RecordChange but saves the event through uow,
+// so it is committed or rolled back with the change it describes
+func RecordChangeIn(uow repository.UnitOfWork, actor AuditActor, action string, before, after interface{}) error {
+	event, err := changeEvent(actor, action, nil, before, after)
+	if err != nil {
+		return err
+	}
+	return uow.CreateAuditEvents([]models.AuditEvent{event})
+}
+
+func recordChange(actor AuditActor, action string, patientID *uint, before, after interface{}) error {
+	event, err := changeEvent(actor, action, patientID, before, after)
+	if err != nil {
+		return err
+	}
+	return repository.CreateAuditEvents([]models.AuditEvent{event})
+}
+
+func changeEvent(actor AuditActor, action string, patientID *uint, before, after interface{}) (models.AuditEvent, error) {
+	event := actor.event(action, patientID)
+
+	beforeMap, err := toFieldMap(before)
+	if err != nil {
+		return event, err
+	}
+	afterMap, err := toFieldMap(after)
+	if err != nil {
+		return event, err
+	}
+
+	if event.Before, err = marshalText(beforeMap); err != nil {
+		return event, err
+	}
+	if event.After, err = marshalText(afterMap); err != nil {
+		return event, err
+	}
+	if event.Diff, err = marshalText(diffFields(beforeMap, afterMap)); err != nil {
+		return event, err
+	}
+	return event, nil
+}
+
+func (a AuditActor) event(action string, patientID *uint) models.AuditEvent {
+	return models.AuditEvent{
+		ActorID:   a.UserID,
+		ActorRole: a.Role,
+		Action:    action,
+		PatientID: patientID,
+		ClientIP:  a.ClientIP,
+	}
+}
+
+// toFieldMap flattens a model into its JSON field names
+func toFieldMap(v interface{}) (map[string]interface{}, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for name := range auditIgnoredFields {
+		delete(fields, name)
+	}
+	return fields, nil
+}
+
+func diffFields(before, after map[string]interface{}) map[string]FieldChange {
+	diff := map[string]FieldChange{}
+	for name, to := range after {
+		if from, ok := before[name]; !ok || !reflect.DeepEqual(from, to) {
+			diff[name] = FieldChange{From: before[name], To: to}
+		}
+	}
+	for name, from := range before {
+		if _, ok := after[name]; !ok {
+			diff[name] = FieldChange{From: from, To: nil}
+		}
+	}
+	return diff
+}
+
+func marshalText(v interface{}) (models.JSONText, error) {
+	if reflect.ValueOf(v).IsNil() {
+		return "", nil
+	}
+	raw, err := json.Marshal(v)
+	return models.JSONText(raw), err
+}
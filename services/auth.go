@@ -2,23 +2,61 @@ package services
 
 import (
 	"errors"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
 
 	"github.com/Sathwik-145/hospital-portal/models"
 	"github.com/Sathwik-145/hospital-portal/config"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func AuthenticateUser(email, password string) (*models.User, error) {
+// Compared against when the email is unknown, so the response takes as long
+// as for a real account
+var dummyHash struct {
+	once sync.Once
+	hash []byte
+}
+
+// AuthenticateUser checks a password. Failures are counted per account and
+// per client IP; once either is throttled it returns a LoginThrottledError
+// without looking at the password. A correct password doesn't reset the
+// count: that waits for StartSession, after any second factor.
+func AuthenticateUser(email, password, clientIP string) (*models.User, error) {
+	if err := checkLoginAllowed(accountKey(email), ipKey(clientIP)); err != nil {
+		return nil, err
+	}
+
 	var user models.User
-	result := config.DB.Where("email = ?", email).First(&user)
+	result := config.DB.Where("lower(email) = lower(?)", strings.TrimSpace(email)).First(&user)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, result.Error
+	}
 	if result.Error != nil {
-		return nil, errors.New("invalid email or password")
+		dummyHash.once.Do(func() {
+			dummyHash.hash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+		})
+		_ = bcrypt.CompareHashAndPassword(dummyHash.hash, []byte(password))
+		return nil, loginFailed(email, clientIP)
 	}
 
 	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return nil, errors.New("invalid email or password")
+		return nil, loginFailed(email, clientIP)
+	}
+	if user.Disabled() {
+		return nil, ErrAccountDisabled
 	}
 
 	return &user, nil
 }
+
+// loginFailed counts the failure. Unknown emails are counted too, so
+// lockouts don't reveal which accounts exist.
+func loginFailed(email, clientIP string) error {
+	if err := recordLoginFailure(email, clientIP); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+var (
+	ErrPatientNotAssigned  = errors.New("this patient is not assigned to you")
+	ErrBreakGlassNotNeeded = errors.New("you already have access to this patient")
+	ErrNotADoctor          = errors.New("must be an active doctor")
+	ErrInactiveStaff       = errors.New("must be an active staff account")
+)
+
+// PatientScope returns the user to limit patient lists to, or 0 when the
+// caller's role sees every patient
+func PatientScope(userID uint, role string) (uint, error) {
+	assignedOnly, err := repository.RoleAssignedOnly(role)
+	if err != nil || !assignedOnly {
+		return 0, err
+	}
+	return userID, nil
+}
+
+// CheckPatientAccess fails with ErrPatientNotAssigned when the caller's role
+// is limited to assigned patients and they are not on this patient's care
+// team. Access through break glass returns the grant in use.
+func CheckPatientAccess(userID uint, role string, patientID int) (*models.BreakGlassAccess, error) {
+	scope, err := PatientScope(userID, role)
+	if err != nil || scope == 0 {
+		return nil, err
+	}
+	onTeam, err := repository.IsOnCareTeam(userID, patientID)
+	if err != nil || onTeam {
+		return nil, err
+	}
+	grant, err := repository.GetActiveBreakGlass(userID, patientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPatientNotAssigned
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// FilterAssignedPatients keeps the patients in ids the caller may open
+func FilterAssignedPatients(userID uint, role string, ids []uint) ([]uint, error) {
+	scope, err := PatientScope(userID, role)
+	if err != nil || scope == 0 {
+		return ids, err
+	}
+	return repository.FilterAssignedPatients(userID, ids)
+}
+
+// BreakGlass gives the caller emergency access to a patient they are not
+// assigned to, for BREAK_GLASS_MINUTES. The reason is audited before access
+// is granted, so there is never a grant without its audit event.
+func BreakGlass(actor AuditActor, patientID int, reason string) (models.BreakGlassAccess, error) {
+	if _, err := CheckPatientAccess(actor.UserID, actor.Role, patientID); !errors.Is(err, ErrPatientNotAssigned) {
+		if err == nil {
+			err = ErrBreakGlassNotNeeded
+		}
+		return models.BreakGlassAccess{}, err
+	}
+
+	grant := models.BreakGlassAccess{
+		PatientID: uint(patientID),
+		UserID:    actor.UserID,
+		Reason:    reason,
+		ExpiresAt: time.Now().Add(config.BreakGlassTTL()),
+	}
+	audited := map[string]interface{}{"reason": reason, "expires_at": grant.ExpiresAt}
+	if err := RecordPatientChange(actor, models.AuditPatientBreakGlass, grant.PatientID, nil, audited); err != nil {
+		return models.BreakGlassAccess{}, err
+	}
+	err := repository.CreateBreakGlassAccess(&grant)
+	return grant, err
+}
+
+// AuthorizePatientAccess is CheckPatientAccess for one request, auditing it
+// when it is made through break glass
+func AuthorizePatientAccess(actor AuditActor, patientID int, method, path string) error {
+	grant, err := CheckPatientAccess(actor.UserID, actor.Role, patientID)
+	if err != nil || grant == nil {
+		return err
+	}
+	if err := recordBreakGlassUse(actor, *grant, method, path); err != nil {
+		return fmt.Errorf("recording break-glass access: %w", err)
+	}
+	return nil
+}
+
+func recordBreakGlassUse(actor AuditActor, grant models.BreakGlassAccess, method, path string) error {
+	use := map[string]interface{}{
+		"grant_id": grant.ID,
+		"reason":   grant.Reason,
+		"method":   method,
+		"path":     path,
+	}
+	return RecordPatientChange(actor, models.AuditPatientBreakGlassUse, grant.PatientID, nil, use)
+}
+
+// CareTeam is everyone assigned to a patient
+type CareTeam struct {
+	PrimaryDoctor *models.User            `json:"primary_doctor"`
+	Members       []models.CareTeamMember `json:"members"`
+}
+
+func GetCareTeam(patient models.Patient) (CareTeam, error) {
+	team := CareTeam{}
+	if patient.PrimaryDoctorID != nil {
+		doctor, err := repository.GetUserByID(*patient.PrimaryDoctorID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return team, err
+		}
+		if err == nil {
+			team.PrimaryDoctor = &doctor
+		}
+	}
+	members, err := repository.GetCareTeam(int(patient.ID))
+	if err != nil {
+		return team, err
+	}
+	team.Members = members
+	return team, nil
+}
+
+// CheckPrimaryDoctor makes sure a patient is assigned to an active doctor
+func CheckPrimaryDoctor(doctorID uint) error {
+	doctor, err := repository.GetUserByID(doctorID)
+	if err != nil || doctor.Role != models.RoleDoctor || doctor.Disabled() {
+		return ErrNotADoctor
+	}
+	return nil
+}
+
+// SetPrimaryDoctor assigns the patient to a doctor; nil unassigns. The
+// change is made through uow so it can be audited in the same transaction.
+func SetPrimaryDoctor(uow repository.UnitOfWork, patientID int, doctorID *uint) error {
+	if doctorID != nil {
+		if err := CheckPrimaryDoctor(*doctorID); err != nil {
+			return err
+		}
+	}
+	return uow.SetPrimaryDoctor(patientID, doctorID)
+}
+
+func AddCareTeamMember(uow repository.UnitOfWork, member *models.CareTeamMember) error {
+	user, err := repository.GetUserByID(member.UserID)
+	if err != nil || user.Disabled() {
+		return ErrInactiveStaff
+	}
+	return uow.AddCareTeamMember(member)
+}
+
+func RemoveCareTeamMember(uow repository.UnitOfWork, patientID int, userID uint) error {
+	return uow.RemoveCareTeamMember(patientID, userID)
+}
+
+// RolePatientScopes maps each role to whether it only sees assigned patients
+func RolePatientScopes() (map[string]bool, error) {
+	rows, err := repository.GetRolePatientScopes()
+	if err != nil {
+		return nil, err
+	}
+	scopes := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		scopes[r.Role] = r.AssignedOnly
+	}
+	return scopes, nil
+}
+
+// SetRoleAssignedOnly changes the role's patient scope and audits it in the
+// same transaction; an access-control change is never saved unaudited
+func SetRoleAssignedOnly(actor AuditActor, role string, assignedOnly bool) (models.RolePatientScope, error) {
+	if !models.ValidRole(role) {
+		return models.RolePatientScope{}, ErrUnknownRole
+	}
+	var scope models.RolePatientScope
+	err := repository.Atomically(func(uow repository.UnitOfWork) error {
+		before, err := uow.RoleAssignedOnly(role)
+		if err != nil {
+			return err
+		}
+		if scope, err = uow.SetRoleAssignedOnly(role, assignedOnly); err != nil {
+			return err
+		}
+		change := func(assignedOnly bool) map[string]interface{} {
+			return map[string]interface{}{"role": role, "assigned_only": assignedOnly}
+		}
+		return RecordChangeIn(uow, actor, models.AuditRolePatientScope, change(before), change(scope.AssignedOnly))
+	})
+	return scope, err
+}
+
+// SeedRolePatientScopes installs the default scope for roles that have none
+func SeedRolePatientScopes() error {
+	return repository.SeedRolePatientScopes(models.Roles, models.DefaultAssignedOnlyRoles)
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvRecord is one data row keyed by lowercased header name
+type csvRecord struct {
+	Line   int
+	Fields map[string]string
+}
+
+func (r csvRecord) get(col string) string {
+	return r.Fields[col]
+}
+
+// readCSV reads a file with a header row. Column order is free and unknown
+// columns are kept but ignored by callers. Every required column must be in
+// the header.
+func readCSV(r io.Reader, known []string, required ...string) ([]csvRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("empty CSV file")
+		}
+		return nil, err
+	}
+	for i, col := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))
+	}
+	for _, col := range required {
+		found := false
+		for _, h := range header {
+			if h == col {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("CSV header must include a %s column (known columns: %s)", col, strings.Join(known, ", "))
+		}
+	}
+	reader.FieldsPerRecord = len(header)
+
+	var records []csvRecord
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := csvRecord{Line: line, Fields: make(map[string]string, len(header))}
+		for i, col := range header {
+			record.Fields[col] = strings.TrimSpace(row[i])
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("CSV file has no rows")
+	}
+	return records, nil
+}
+
+func parseCSVBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+// Printable document types
+const (
+	DocumentPrescription = "prescription"
+	DocumentVisitSummary = "visit-summary"
+)
+
+// Verification outcomes
+const (
+	VerificationValid         = "valid"
+	VerificationRecordChanged = "record_changed"
+	VerificationInvalid       = "invalid"
+)
+
+var ErrSigningKeyMissing = errors.New("DOCUMENT_SIGNING_KEY is not configured")
+
+// VisitDocument is everything printed on a prescription slip or visit summary
+type VisitDocument struct {
+	Type          string
+	Clinic        config.Clinic
+	Visit         models.MedicalHistory
+	Doctor        *models.User
+	Prescriptions []models.Prescription
+}
+
+// DocumentSignature is what the verification QR code carries
+type DocumentSignature struct {
+	IssuedAt  time.Time
+	Hash      string
+	Signature string
+	VerifyURL string
+}
+
+type VerificationResult struct {
+	Status             string    `json:"status"`
+	Valid              bool      `json:"valid"`
+	DocumentType       string    `json:"document_type,omitempty"`
+	IssuedAt           time.Time `json:"issued_at,omitempty"`
+	Clinic             string    `json:"clinic,omitempty"`
+	DoctorName         string    `json:"doctor_name,omitempty"`
+	RegistrationNumber string    `json:"registration_number,omitempty"`
+	VisitDate          string    `json:"visit_date,omitempty"`
+	PatientInitials    string    `json:"patient_initials,omitempty"`
+}
+
+func ValidDocumentType(t string) bool {
+	return t == DocumentPrescription || t == DocumentVisitSummary
+}
+
+// LoadVisitDocument gathers the data for one visit of one patient
+func LoadVisitDocument(docType string, patientID, visitID int) (VisitDocument, error) {
+	visit, err := repository.GetPatientVisit(patientID, visitID)
+	if err != nil {
+		return VisitDocument{}, err
+	}
+	return buildVisitDocument(docType, visit)
+}
+
+func buildVisitDocument(docType string, visit models.MedicalHistory) (VisitDocument, error) {
+	prescriptions, err := repository.GetVisitPrescriptions(visit.ID)
+	if err != nil {
+		return VisitDocument{}, err
+	}
+	return VisitDocument{
+		Type:          docType,
+		Clinic:        config.ClinicBranding(),
+		Visit:         visit,
+		Doctor:        visit.Doctor,
+		Prescriptions: prescriptions,
+	}, nil
+}
+
+func (d VisitDocument) DoctorName() string {
+	if d.Doctor != nil {
+		return d.Doctor.Name
+	}
+	return d.Visit.DoctorName
+}
+
+func (d VisitDocument) RegistrationNumber() string {
+	if d.Doctor != nil {
+		return d.Doctor.RegistrationNumber
+	}
+	return ""
+}
+
+// Fingerprint is a SHA-256 over the clinical content of the document. It
+// changes if anything printed on it changes in the record.
+func (d VisitDocument) Fingerprint() (string, error) {
+	type item struct {
+		Drug, Strength, Form, Dose, Route, Frequency string
+		DurationDays, Quantity, Refills              int
+		Instructions                                 string
+	}
+	content := struct {
+		Type               string
+		VisitID, PatientID uint
+		PatientName        string
+		DateOfBirth        string
+		Gender             string
+		VisitDate          string
+		DoctorName         string
+		RegistrationNumber string
+		Diagnosis          string
+		MedicalNotes       string
+		Prescriptions      string
+		Items              []item
+		// Encounter fields; omitted when empty so visits recorded before
+		// they existed keep their fingerprint
+		ChiefComplaint string `json:",omitempty"`
+		Findings       string `json:",omitempty"`
+		Plan           string `json:",omitempty"`
+	}{
+		Type:               d.Type,
+		VisitID:            d.Visit.ID,
+		PatientID:          d.Visit.PatientID,
+		PatientName:        d.Visit.PatientName,
+		DateOfBirth:        d.Visit.DateOfBirth.String(),
+		Gender:             d.Visit.Gender,
+		VisitDate:          d.Visit.VisitDate.UTC().Format(time.RFC3339),
+		DoctorName:         d.DoctorName(),
+		RegistrationNumber: d.RegistrationNumber(),
+		Diagnosis:          d.Visit.Diagnosis,
+		Prescriptions:      d.Visit.Prescriptions,
+	}
+	if d.Type == DocumentVisitSummary {
+		content.MedicalNotes = d.Visit.MedicalNotes
+		content.ChiefComplaint = d.Visit.ChiefComplaint
+		content.Findings = d.Visit.Findings
+		content.Plan = d.Visit.Plan
+	}
+	for _, p := range d.Prescriptions {
+		content.Items = append(content.Items, item{
+			p.Drug, p.Strength, p.Form, p.Dose, p.Route, p.Frequency,
+			p.DurationDays, p.Quantity, p.Refills, p.Instructions,
+		})
+	}
+
+	b, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SignDocument issues a verification code for the document as it is now
+func SignDocument(d VisitDocument, issuedAt time.Time) (DocumentSignature, error) {
+	key := config.DocumentSigningKey()
+	if len(key) == 0 {
+		return DocumentSignature{}, ErrSigningKeyMissing
+	}
+	hash, err := d.Fingerprint()
+	if err != nil {
+		return DocumentSignature{}, err
+	}
+	issuedAt = issuedAt.UTC().Truncate(time.Second)
+	sig := documentMAC(key, d.Type, d.Visit.ID, issuedAt.Unix(), hash)
+
+	q := url.Values{}
+	q.Set("t", d.Type)
+	q.Set("v", strconv.FormatUint(uint64(d.Visit.ID), 10))
+	q.Set("i", strconv.FormatInt(issuedAt.Unix(), 10))
+	q.Set("h", hash)
+	q.Set("s", sig)
+
+	return DocumentSignature{
+		IssuedAt:  issuedAt,
+		Hash:      hash,
+		Signature: sig,
+		VerifyURL: config.PublicBaseURL() + "/verify/document?" + q.Encode(),
+	}, nil
+}
+
+// VerifyDocument checks a scanned code. The signature proves this clinic
+// issued the code; the hash is then compared with the record as it is now.
+func VerifyDocument(docType string, visitID uint, issuedUnix int64, hash, sig string) (VerificationResult, error) {
+	invalid := VerificationResult{Status: VerificationInvalid}
+	key := config.DocumentSigningKey()
+	if len(key) == 0 {
+		return invalid, ErrSigningKeyMissing
+	}
+	if !ValidDocumentType(docType) {
+		return invalid, nil
+	}
+	expected := documentMAC(key, docType, visitID, issuedUnix, hash)
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return invalid, nil
+	}
+
+	visit, err := repository.GetVisit(int(visitID))
+	if err != nil {
+		// Signed by us but the visit is gone, e.g. purged
+		return invalid, nil
+	}
+	d, err := buildVisitDocument(docType, visit)
+	if err != nil {
+		return invalid, err
+	}
+	current, err := d.Fingerprint()
+	if err != nil {
+		return invalid, err
+	}
+
+	result := VerificationResult{
+		Status:             VerificationValid,
+		Valid:              true,
+		DocumentType:       docType,
+		IssuedAt:           time.Unix(issuedUnix, 0).UTC(),
+		Clinic:             d.Clinic.Name,
+		DoctorName:         d.DoctorName(),
+		RegistrationNumber: d.RegistrationNumber(),
+		VisitDate:          visit.VisitDate.Format(models.DateLayout),
+		PatientInitials:    initials(visit.PatientName),
+	}
+	if current != hash {
+		result.Status = VerificationRecordChanged
+		result.Valid = false
+	}
+	return result, nil
+}
+
+func documentMAC(key []byte, docType string, visitID uint, issuedUnix int64, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "v1|%s|%d|%d|%s", docType, visitID, issuedUnix, hash)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// initials keeps the public verification page free of patient names
+func initials(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Fields(name) {
+		r := []rune(part)
+		b.WriteString(strings.ToUpper(string(r[0])))
+		b.WriteString(".")
+	}
+	return b.String()
+}
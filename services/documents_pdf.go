@@ -0,0 +1,219 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/skip2/go-qrcode"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+const qrSizeMM = 28
+
+// RenderVisitPDF writes the document as a PDF: an A5 slip for prescriptions,
+// A4 for visit summaries. The footer carries the verification QR code.
+func RenderVisitPDF(w io.Writer, d VisitDocument, sig DocumentSignature) error {
+	size, title := "A4", "Visit Summary"
+	if d.Type == DocumentPrescription {
+		size, title = "A5", "Prescription"
+	}
+
+	pdf := gofpdf.New("P", "mm", size, "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(tr(title+" - "+d.Clinic.Name), false)
+	pdf.SetAuthor(tr(d.Clinic.Name), false)
+	pdf.SetCreationDate(sig.IssuedAt)
+	pdf.SetMargins(12, 12, 12)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-10)
+		pdf.SetFont("Helvetica", "I", 7)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 4, tr(fmt.Sprintf("%s - visit #%d - page %d/{nb}", title, d.Visit.ID, pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+	pageW, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	width := pageW - left - right
+
+	// Clinic header
+	pdf.SetFont("Helvetica", "B", 15)
+	pdf.SetTextColor(20, 60, 110)
+	pdf.CellFormat(0, 7, tr(d.Clinic.Name), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(80, 80, 80)
+	var contact []string
+	for _, v := range []string{d.Clinic.Address, d.Clinic.Phone, d.Clinic.Email} {
+		if v != "" {
+			contact = append(contact, v)
+		}
+	}
+	if len(contact) > 0 {
+		pdf.MultiCell(0, 4, tr(strings.Join(contact, "  |  ")), "", "L", false)
+	}
+	pdf.SetDrawColor(20, 60, 110)
+	pdf.SetLineWidth(0.5)
+	pdf.Line(left, pdf.GetY()+1, left+width, pdf.GetY()+1)
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(0, 7, tr(title), "", 1, "C", false, 0, "")
+	pdf.Ln(1)
+
+	// Patient and doctor details
+	visit := d.Visit
+	ageGender := fmt.Sprintf("%d y", visit.Age)
+	if visit.DateOfBirth.IsZero() {
+		ageGender = "-"
+	}
+	if visit.Gender != "" {
+		ageGender += " / " + visit.Gender
+	}
+	regNo := d.RegistrationNumber()
+	if regNo == "" {
+		regNo = "Not recorded"
+	}
+	rows := [][4]string{
+		{"Patient", visit.PatientName, "Visit date", visit.VisitDate.Format("02 Jan 2006")},
+		{"Patient ID", fmt.Sprintf("%d", visit.PatientID), "Age / Gender", ageGender},
+		{"Doctor", d.DoctorName(), "Reg. No.", regNo},
+	}
+	labelW, valueW := width*0.17, width*0.33
+	for _, r := range rows {
+		for i := 0; i < 4; i += 2 {
+			pdf.SetFont("Helvetica", "B", 9)
+			pdf.CellFormat(labelW, 5, tr(r[i]+":"), "", 0, "L", false, 0, "")
+			pdf.SetFont("Helvetica", "", 9)
+			pdf.CellFormat(valueW, 5, tr(r[i+1]), "", 0, "L", false, 0, "")
+		}
+		pdf.Ln(5)
+	}
+	pdf.Ln(2)
+
+	section := func(heading, body string) {
+		if strings.TrimSpace(body) == "" {
+			return
+		}
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(0, 6, tr(heading), "B", 1, "L", false, 0, "")
+		pdf.Ln(1)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, 4.5, tr(body), "", "L", false)
+		pdf.Ln(2)
+	}
+
+	if d.Type == DocumentVisitSummary {
+		section("Chief complaint", visit.ChiefComplaint)
+		section("Findings", visit.Findings)
+		section("Diagnosis", visit.Diagnosis)
+		section("Plan", visit.Plan)
+		section("Clinical notes", visit.MedicalNotes)
+	} else if visit.Diagnosis != "" {
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, 4.5, tr("Diagnosis: "+visit.Diagnosis), "", "L", false)
+		pdf.Ln(2)
+	}
+
+	if len(d.Prescriptions) > 0 {
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(0, 7, "Rx", "", 1, "L", false, 0, "")
+		for i, p := range d.Prescriptions {
+			pdf.SetFont("Helvetica", "B", 9.5)
+			pdf.MultiCell(0, 5, tr(fmt.Sprintf("%d. %s", i+1, prescriptionName(p))), "", "L", false)
+			pdf.SetFont("Helvetica", "", 9)
+			pdf.SetX(left + 5)
+			pdf.MultiCell(width-5, 4.5, tr(prescriptionDirections(p)), "", "L", false)
+			if p.Instructions != "" {
+				pdf.SetFont("Helvetica", "I", 8.5)
+				pdf.SetX(left + 5)
+				pdf.MultiCell(width-5, 4.5, tr(p.Instructions), "", "L", false)
+			}
+			pdf.Ln(1.5)
+		}
+		pdf.Ln(1)
+	}
+	heading := "Prescriptions"
+	if len(d.Prescriptions) > 0 {
+		heading = "Additional instructions"
+	}
+	section(heading, visit.Prescriptions)
+
+	// Signature and verification block, kept together on one page
+	_, pageH := pdf.GetPageSize()
+	if pdf.GetY()+qrSizeMM+12 > pageH-15 {
+		pdf.AddPage()
+	}
+	pdf.Ln(4)
+	top := pdf.GetY()
+
+	png, err := qrcode.Encode(sig.VerifyURL, qrcode.Medium, 256)
+	if err != nil {
+		return err
+	}
+	opts := gofpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader("verify-qr", opts, bytes.NewReader(png))
+	pdf.ImageOptions("verify-qr", left, top, qrSizeMM, qrSizeMM, false, opts, 0, "")
+
+	pdf.SetXY(left+qrSizeMM+4, top+2)
+	pdf.SetFont("Helvetica", "", 7.5)
+	pdf.SetTextColor(80, 80, 80)
+	verifyLines := []string{
+		"Scan to verify this document.",
+		"Issued " + sig.IssuedAt.Format("02 Jan 2006 15:04 MST"),
+		"Document hash: " + sig.Hash[:16],
+	}
+	for _, line := range verifyLines {
+		pdf.SetX(left + qrSizeMM + 4)
+		pdf.CellFormat(0, 4, tr(line), "", 1, "L", false, 0, "")
+	}
+
+	pdf.SetTextColor(0, 0, 0)
+	sigW := width * 0.4
+	pdf.SetXY(left+width-sigW, top+qrSizeMM-10)
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(0.2)
+	pdf.Line(left+width-sigW, top+qrSizeMM-11, left+width, top+qrSizeMM-11)
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.CellFormat(sigW, 4.5, tr("Dr. "+strings.TrimPrefix(d.DoctorName(), "Dr. ")), "", 2, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.CellFormat(sigW, 4, tr("Reg. No. "+regNo), "", 2, "C", false, 0, "")
+
+	return pdf.Output(w)
+}
+
+func prescriptionName(p models.Prescription) string {
+	parts := []string{p.Drug}
+	for _, v := range []string{p.Strength, p.Form} {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// prescriptionDirections is the "sig" line, e.g. "1 tablet, oral, twice daily, for 5 days"
+func prescriptionDirections(p models.Prescription) string {
+	var parts []string
+	for _, v := range []string{p.Dose, p.Route, p.Frequency} {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	if p.DurationDays > 0 {
+		parts = append(parts, fmt.Sprintf("for %d days", p.DurationDays))
+	}
+	line := strings.Join(parts, ", ")
+	if p.Quantity > 0 {
+		line += fmt.Sprintf("  |  Qty: %d", p.Quantity)
+	}
+	if p.Refills > 0 {
+		line += fmt.Sprintf("  |  Refills: %d", p.Refills)
+	}
+	return line
+}
@@ -1,19 +1,66 @@
 package services
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
 )
 
-var jwtKey = []byte("your-secret-key")
+var ErrInvalidToken = errors.New("invalid token")
 
-func GenerateToken(userID uint, role string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"role":    role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+// GenerateAccessToken issues a short-lived JWT for one session
+func GenerateAccessToken(user models.User, sessionID uint) (string, time.Time, error) {
+	jti, err := randomToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	set, err := currentKeySet()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(config.AccessTokenTTL())
+	claims := models.Claims{
+		UserID:    user.ID,
+		Role:      user.Role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    config.JWTIssuer(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token := jwt.NewWithClaims(set.Signing.Method, claims)
+	token.Header["kid"] = set.Signing.ID
+	signed, err := token.SignedString(set.Signing.signKey())
+	return signed, expiresAt, err
+}
+
+// ParseAccessToken verifies the signature, issuer and expiry. The kid header
+// picks the key, so tokens signed before a rotation still verify while their
+// key is in the set. Revocation is checked separately, against the session.
+func ParseAccessToken(tokenString string) (*models.Claims, error) {
+	set, err := currentKeySet()
+	if err != nil {
+		return nil, err
+	}
+	claims := &models.Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := set.Keys[kid]
+		// The alg must match the key, or a public key could be used as an HMAC secret
+		if !ok || token.Method.Alg() != key.Method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return key.verifyKey(), nil
+	})
+	if err != nil || !token.Valid || !claims.VerifyIssuer(config.JWTIssuer(), true) {
+		return nil, ErrInvalidToken
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	return claims, nil
 }
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+const (
+	// Mistakes allowed before sign-ins are slowed down
+	freeLoginFailures = 2
+	maxFailureDelay   = time.Minute
+	// Idle counts are dropped after this long
+	loginAttemptRetention = 24 * time.Hour
+)
+
+var ErrLoginThrottled = errors.New("too many failed sign-in attempts")
+
+// LoginThrottledError says how long the caller must wait. Locked is set for
+// a lockout, as opposed to the short delay after each failure.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	wait := int(math.Ceil(e.RetryAfter.Seconds()))
+	if e.Locked {
+		return fmt.Sprintf("too many failed sign-in attempts; try again in %d minutes or ask an administrator to unlock the account", (wait+59)/60)
+	}
+	return fmt.Sprintf("too many failed sign-in attempts; try again in %s", time.Duration(wait)*time.Second)
+}
+
+func (e *LoginThrottledError) Is(target error) bool {
+	return target == ErrLoginThrottled
+}
+
+// LoginAttempts is the failure count for one account or client IP
+type LoginAttempts struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// LoginAttemptStore keeps failed sign-in counts. The memory store is enough
+// for a single instance; with several instances use the database store, or
+// plug in another shared one (e.g. Redis) with SetLoginAttemptStore.
+type LoginAttemptStore interface {
+	Get(key string) (LoginAttempts, error)
+	// RecordFailure adds a failure, starting the count over when the last
+	// one is older than window. Reaching lockAt locks the key until lockUntil.
+	RecordFailure(key string, now time.Time, window time.Duration, lockAt int, lockUntil time.Time) (LoginAttempts, error)
+	Clear(key string) error
+}
+
+var loginAttempts LoginAttemptStore = NewMemoryLoginAttemptStore()
+
+func SetLoginAttemptStore(store LoginAttemptStore) {
+	loginAttempts = store
+}
+
+// ConfigureLoginAttemptStore picks the store named by LOGIN_ATTEMPT_STORE
+func ConfigureLoginAttemptStore() error {
+	switch name := config.LoginAttemptStore(); name {
+	case "memory":
+		SetLoginAttemptStore(NewMemoryLoginAttemptStore())
+	case "database":
+		SetLoginAttemptStore(DatabaseLoginAttemptStore{})
+		return repository.PruneLoginAttempts(time.Now().Add(-loginAttemptRetention))
+	default:
+		return fmt.Errorf("unknown LOGIN_ATTEMPT_STORE %q (memory or database)", name)
+	}
+	return nil
+}
+
+// checkLoginAllowed fails while any key is locked or still waiting out the
+// delay after its last failure
+func checkLoginAllowed(keys ...string) error {
+	now := time.Now()
+	var throttled *LoginThrottledError
+	for _, key := range keys {
+		a, err := loginAttempts.Get(key)
+		if err != nil {
+			return err
+		}
+		wait, locked := a.LockedUntil.Sub(now), true
+		if wait <= 0 {
+			wait, locked = a.LastFailure.Add(failureDelay(a.Failures)).Sub(now), false
+		}
+		if wait > 0 && (throttled == nil || wait > throttled.RetryAfter) {
+			throttled = &LoginThrottledError{RetryAfter: wait, Locked: locked}
+		}
+	}
+	if throttled != nil {
+		return throttled
+	}
+	return nil
+}
+
+// failureDelay is nothing for the first mistakes, then doubles from one
+// second up to a minute
+func failureDelay(failures int) time.Duration {
+	if failures <= freeLoginFailures {
+		return 0
+	}
+	shift := failures - freeLoginFailures - 1
+	if shift >= 6 {
+		return maxFailureDelay
+	}
+	return time.Second << shift
+}
+
+// recordLoginFailure counts a wrong password against the account and the
+// client IP, and audits any lockout it causes. The returned error is a
+// LoginThrottledError when the caller is now locked out.
+func recordLoginFailure(email, clientIP string) error {
+	settings := config.LoginThrottleSettings()
+	now := time.Now()
+	lockUntil := now.Add(settings.Lockout)
+
+	checks := []struct {
+		scope, key string
+		lockAt     int
+	}{
+		{"account", accountKey(email), settings.MaxAccountFailures},
+		{"ip", ipKey(clientIP), settings.MaxIPFailures},
+	}
+	var lockedErr error
+	for _, c := range checks {
+		a, err := loginAttempts.RecordFailure(c.key, now, settings.Window, c.lockAt, lockUntil)
+		if err != nil {
+			return err
+		}
+		if a.Failures != c.lockAt {
+			if a.LockedUntil.After(now) {
+				lockedErr = &LoginThrottledError{RetryAfter: a.LockedUntil.Sub(now), Locked: true}
+			}
+			continue
+		}
+		// The failure that reached the limit; later ones while locked are
+		// not audited again
+		lockedErr = &LoginThrottledError{RetryAfter: settings.Lockout, Locked: true}
+		lockout := map[string]interface{}{
+			"scope":        c.scope,
+			"email":        normalizeEmail(email),
+			"ip":           clientIP,
+			"failures":     a.Failures,
+			"locked_until": lockUntil,
+		}
+		if err := RecordChange(AuditActor{ClientIP: clientIP}, models.AuditLoginLockout, nil, lockout); err != nil {
+			return err
+		}
+	}
+	return lockedErr
+}
+
+// UnlockAccount lifts a lockout and forgets the account's failures
+func UnlockAccount(email string) error {
+	return loginAttempts.Clear(accountKey(email))
+}
+
+// UnlockIP lifts a lockout on a client address
+func UnlockIP(ip string) error {
+	return loginAttempts.Clear(ipKey(ip))
+}
+
+func accountKey(email string) string {
+	return "account:" + normalizeEmail(email)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// MemoryLoginAttemptStore keeps counts in this process only
+type MemoryLoginAttemptStore struct {
+	mu        sync.Mutex
+	attempts  map[string]LoginAttempts
+	lastSweep time.Time
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{attempts: map[string]LoginAttempts{}}
+}
+
+func (s *MemoryLoginAttemptStore) Get(key string) (LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[key], nil
+}
+
+func (s *MemoryLoginAttemptStore) RecordFailure(key string, now time.Time, window time.Duration, lockAt int, lockUntil time.Time) (LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	a := s.attempts[key]
+	if now.Sub(a.LastFailure) > window {
+		a.Failures = 0
+	}
+	a.Failures++
+	a.LastFailure = now
+	if lockAt > 0 && a.Failures >= lockAt {
+		a.LockedUntil = lockUntil
+	}
+	s.attempts[key] = a
+	return a, nil
+}
+
+func (s *MemoryLoginAttemptStore) Clear(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+// sweep drops idle counts now and then so the map doesn't grow forever
+func (s *MemoryLoginAttemptStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, a := range s.attempts {
+		if now.Sub(a.LastFailure) > loginAttemptRetention && now.After(a.LockedUntil) {
+			delete(s.attempts, key)
+		}
+	}
+}
+
+// DatabaseLoginAttemptStore shares counts between instances through the
+// login_attempts table
+type DatabaseLoginAttemptStore struct{}
+
+func (DatabaseLoginAttemptStore) Get(key string) (LoginAttempts, error) {
+	a, err := repository.GetLoginAttempt(key)
+	return fromLoginAttempt(a), err
+}
+
+func (DatabaseLoginAttemptStore) RecordFailure(key string, now time.Time, window time.Duration, lockAt int, lockUntil time.Time) (LoginAttempts, error) {
+	a, err := repository.RecordLoginFailure(key, now, window, lockAt, lockUntil)
+	return fromLoginAttempt(a), err
+}
+
+func (DatabaseLoginAttemptStore) Clear(key string) error {
+	return repository.ClearLoginAttempts(key)
+}
+
+func fromLoginAttempt(a models.LoginAttempt) LoginAttempts {
+	out := LoginAttempts{Failures: a.Failures, LastFailure: a.LastFailureAt}
+	if a.LockedUntil != nil {
+		out.LockedUntil = *a.LockedUntil
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+// Columns accepted in a medication catalog CSV; only name is required
+var medicationCSVColumns = []string{"name", "generic_name", "strength", "form", "route", "code", "active"}
+
+// ParseMedicationCSV reads a catalog file with a header row. A later row for
+// the same name, strength and form replaces an earlier one.
+func ParseMedicationCSV(r io.Reader) ([]models.Medication, error) {
+	records, err := readCSV(r, medicationCSVColumns, "name")
+	if err != nil {
+		return nil, err
+	}
+
+	var meds []models.Medication
+	seen := map[string]int{}
+	for _, record := range records {
+		med := models.Medication{
+			Name:        record.get("name"),
+			GenericName: record.get("generic_name"),
+			Strength:    record.get("strength"),
+			Form:        strings.ToLower(record.get("form")),
+			Route:       strings.ToLower(record.get("route")),
+			Code:        record.get("code"),
+			Active:      true,
+		}
+		if med.Name == "" {
+			return nil, fmt.Errorf("line %d: name is required", record.Line)
+		}
+		if v := record.get("active"); v != "" {
+			active, err := parseCSVBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid active value %q", record.Line, v)
+			}
+			med.Active = active
+		}
+
+		key := strings.ToLower(med.Name + "\x00" + med.Strength + "\x00" + med.Form)
+		if i, dup := seen[key]; dup {
+			meds[i] = med
+			continue
+		}
+		seen[key] = len(meds)
+		meds = append(meds, med)
+	}
+	return meds, nil
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+// Interaction rules shipped with the app so checking works without a feed
+//
+//go:embed data/interactions.json
+var bundledInteractionRules []byte
+
+var interactionCSVColumns = []string{"drug_a", "drug_b", "severity", "description"}
+
+// Safety warning types
+const (
+	WarningAllergy     = "allergy"
+	WarningInteraction = "interaction"
+)
+
+type SafetyWarning struct {
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	// Drug being prescribed and the medication or allergy it conflicts with
+	Drug          string `json:"drug"`
+	ConflictsWith string `json:"conflicts_with"`
+	Message       string `json:"message"`
+	// Blocking warnings need an override reason to proceed
+	Blocking bool `json:"blocking"`
+}
+
+type SafetyCheck struct {
+	Warnings []SafetyWarning `json:"warnings"`
+	Blocked  bool            `json:"blocked"`
+}
+
+// drugCandidate is a drug under consideration with every name it may be
+// known by (brand, generic)
+type drugCandidate struct {
+	Label string
+	Names []string
+}
+
+// SeedInteractionRules loads the bundled rules, keeping any pair that
+// already exists.
+func SeedInteractionRules() error {
+	rules, err := ParseInteractionJSON(bytes.NewReader(bundledInteractionRules))
+	if err != nil {
+		return fmt.Errorf("bundled interaction rules: %w", err)
+	}
+	_, err = repository.ImportInteractionRules(rules, false)
+	return err
+}
+
+// ParseInteractionJSON reads a JSON array of rules
+func ParseInteractionJSON(r io.Reader) ([]models.InteractionRule, error) {
+	var rules []models.InteractionRule
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, err
+	}
+	return normalizeInteractionRules(rules, func(i int) string { return fmt.Sprintf("rule %d", i+1) })
+}
+
+// ParseInteractionCSV reads rules with a drug_a, drug_b, severity, description header
+func ParseInteractionCSV(r io.Reader) ([]models.InteractionRule, error) {
+	records, err := readCSV(r, interactionCSVColumns, "drug_a", "drug_b", "severity")
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]models.InteractionRule, 0, len(records))
+	for _, record := range records {
+		rules = append(rules, models.InteractionRule{
+			DrugA:       record.get("drug_a"),
+			DrugB:       record.get("drug_b"),
+			Severity:    record.get("severity"),
+			Description: record.get("description"),
+		})
+	}
+	return normalizeInteractionRules(rules, func(i int) string { return fmt.Sprintf("line %d", records[i].Line) })
+}
+
+// normalizeInteractionRules validates each rule and drops repeated pairs,
+// keeping the last one
+func normalizeInteractionRules(rules []models.InteractionRule, where func(int) string) ([]models.InteractionRule, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no interaction rules")
+	}
+	out := make([]models.InteractionRule, 0, len(rules))
+	seen := map[[2]string]int{}
+	for i, rule := range rules {
+		rule.ID = 0
+		rule.Normalize()
+		if rule.DrugA == "" || rule.DrugB == "" {
+			return nil, fmt.Errorf("%s: drug_a and drug_b are required", where(i))
+		}
+		if rule.DrugA == rule.DrugB {
+			return nil, fmt.Errorf("%s: a drug cannot interact with itself", where(i))
+		}
+		if !models.ValidInteractionSeverity(rule.Severity) {
+			return nil, fmt.Errorf("%s: severity must be minor, moderate, major or contraindicated", where(i))
+		}
+		key := [2]string{rule.DrugA, rule.DrugB}
+		if j, dup := seen[key]; dup {
+			out[j] = rule
+			continue
+		}
+		seen[key] = len(out)
+		out = append(out, rule)
+	}
+	return out, nil
+}
+
+// CheckPrescription checks a new prescription against the patient's
+// allergies and active medications
+func CheckPrescription(patientID uint, p models.Prescription) (SafetyCheck, error) {
+	candidate := drugCandidate{Label: p.Drug, Names: []string{p.Drug}}
+	if p.MedicationID != nil {
+		med, err := repository.GetMedicationByID(int(*p.MedicationID))
+		if err != nil {
+			return SafetyCheck{}, err
+		}
+		candidate = drugCandidate{Label: med.Name, Names: []string{med.Name, med.GenericName}}
+	}
+	return checkDrugs(patientID, []drugCandidate{candidate}, nil)
+}
+
+// CheckPrescriptionText checks free-text prescriptions. Only drugs the rules
+// or allergies know about can be recognised. Drugs already mentioned in
+// previous aren't flagged again on every edit, but the new ones are still
+// checked against them as current medications.
+func CheckPrescriptionText(patientID uint, text, previous string) (SafetyCheck, error) {
+	allergies, err := repository.GetPatientAllergies(int(patientID))
+	if err != nil {
+		return SafetyCheck{}, err
+	}
+	known, err := repository.InteractionDrugNames()
+	if err != nil {
+		return SafetyCheck{}, err
+	}
+	for _, a := range allergies {
+		known = append(known, models.NormalizeDrugName(a.Substance))
+	}
+
+	var candidates, current []drugCandidate
+	seen := map[string]bool{}
+	for _, name := range known {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		drug := drugCandidate{Label: name, Names: []string{name}}
+		switch {
+		case mentions(previous, name):
+			current = append(current, drug)
+		case mentions(text, name):
+			candidates = append(candidates, drug)
+		}
+	}
+	if len(candidates) == 0 {
+		return SafetyCheck{Warnings: []SafetyWarning{}}, nil
+	}
+	return checkDrugs(patientID, candidates, current)
+}
+
+// checkDrugs checks candidates against the patient's allergies, their active
+// prescriptions, current (medications known from elsewhere, e.g. an earlier
+// free-text prescription) and each other
+func checkDrugs(patientID uint, candidates, current []drugCandidate) (SafetyCheck, error) {
+	check := SafetyCheck{Warnings: []SafetyWarning{}}
+
+	allergies, err := repository.GetPatientAllergies(int(patientID))
+	if err != nil {
+		return check, err
+	}
+	active, err := repository.GetPatientPrescriptions(int(patientID), true)
+	if err != nil {
+		return check, err
+	}
+
+	for _, candidate := range candidates {
+		for _, allergy := range allergies {
+			if matchesAllergy(candidate.Names, allergy.Substance) {
+				check.add(SafetyWarning{
+					Type:          WarningAllergy,
+					Severity:      allergy.Severity,
+					Drug:          candidate.Label,
+					ConflictsWith: allergy.Substance,
+					Message:       allergyMessage(candidate.Label, allergy),
+					Blocking:      true,
+				})
+			}
+		}
+	}
+
+	// Existing medications plus the other new drugs, each with its names
+	others := make([]drugCandidate, 0, len(active)+len(current)+len(candidates))
+	for _, p := range active {
+		names := []string{p.Drug}
+		if p.Medication != nil {
+			names = append(names, p.Medication.GenericName)
+		}
+		others = append(others, drugCandidate{Label: p.Drug, Names: names})
+	}
+	others = append(others, current...)
+	existing := len(others)
+	others = append(others, candidates...)
+
+	nameSet := map[string]bool{}
+	for _, d := range others {
+		for _, n := range d.Names {
+			if n = models.NormalizeDrugName(n); n != "" {
+				nameSet[n] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(nameSet))
+	for n := range nameSet {
+		names = append(names, n)
+	}
+	rules, err := repository.FindInteractionRules(names)
+	if err != nil {
+		return check, err
+	}
+
+	reported := map[string]bool{}
+	for ci, candidate := range candidates {
+		for oi, other := range others {
+			// New drugs are also in others; compare each pair once
+			if oi >= existing && oi-existing <= ci {
+				continue
+			}
+			for _, rule := range rules {
+				if !pairMatches(rule, candidate.Names, other.Names) {
+					continue
+				}
+				key := fmt.Sprintf("%s|%s|%d", candidate.Label, other.Label, rule.ID)
+				if reported[key] {
+					continue
+				}
+				reported[key] = true
+				check.add(SafetyWarning{
+					Type:          WarningInteraction,
+					Severity:      rule.Severity,
+					Drug:          candidate.Label,
+					ConflictsWith: other.Label,
+					Message:       fmt.Sprintf("%s interacts with %s: %s", candidate.Label, other.Label, rule.Description),
+					Blocking:      rule.IsBlocking(),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(check.Warnings, func(i, j int) bool {
+		return check.Warnings[i].Blocking && !check.Warnings[j].Blocking
+	})
+	return check, nil
+}
+
+func (c *SafetyCheck) add(w SafetyWarning) {
+	c.Warnings = append(c.Warnings, w)
+	if w.Blocking {
+		c.Blocked = true
+	}
+}
+
+func allergyMessage(drug string, a models.PatientAllergy) string {
+	msg := fmt.Sprintf("Patient has a documented %s allergy", a.Substance)
+	if a.Reaction != "" {
+		msg += " (" + a.Reaction + ")"
+	}
+	return msg + "; " + drug + " may contain or cross-react with it"
+}
+
+// matchesAllergy is true when the substance names the drug or is part of its
+// name, e.g. "penicillin" and "benzathine penicillin"
+func matchesAllergy(names []string, substance string) bool {
+	substance = models.NormalizeDrugName(substance)
+	if substance == "" {
+		return false
+	}
+	for _, n := range names {
+		if n = models.NormalizeDrugName(n); n != "" && mentions(n, substance) {
+			return true
+		}
+	}
+	return false
+}
+
+func pairMatches(rule models.InteractionRule, a, b []string) bool {
+	return (hasName(a, rule.DrugA) && hasName(b, rule.DrugB)) ||
+		(hasName(a, rule.DrugB) && hasName(b, rule.DrugA))
+}
+
+func hasName(names []string, target string) bool {
+	for _, n := range names {
+		if models.NormalizeDrugName(n) == target {
+			return true
+		}
+	}
+	return false
+}
+
+// mentions reports whether name appears in text as whole words
+func mentions(text, name string) bool {
+	if text == "" || name == "" {
+		return false
+	}
+	pattern := `(?i)(^|[^\pL\pN])` + strings.ReplaceAll(regexp.QuoteMeta(name), " ", `\s+`) + `($|[^\pL\pN])`
+	matched, _ := regexp.MatchString(pattern, text)
+	return matched
+}
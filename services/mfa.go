@@ -0,0 +1,352 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+const (
+	// How long the user has to enter their code after the password
+	mfaChallengeTTL = 5 * time.Minute
+	// Wrong codes allowed per challenge before the password is asked again.
+	// Each one also counts as a failed sign-in towards the lockout.
+	maxMFAAttempts = 5
+	// Challenges one user can have open at once; logging in again retires
+	// the oldest
+	maxOpenMFAChallenges = 3
+	recoveryCodeCount    = 10
+)
+
+var (
+	ErrMFACodeInvalid     = errors.New("invalid authentication code")
+	ErrMFAAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled      = errors.New("two-factor authentication is not set up")
+	ErrMFARequiredForRole = errors.New("two-factor authentication is required for your role")
+)
+
+// MFAEnrollment is what the user needs to add the account to an authenticator app
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+	// PNG data URL of the URI, for scanning
+	QRCode string `json:"qr_code"`
+}
+
+// LoginChallenge stands in for the tokens when login needs a second factor
+type LoginChallenge struct {
+	Token     string    `json:"challenge_token"`
+	Enroll    bool      `json:"enrollment_required"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type MFAStatus struct {
+	Enabled           bool  `json:"enabled"`
+	Required          bool  `json:"required"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+// BeginLoginChallenge is called after a correct password. It returns nil when
+// the user can be signed in straight away: no authenticator, and none
+// required for their role.
+func BeginLoginChallenge(user models.User) (*LoginChallenge, error) {
+	enabled, err := mfaEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		required, err := repository.RoleMFARequired(user.Role)
+		if err != nil || !required {
+			return nil, err
+		}
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	ch := models.MFAChallenge{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		Enroll:    !enabled,
+		ExpiresAt: time.Now().Add(mfaChallengeTTL),
+	}
+	if err := repository.CreateMFAChallenge(&ch, maxOpenMFAChallenges); err != nil {
+		return nil, err
+	}
+	return &LoginChallenge{Token: token, Enroll: ch.Enroll, ExpiresAt: ch.ExpiresAt}, nil
+}
+
+// EnrollForChallenge starts authenticator setup for a user who must enroll
+// before their first sign-in completes
+func EnrollForChallenge(token string) (MFAEnrollment, error) {
+	ch, err := repository.GetMFAChallenge(hashToken(token), maxMFAAttempts)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	if !ch.Enroll {
+		return MFAEnrollment{}, ErrMFAAlreadyEnabled
+	}
+	user, err := repository.GetUserByID(ch.UserID)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	return BeginEnrollment(user)
+}
+
+// CompleteLoginChallenge checks the second factor and returns the user to
+// start a session for. Answering an enrollment challenge confirms the new
+// authenticator, and its recovery codes are returned. Wrong codes count
+// against the account and client IP like wrong passwords, so a new challenge
+// per login doesn't give unlimited guesses.
+func CompleteLoginChallenge(token, code, recoveryCode, clientIP string) (models.User, []string, error) {
+	ch, err := repository.GetMFAChallenge(hashToken(token), maxMFAAttempts)
+	if err != nil {
+		return models.User{}, nil, err
+	}
+	user, err := repository.GetUserByID(ch.UserID)
+	if err != nil || user.Disabled() {
+		return models.User{}, nil, ErrAccountDisabled
+	}
+	if err := checkLoginAllowed(accountKey(user.Email), ipKey(clientIP)); err != nil {
+		return models.User{}, nil, err
+	}
+	if err := repository.CountMFAAttempt(ch.ID, maxMFAAttempts); err != nil {
+		return models.User{}, nil, err
+	}
+
+	var recovery []string
+	if ch.Enroll {
+		recovery, err = ConfirmEnrollment(user.ID, code)
+	} else {
+		err = VerifySecondFactor(user.ID, code, recoveryCode)
+	}
+	if errors.Is(err, ErrMFACodeInvalid) {
+		if err := recordLoginFailure(user.Email, clientIP); err != nil {
+			return models.User{}, nil, err
+		}
+		return models.User{}, nil, ErrMFACodeInvalid
+	}
+	if err != nil {
+		return models.User{}, nil, err
+	}
+	if err := repository.UseMFAChallenge(ch.ID); err != nil {
+		return models.User{}, nil, err
+	}
+	return user, recovery, nil
+}
+
+// BeginEnrollment creates a new secret. It is not used for sign-in until
+// ConfirmEnrollment sees a valid code from it.
+func BeginEnrollment(user models.User) (MFAEnrollment, error) {
+	enabled, err := mfaEnabled(user.ID)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	if enabled {
+		return MFAEnrollment{}, ErrMFAAlreadyEnabled
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	if err := repository.SavePendingMFA(user.ID, secret); err != nil {
+		return MFAEnrollment{}, err
+	}
+
+	uri := totpURI(config.ClinicBranding().Name, user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	return MFAEnrollment{
+		Secret: secret,
+		URI:    uri,
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// ConfirmEnrollment turns MFA on and returns the recovery codes. They are
+// only shown here.
+func ConfirmEnrollment(userID uint, code string) ([]string, error) {
+	m, err := repository.GetUserMFA(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMFANotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	step, ok := matchTOTP(m.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrMFACodeInvalid
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.ConfirmMFA(userID, step, hashes); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifySecondFactor accepts a current authenticator code, or an unused
+// recovery code when one is given
+func VerifySecondFactor(userID uint, code, recoveryCode string) error {
+	m, err := repository.GetUserMFA(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !m.Enabled()) {
+		return ErrMFANotEnabled
+	}
+	if err != nil {
+		return err
+	}
+
+	if recoveryCode != "" {
+		ok, err := repository.UseRecoveryCode(userID, hashToken(normalizeRecoveryCode(recoveryCode)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrMFACodeInvalid
+		}
+		return nil
+	}
+
+	step, ok := matchTOTP(m.Secret, code, time.Now())
+	if !ok {
+		return ErrMFACodeInvalid
+	}
+	accepted, err := repository.AcceptTOTPStep(userID, step)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return ErrMFACodeInvalid
+	}
+	return nil
+}
+
+// DisableMFA lets a user remove their own authenticator, unless their role
+// requires one
+func DisableMFA(user models.User, code, recoveryCode string) error {
+	required, err := repository.RoleMFARequired(user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequiredForRole
+	}
+	if err := VerifySecondFactor(user.ID, code, recoveryCode); err != nil {
+		return err
+	}
+	return repository.DeleteUserMFA(user.ID)
+}
+
+// ResetMFA removes a user's authenticator, e.g. after a lost phone. If their
+// role requires MFA they enroll again at the next sign-in.
+func ResetMFA(userID uint) error {
+	return repository.DeleteUserMFA(userID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes; it takes an
+// authenticator code so a stolen session alone can't mint new ones
+func RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	if err := VerifySecondFactor(userID, code, ""); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	return codes, repository.ReplaceRecoveryCodes(userID, hashes)
+}
+
+func GetMFAStatus(user models.User) (MFAStatus, error) {
+	var status MFAStatus
+	var err error
+	if status.Enabled, err = mfaEnabled(user.ID); err != nil {
+		return status, err
+	}
+	if status.Required, err = repository.RoleMFARequired(user.Role); err != nil {
+		return status, err
+	}
+	if status.Enabled {
+		status.RecoveryCodesLeft, err = repository.CountRecoveryCodes(user.ID)
+	}
+	return status, err
+}
+
+// RoleMFAPolicies maps each role to whether it requires MFA
+func RoleMFAPolicies() (map[string]bool, error) {
+	rows, err := repository.GetRoleMFAPolicies()
+	if err != nil {
+		return nil, err
+	}
+	policies := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		policies[r.Role] = r.Required
+	}
+	return policies, nil
+}
+
+func SetRoleMFARequired(role string, required bool) (models.RoleMFAPolicy, error) {
+	if !models.ValidRole(role) {
+		return models.RoleMFAPolicy{}, ErrUnknownRole
+	}
+	return repository.SetRoleMFARequired(role, required)
+}
+
+// SeedRoleMFAPolicies gives new roles their default policy
+func SeedRoleMFAPolicies() error {
+	return repository.SeedRoleMFAPolicies(models.Roles, models.DefaultMFARoles)
+}
+
+// PruneMFAChallenges removes expired sign-in challenges
+func PruneMFAChallenges() error {
+	return repository.PruneMFAChallenges(time.Now().Add(-mfaChallengeTTL))
+}
+
+func mfaEnabled(userID uint) (bool, error) {
+	m, err := repository.GetUserMFA(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return m.Enabled(), err
+}
+
+// Recovery codes look like "k7qd-m2xa": 8 base32 characters, 40 random bits
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := recoveryEncoding.EncodeToString(b)
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+)
+
+// Notifier delivers account messages to staff. Email is built in; other
+// channels (SMS, a messaging app) can be plugged in with SetNotifier.
+type Notifier interface {
+	PasswordReset(user models.User, link string, expiresAt time.Time) error
+}
+
+var notifier Notifier = logNotifier{}
+
+func SetNotifier(n Notifier) {
+	notifier = n
+}
+
+// ConfigureNotifier sends email when SMTP_HOST is set and otherwise falls
+// back to the server log, which is only suitable for development
+func ConfigureNotifier() {
+	if settings := config.SMTPSettings(); settings.Host != "" {
+		SetNotifier(smtpNotifier{settings})
+		return
+	}
+	log.Println("⚠️ SMTP_HOST is not set; password reset links will be written to the server log")
+	SetNotifier(logNotifier{})
+}
+
+type logNotifier struct{}
+
+func (logNotifier) PasswordReset(user models.User, link string, expiresAt time.Time) error {
+	log.Printf("📧 Password reset for %s (valid until %s): %s", user.Email, expiresAt.Format(time.RFC3339), link)
+	return nil
+}
+
+type smtpNotifier struct {
+	settings config.SMTP
+}
+
+func (n smtpNotifier) PasswordReset(user models.User, link string, expiresAt time.Time) error {
+	clinic := config.ClinicBranding().Name
+	body := fmt.Sprintf("Hello %s,\r\n\r\n"+
+		"An administrator at %s has started a password reset for your account.\r\n"+
+		"Choose a new password here:\r\n\r\n%s\r\n\r\n"+
+		"The link works once and expires at %s.\r\n"+
+		"If you did not expect this, contact your administrator.\r\n",
+		user.Name, clinic, link, expiresAt.Format("02 Jan 2006 15:04 MST"))
+	return n.send(user.Email, clinic+": reset your password", body)
+}
+
+func (n smtpNotifier) send(to, subject, body string) error {
+	s := n.settings
+	from := s.From
+	if from == "" {
+		from = s.Username
+	}
+	msg := strings.Join([]string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	return smtp.SendMail(addr, auth, from, []string{to}, []byte(msg))
+}
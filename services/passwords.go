@@ -0,0 +1,244 @@
+package services
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+//go:embed data/breached_passwords.txt
+var bundledBreachedPasswords string
+
+// bcrypt ignores everything past 72 bytes
+const maxPasswordBytes = 72
+
+var (
+	ErrPasswordPolicy     = errors.New("password does not meet the password policy")
+	ErrWrongPassword      = errors.New("current password is incorrect")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+)
+
+// passwordPolicyError is the reason a password was rejected, worded for
+// the user. It matches ErrPasswordPolicy.
+type passwordPolicyError string
+
+func (e passwordPolicyError) Error() string { return string(e) }
+
+func (e passwordPolicyError) Is(target error) bool { return target == ErrPasswordPolicy }
+
+var breachedPasswords struct {
+	once sync.Once
+	set  map[string]bool
+}
+
+// checkPasswordPolicy tests a new password against length and the breach
+// lists. History is checked separately since it needs the account.
+func checkPasswordPolicy(password string) error {
+	policy := config.PasswordPolicySettings()
+	if len([]rune(password)) < policy.MinLength {
+		return passwordPolicyError(fmt.Sprintf("password must be at least %d characters", policy.MinLength))
+	}
+	if len(password) > maxPasswordBytes {
+		return passwordPolicyError(fmt.Sprintf("password must be at most %d bytes", maxPasswordBytes))
+	}
+	if policy.BreachCheck && isBreachedPassword(password) {
+		return passwordPolicyError("this password appears in known data breaches; choose another")
+	}
+	return nil
+}
+
+// checkPasswordHistory rejects the current password and the ones before it,
+// as far back as PASSWORD_HISTORY goes
+func checkPasswordHistory(user models.User, password string) error {
+	limit := config.PasswordPolicySettings().History
+	if limit == 0 {
+		return nil
+	}
+	previous, err := repository.GetPasswordHistory(user.ID, limit-1)
+	if err != nil {
+		return err
+	}
+	for _, hash := range append([]string{user.Password}, previous...) {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return passwordPolicyError(fmt.Sprintf("password must differ from your last %d passwords", limit))
+		}
+	}
+	return nil
+}
+
+func isBreachedPassword(password string) bool {
+	breachedPasswords.once.Do(loadBreachedPasswords)
+	return breachedPasswords.set[strings.ToLower(password)]
+}
+
+func loadBreachedPasswords() {
+	set := map[string]bool{}
+	readPasswordList(strings.NewReader(bundledBreachedPasswords), set)
+	if path := config.PasswordPolicySettings().BreachListFile; path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Println("password policy: can't read PASSWORD_BREACH_LIST_FILE, using the bundled list only:", err)
+		} else {
+			readPasswordList(f, set)
+			f.Close()
+		}
+	}
+	breachedPasswords.set = set
+}
+
+func readPasswordList(r io.Reader, set map[string]bool) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[strings.ToLower(line)] = true
+	}
+}
+
+// hashPassword checks the policy and hashes a password for a new account
+func hashPassword(password string) (string, error) {
+	if err := checkPasswordPolicy(password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// setPassword applies the full policy, including history, and stores the
+// new password. It signs the user out of every session but keepSessionID.
+func setPassword(user models.User, password string, mustChange bool, keepSessionID uint) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := checkPasswordHistory(user, password); err != nil {
+		return err
+	}
+	return repository.ChangePassword(user.ID, hash, config.PasswordPolicySettings().History, mustChange, keepSessionID)
+}
+
+// ChangePassword is the self-service change. A wrong current password counts
+// as a failed sign-in, so a stolen session can't be used to guess it. Only
+// sessionID, the caller's own session, stays signed in.
+func ChangePassword(user models.User, current, next, clientIP string, sessionID uint) error {
+	if err := checkLoginAllowed(accountKey(user.Email), ipKey(clientIP)); err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(current)) != nil {
+		if err := recordLoginFailure(user.Email, clientIP); err != nil {
+			return err
+		}
+		return ErrWrongPassword
+	}
+	return setPassword(user, next, false, sessionID)
+}
+
+// ResetPassword sets a temporary password chosen by an admin and signs the
+// user out everywhere. The user must replace it at their next sign-in.
+func ResetPassword(userID uint, password string) error {
+	user, err := repository.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := setPassword(user, password, true, 0); err != nil {
+		return err
+	}
+	return UnlockAccount(user.Email)
+}
+
+// RequestPasswordReset sends the user a single-use link to choose a new
+// password. The link itself is only ever given to the notifier.
+func RequestPasswordReset(userID, requestedBy uint) (models.PasswordResetToken, error) {
+	user, err := repository.GetUserByID(userID)
+	if err != nil {
+		return models.PasswordResetToken{}, err
+	}
+	if user.Disabled() {
+		return models.PasswordResetToken{}, ErrAccountDisabled
+	}
+	token, err := randomToken()
+	if err != nil {
+		return models.PasswordResetToken{}, err
+	}
+	reset := models.PasswordResetToken{
+		UserID:        user.ID,
+		TokenHash:     hashToken(token),
+		RequestedByID: requestedBy,
+		ExpiresAt:     time.Now().Add(config.PasswordResetTTL()),
+	}
+	if err := repository.CreatePasswordResetToken(&reset); err != nil {
+		return models.PasswordResetToken{}, err
+	}
+	link := config.AppBaseURL() + "/reset-password?token=" + token
+	if err := notifier.PasswordReset(user, link, reset.ExpiresAt); err != nil {
+		return models.PasswordResetToken{}, fmt.Errorf("sending password reset: %w", err)
+	}
+	return reset, nil
+}
+
+// CompletePasswordReset sets the password chosen through a reset link and
+// signs the user out everywhere. It also lifts any lockout, since the link
+// proves access to the account.
+func CompletePasswordReset(token, password string) (models.User, error) {
+	reset, err := repository.GetPasswordResetToken(hashToken(token))
+	if err != nil {
+		return models.User{}, err
+	}
+	user, err := repository.GetUserByID(reset.UserID)
+	if err != nil || user.Disabled() {
+		return models.User{}, repository.ErrResetTokenInvalid
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return models.User{}, err
+	}
+	if err := checkPasswordHistory(user, password); err != nil {
+		return models.User{}, err
+	}
+	if err := repository.RedeemPasswordReset(reset.ID, user.ID, hash, config.PasswordPolicySettings().History); err != nil {
+		return models.User{}, err
+	}
+	if err := UnlockAccount(user.Email); err != nil {
+		log.Println("failed to clear lockout after password reset:", err)
+	}
+	return user, nil
+}
+
+// PrunePasswordResetTokens removes reset links that expired a day ago
+func PrunePasswordResetTokens() error {
+	return repository.PrunePasswordResetTokens(time.Now().Add(-24 * time.Hour))
+}
+
+// PasswordRules is the policy as shown to users choosing a password
+type PasswordRules struct {
+	MinLength   int  `json:"min_length"`
+	MaxBytes    int  `json:"max_bytes"`
+	History     int  `json:"history"`
+	BreachCheck bool `json:"breach_check"`
+}
+
+func GetPasswordRules() PasswordRules {
+	policy := config.PasswordPolicySettings()
+	return PasswordRules{
+		MinLength:   policy.MinLength,
+		MaxBytes:    maxPasswordBytes,
+		History:     policy.History,
+		BreachCheck: policy.BreachCheck,
+	}
+}
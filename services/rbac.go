@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+// How long the permission matrix is cached. Edits made through this
+// instance apply at once; other instances pick them up within this window.
+const permissionCacheTTL = 30 * time.Second
+
+var (
+	ErrUnknownRole       = errors.New("unknown role")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrAdminLockout      = errors.New("the admin role must keep " + models.PermRoleManage)
+)
+
+var permissionCache struct {
+	sync.RWMutex
+	matrix   map[string]map[string]bool
+	loadedAt time.Time
+}
+
+func SeedRolePermissions() error {
+	if err := repository.SeedRolePermissions(models.DefaultRolePermissions, models.PermissionChanges); err != nil {
+		return err
+	}
+	return ReloadPermissions()
+}
+
+// ReloadPermissions refreshes the cached matrix from the database
+func ReloadPermissions() error {
+	rows, err := repository.GetRolePermissions()
+	if err != nil {
+		return err
+	}
+	matrix := make(map[string]map[string]bool, len(rows))
+	for role, permissions := range rows {
+		set := make(map[string]bool, len(permissions))
+		for _, p := range permissions {
+			set[p] = true
+		}
+		matrix[role] = set
+	}
+
+	permissionCache.Lock()
+	permissionCache.matrix = matrix
+	permissionCache.loadedAt = time.Now()
+	permissionCache.Unlock()
+	return nil
+}
+
+// HasPermission reports whether role is granted permission
+func HasPermission(role, permission string) (bool, error) {
+	permissionCache.RLock()
+	stale := permissionCache.matrix == nil || time.Since(permissionCache.loadedAt) > permissionCacheTTL
+	permissionCache.RUnlock()
+	if stale {
+		if err := ReloadPermissions(); err != nil {
+			return false, err
+		}
+	}
+
+	permissionCache.RLock()
+	defer permissionCache.RUnlock()
+	return permissionCache.matrix[role][permission], nil
+}
+
+// RolePermissions lists every role with its permissions, sorted
+func RolePermissions() (map[string][]string, error) {
+	matrix, err := repository.GetRolePermissions()
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range models.Roles {
+		if matrix[role] == nil {
+			matrix[role] = []string{}
+		}
+	}
+	return matrix, nil
+}
+
+// SetRolePermissions validates and saves the permissions for one role
+func SetRolePermissions(role string, permissions []string) ([]string, error) {
+	if !models.ValidRole(role) {
+		return nil, fmt.Errorf("%w %q", ErrUnknownRole, role)
+	}
+	seen := map[string]bool{}
+	var cleaned []string
+	for _, p := range permissions {
+		if !models.ValidPermission(p) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownPermission, p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			cleaned = append(cleaned, p)
+		}
+	}
+	if role == models.RoleAdmin && !seen[models.PermRoleManage] {
+		return nil, ErrAdminLockout
+	}
+	sort.Strings(cleaned)
+
+	if err := repository.SetRolePermissions(role, cleaned); err != nil {
+		return nil, err
+	}
+	if cleaned == nil {
+		cleaned = []string{}
+	}
+	return cleaned, ReloadPermissions()
+}
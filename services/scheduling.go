@@ -0,0 +1,246 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+// How far ahead slot search looks before giving up
+const slotSearchHorizonDays = 60
+
+const defaultSlotMinutes = 15
+
+type Slot struct {
+	DoctorID   uint      `json:"doctor_id"`
+	DoctorName string    `json:"doctor_name"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+}
+
+// doctorCalendar is everything needed to decide whether a doctor is free
+type doctorCalendar struct {
+	doctor models.User
+	hours  []models.DoctorAvailability
+	breaks []models.DoctorBreak
+	leaves []models.DoctorLeave
+	booked []models.Appointment
+}
+
+// ParseClock converts "15:04" into minutes since midnight
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ValidateSchedule checks a weekly template before it is stored
+func ValidateSchedule(hours []models.DoctorAvailability, breaks []models.DoctorBreak) error {
+	for _, h := range hours {
+		if h.Weekday < 0 || h.Weekday > 6 {
+			return errors.New("weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		start, err := ParseClock(h.StartTime)
+		if err != nil {
+			return err
+		}
+		end, err := ParseClock(h.EndTime)
+		if err != nil {
+			return err
+		}
+		if end <= start {
+			return fmt.Errorf("working hours %s-%s end before they start", h.StartTime, h.EndTime)
+		}
+		if h.SlotMinutes < 0 || h.SlotMinutes > end-start {
+			return errors.New("slot_minutes must fit inside the working hours")
+		}
+	}
+	for _, b := range breaks {
+		if b.Weekday < 0 || b.Weekday > 6 {
+			return errors.New("weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		start, err := ParseClock(b.StartTime)
+		if err != nil {
+			return err
+		}
+		end, err := ParseClock(b.EndTime)
+		if err != nil {
+			return err
+		}
+		if end <= start {
+			return fmt.Errorf("break %s-%s ends before it starts", b.StartTime, b.EndTime)
+		}
+	}
+	return nil
+}
+
+func loadDoctorCalendar(doctor models.User, from, to time.Time) (doctorCalendar, error) {
+	cal := doctorCalendar{doctor: doctor}
+	var err error
+	if cal.hours, cal.breaks, err = repository.GetDoctorSchedule(doctor.ID); err != nil {
+		return cal, err
+	}
+	if cal.leaves, err = repository.GetDoctorLeaves(doctor.ID, from); err != nil {
+		return cal, err
+	}
+	if cal.booked, err = repository.GetDoctorBusyAppointments(doctor.ID, from, to); err != nil {
+		return cal, err
+	}
+	return cal, nil
+}
+
+// at returns the instant on day's date at the given minutes after midnight
+func at(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+}
+
+// isFree reports whether [start, end) is inside working hours and clear of
+// breaks, leave and existing appointments. A doctor without a template is
+// treated as always available.
+func (cal doctorCalendar) isFree(start, end time.Time) bool {
+	for _, l := range cal.leaves {
+		if l.Covers(start) || l.Covers(end.Add(-time.Nanosecond)) {
+			return false
+		}
+	}
+
+	if len(cal.hours) > 0 {
+		inHours := false
+		for _, h := range cal.hours {
+			if time.Weekday(h.Weekday) != start.Weekday() {
+				continue
+			}
+			hs, _ := ParseClock(h.StartTime)
+			he, _ := ParseClock(h.EndTime)
+			if !start.Before(at(start, hs)) && !end.After(at(start, he)) {
+				inHours = true
+				break
+			}
+		}
+		if !inHours {
+			return false
+		}
+	}
+
+	for _, b := range cal.breaks {
+		if time.Weekday(b.Weekday) != start.Weekday() {
+			continue
+		}
+		bs, _ := ParseClock(b.StartTime)
+		be, _ := ParseClock(b.EndTime)
+		if start.Before(at(start, be)) && end.After(at(start, bs)) {
+			return false
+		}
+	}
+
+	for _, a := range cal.booked {
+		if start.Before(a.EndTime) && end.After(a.StartTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextSlots walks the template day by day collecting up to count free slots
+func (cal doctorCalendar) nextSlots(from time.Time, count int, duration time.Duration) []Slot {
+	var slots []Slot
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+
+	for d := 0; d < slotSearchHorizonDays && len(slots) < count; d++ {
+		current := day.AddDate(0, 0, d)
+		var windows []models.DoctorAvailability
+		for _, h := range cal.hours {
+			if time.Weekday(h.Weekday) == current.Weekday() {
+				windows = append(windows, h)
+			}
+		}
+		sort.Slice(windows, func(i, j int) bool { return windows[i].StartTime < windows[j].StartTime })
+
+		for _, w := range windows {
+			ws, _ := ParseClock(w.StartTime)
+			we, _ := ParseClock(w.EndTime)
+			step := w.SlotMinutes
+			if step <= 0 {
+				step = defaultSlotMinutes
+			}
+			length := duration
+			if length <= 0 {
+				length = time.Duration(step) * time.Minute
+			}
+
+			for m := ws; len(slots) < count; m += step {
+				start := at(current, m)
+				end := start.Add(length)
+				if end.After(at(current, we)) {
+					break
+				}
+				if start.Before(from) || !cal.isFree(start, end) {
+					continue
+				}
+				slots = append(slots, Slot{
+					DoctorID:   cal.doctor.ID,
+					DoctorName: cal.doctor.Name,
+					StartTime:  start,
+					EndTime:    end,
+				})
+			}
+		}
+	}
+	return slots
+}
+
+// FindFreeSlots returns the next count free slots for one doctor, or across
+// all doctors when doctorID is 0. Doctors without a weekly template are
+// skipped since there is nothing to search.
+func FindFreeSlots(doctorID uint, from time.Time, count int, duration time.Duration) ([]Slot, error) {
+	var doctors []models.User
+	if doctorID != 0 {
+		doctor, err := repository.GetDoctorByID(doctorID)
+		if err != nil {
+			return nil, err
+		}
+		doctors = append(doctors, doctor)
+	} else {
+		var err error
+		if doctors, err = repository.GetDoctors(); err != nil {
+			return nil, err
+		}
+	}
+
+	to := from.AddDate(0, 0, slotSearchHorizonDays+1)
+	var slots []Slot
+	for _, doctor := range doctors {
+		cal, err := loadDoctorCalendar(doctor, from, to)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, cal.nextSlots(from, count, duration)...)
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].StartTime.Before(slots[j].StartTime) })
+	if len(slots) > count {
+		slots = slots[:count]
+	}
+	return slots, nil
+}
+
+// IsDoctorAvailable checks a proposed appointment against the doctor's
+// template, breaks and leave. Existing bookings are checked separately by
+// repository.CreateAppointment under a lock.
+func IsDoctorAvailable(doctor models.User, start, end time.Time) (bool, error) {
+	// Templates are wall-clock times in the clinic's zone, as for
+	// FindFreeSlots; a request made in UTC must not shift the day or hour
+	start, end = start.In(time.Local), end.In(time.Local)
+	cal, err := loadDoctorCalendar(doctor, start, end)
+	if err != nil {
+		return false, err
+	}
+	cal.booked = nil
+	return cal.isFree(start, end), nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+// Ended sessions are kept this long for the sessions list and audits
+const sessionRetention = 30 * 24 * time.Hour
+
+var ErrSessionRevoked = errors.New("session has been revoked")
+
+// TokenPair is what login and refresh return
+type TokenPair struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// StartSession signs the user in on a new device
+func StartSession(user models.User, userAgent, clientIP string) (TokenPair, error) {
+	// The sign-in is complete, so earlier mistakes no longer count
+	if err := UnlockAccount(user.Email); err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := randomToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		ClientIP:   clientIP,
+		ExpiresAt:  now.Add(config.SessionTTL()),
+		LastUsedAt: now,
+	}
+	if err := repository.CreateSession(&session, hashToken(refresh)); err != nil {
+		return TokenPair{}, err
+	}
+	return issueTokens(user, session.ID, refresh)
+}
+
+// RefreshSession swaps a refresh token for a new access and refresh token
+func RefreshSession(refreshToken string) (TokenPair, models.User, error) {
+	next, err := randomToken()
+	if err != nil {
+		return TokenPair{}, models.User{}, err
+	}
+	session, err := repository.RotateRefreshToken(hashToken(refreshToken), hashToken(next))
+	if err != nil {
+		return TokenPair{}, models.User{}, err
+	}
+	// A deleted or disabled user ends the session; a failed lookup does not
+	user, err := repository.GetUserByID(session.UserID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return TokenPair{}, models.User{}, err
+	}
+	if err != nil || user.Disabled() {
+		if err := repository.RevokeSession(session.ID, 0, models.SessionAdminRevoked); err != nil {
+			return TokenPair{}, models.User{}, err
+		}
+		return TokenPair{}, models.User{}, ErrAccountDisabled
+	}
+	pair, err := issueTokens(user, session.ID, next)
+	return pair, user, err
+}
+
+// EndSessionByRefreshToken logs out the device holding the refresh token
+func EndSessionByRefreshToken(refreshToken string) (models.Session, error) {
+	session, err := repository.SessionForRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		return session, err
+	}
+	return session, repository.RevokeSession(session.ID, 0, models.SessionLogout)
+}
+
+// CheckSession is the revocation check run for every authenticated request
+func CheckSession(sessionID, userID uint) error {
+	session, err := repository.GetSession(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && session.UserID != userID) {
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if !session.Active(time.Now()) {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// PruneSessions removes sessions that ended more than a month ago
+func PruneSessions() error {
+	return repository.PruneSessions(time.Now().Add(-sessionRetention))
+}
+
+func issueTokens(user models.User, sessionID uint, refresh string) (TokenPair, error) {
+	access, expiresAt, err := GenerateAccessToken(user, sessionID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresAt: expiresAt}, nil
+}
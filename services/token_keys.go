@@ -0,0 +1,285 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+)
+
+// HS256 secrets shorter than this can be brute-forced from a single token
+const minHMACSecretBytes = 32
+
+// SigningKey is one key in the key set. Only the key named by signing_key
+// signs new tokens; the rest only verify, so tokens issued before a
+// rotation stay valid until they expire.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+	secret  []byte
+}
+
+func (k SigningKey) signKey() interface{} {
+	if k.secret != nil {
+		return k.secret
+	}
+	return k.private
+}
+
+func (k SigningKey) verifyKey() interface{} {
+	if k.secret != nil {
+		return k.secret
+	}
+	return k.public
+}
+
+type KeySet struct {
+	Signing *SigningKey
+	Keys    map[string]*SigningKey
+}
+
+// keySetFile is the JSON layout of JWT_KEYS_FILE. Paths are relative to the
+// file; secrets are read from the named environment variable.
+//
+//	{
+//	  "signing_key": "2026-10",
+//	  "keys": [
+//	    {"kid": "2026-10", "alg": "EdDSA", "private_key_file": "jwt-2026-10.pem"},
+//	    {"kid": "2026-04", "alg": "RS256", "public_key_file": "jwt-2026-04.pub.pem"},
+//	    {"kid": "default", "alg": "HS256", "secret_env": "JWT_SECRET"}
+//	  ]
+//	}
+type keySetFile struct {
+	SigningKey string `json:"signing_key"`
+	Keys       []struct {
+		ID             string `json:"kid"`
+		Alg            string `json:"alg"`
+		PrivateKeyFile string `json:"private_key_file"`
+		PublicKeyFile  string `json:"public_key_file"`
+		SecretEnv      string `json:"secret_env"`
+	} `json:"keys"`
+}
+
+var tokenKeys struct {
+	sync.RWMutex
+	set *KeySet
+}
+
+var ErrKeysNotLoaded = errors.New("JWT signing keys are not loaded")
+
+// LoadTokenKeys reads the key set from configuration. It runs at startup and
+// can be called again to pick up a rotated key file.
+func LoadTokenKeys() error {
+	var set *KeySet
+	var err error
+	if path := config.JWTKeysFile(); path != "" {
+		set, err = loadKeySetFile(path)
+	} else {
+		set, err = secretKeySet(config.JWTKeyID(), config.JWTSecret())
+	}
+	if err != nil {
+		return err
+	}
+
+	tokenKeys.Lock()
+	tokenKeys.set = set
+	tokenKeys.Unlock()
+	return nil
+}
+
+func currentKeySet() (*KeySet, error) {
+	tokenKeys.RLock()
+	defer tokenKeys.RUnlock()
+	if tokenKeys.set == nil {
+		return nil, ErrKeysNotLoaded
+	}
+	return tokenKeys.set, nil
+}
+
+func secretKeySet(kid string, secret []byte) (*KeySet, error) {
+	key, err := hmacKey(kid, secret)
+	if err != nil {
+		return nil, err
+	}
+	return &KeySet{Signing: key, Keys: map[string]*SigningKey{kid: key}}, nil
+}
+
+func hmacKey(kid string, secret []byte) (*SigningKey, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("JWT_SECRET or JWT_KEYS_FILE must be set")
+	}
+	if len(secret) < minHMACSecretBytes {
+		log.Printf("⚠️ JWT key %q: HS256 secret is shorter than %d bytes; use a long random value in production", kid, minHMACSecretBytes)
+	}
+	return &SigningKey{ID: kid, Method: jwt.SigningMethodHS256, secret: secret}, nil
+}
+
+func loadKeySetFile(path string) (*KeySet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file keySetFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	set := &KeySet{Keys: map[string]*SigningKey{}}
+	for i, k := range file.Keys {
+		if k.ID == "" {
+			return nil, fmt.Errorf("%s: key %d has no kid", path, i+1)
+		}
+		if _, dup := set.Keys[k.ID]; dup {
+			return nil, fmt.Errorf("%s: duplicate kid %q", path, k.ID)
+		}
+
+		var key *SigningKey
+		switch k.Alg {
+		case "HS256":
+			if k.SecretEnv == "" {
+				return nil, fmt.Errorf("%s: key %q: HS256 keys need secret_env", path, k.ID)
+			}
+			key, err = hmacKey(k.ID, []byte(os.Getenv(k.SecretEnv)))
+		case "RS256", "EdDSA":
+			key, err = loadAsymmetricKey(k.ID, k.Alg, resolve, k.PrivateKeyFile, k.PublicKeyFile)
+		default:
+			err = fmt.Errorf("key %q: unsupported alg %q (HS256, RS256 or EdDSA)", k.ID, k.Alg)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		set.Keys[k.ID] = key
+	}
+
+	signing, ok := set.Keys[file.SigningKey]
+	if !ok {
+		return nil, fmt.Errorf("%s: signing_key %q is not in keys", path, file.SigningKey)
+	}
+	if signing.signKey() == nil {
+		return nil, fmt.Errorf("%s: signing key %q has no private key", path, signing.ID)
+	}
+	set.Signing = signing
+	return set, nil
+}
+
+func loadAsymmetricKey(kid, alg string, resolve func(string) string, privateFile, publicFile string) (*SigningKey, error) {
+	if privateFile == "" && publicFile == "" {
+		return nil, fmt.Errorf("key %q: private_key_file or public_key_file is required", kid)
+	}
+	key := &SigningKey{ID: kid}
+	if privateFile != "" {
+		pem, err := os.ReadFile(resolve(privateFile))
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+		switch alg {
+		case "RS256":
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", kid, err)
+			}
+			key.private, key.public = priv, &priv.PublicKey
+		case "EdDSA":
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", kid, err)
+			}
+			key.private, key.public = priv, priv.(ed25519.PrivateKey).Public()
+		}
+	} else {
+		pem, err := os.ReadFile(resolve(publicFile))
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+		switch alg {
+		case "RS256":
+			key.public, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		case "EdDSA":
+			key.public, err = jwt.ParseEdPublicKeyFromPEM(pem)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+	}
+
+	switch alg {
+	case "RS256":
+		key.Method = jwt.SigningMethodRS256
+		if key.public.(*rsa.PublicKey).N.BitLen() < 2048 {
+			return nil, fmt.Errorf("key %q: RSA keys must be at least 2048 bits", kid)
+		}
+	case "EdDSA":
+		key.Method = jwt.SigningMethodEdDSA
+	}
+	return key, nil
+}
+
+// JWK is one public key in JWKS format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// PublicJWKS lists the public half of every asymmetric key so other services
+// can verify our tokens. HS256 secrets are never published.
+func PublicJWKS() ([]JWK, error) {
+	set, err := currentKeySet()
+	if err != nil {
+		return nil, err
+	}
+	keys := []JWK{}
+	for _, k := range set.Keys {
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{
+				KeyType:   "RSA",
+				Use:       "sig",
+				Algorithm: k.Method.Alg(),
+				KeyID:     k.ID,
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{
+				KeyType:   "OKP",
+				Use:       "sig",
+				Algorithm: k.Method.Alg(),
+				KeyID:     k.ID,
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].KeyID < keys[j].KeyID })
+	return keys, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Sathwik-145/hospital-portal/config"
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+var ErrAccountDisabled = errors.New("account is disabled")
+
+// NewUser is what an admin, an invite or the bootstrap command provides
+type NewUser struct {
+	Name               string
+	Email              string
+	Password           string
+	Role               string
+	RegistrationNumber string
+	// Set when an admin picked the password, so the user replaces it
+	MustChangePassword bool
+}
+
+// CreateUser hashes the password and stores a new account
+func CreateUser(in NewUser) (models.User, error) {
+	if !models.ValidRole(in.Role) {
+		return models.User{}, ErrUnknownRole
+	}
+	hash, err := hashPassword(in.Password)
+	if err != nil {
+		return models.User{}, err
+	}
+	user := models.User{
+		Name:               strings.TrimSpace(in.Name),
+		Email:              normalizeEmail(in.Email),
+		Password:           hash,
+		Role:               in.Role,
+		RegistrationNumber: strings.TrimSpace(in.RegistrationNumber),
+		MustChangePassword: in.MustChangePassword,
+	}
+	if err := repository.CreateUser(&user); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// CreateInvite issues a single-use registration token. The token is only
+// returned here; the database keeps its hash.
+func CreateInvite(email, name, role, registrationNumber string, invitedBy uint) (models.UserInvite, string, error) {
+	if !models.ValidRole(role) {
+		return models.UserInvite{}, "", ErrUnknownRole
+	}
+	token, err := randomToken()
+	if err != nil {
+		return models.UserInvite{}, "", err
+	}
+	invite := models.UserInvite{
+		TokenHash:          hashToken(token),
+		Email:              normalizeEmail(email),
+		Name:               strings.TrimSpace(name),
+		Role:               role,
+		RegistrationNumber: strings.TrimSpace(registrationNumber),
+		InvitedByID:        invitedBy,
+		ExpiresAt:          time.Now().Add(config.InviteTTL()),
+	}
+	if err := repository.CreateInvite(&invite); err != nil {
+		return models.UserInvite{}, "", err
+	}
+	return invite, token, nil
+}
+
+// RedeemInvite registers the invited person with the role the admin chose
+func RedeemInvite(token, name, password, registrationNumber string) (models.User, error) {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return models.User{}, err
+	}
+	user := models.User{
+		Name:               strings.TrimSpace(name),
+		Password:           hash,
+		RegistrationNumber: strings.TrimSpace(registrationNumber),
+	}
+	if _, err := repository.RedeemInvite(hashToken(token), &user); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
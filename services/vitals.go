@@ -0,0 +1,177 @@
+package services
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Sathwik-145/hospital-portal/models"
+	"github.com/Sathwik-145/hospital-portal/repository"
+)
+
+// Default adult and paediatric reference ranges, installed on first start
+//
+//go:embed data/vital_ranges.json
+var bundledVitalRanges []byte
+
+// SeriesPoint is one reading of one measure
+type SeriesPoint struct {
+	RecordedAt time.Time `json:"recorded_at"`
+	Value      float64   `json:"value"`
+	VitalsID   uint      `json:"vitals_id"`
+	// Flag status, empty when in range
+	Status string `json:"status,omitempty"`
+}
+
+// MeasureSeries is a chartable series with the patient's current normal band
+type MeasureSeries struct {
+	Measure string        `json:"measure"`
+	Unit    string        `json:"unit"`
+	Low     *float64      `json:"low"`
+	High    *float64      `json:"high"`
+	Points  []SeriesPoint `json:"points"`
+}
+
+func SeedVitalRanges() error {
+	ranges, err := ParseReferenceRanges(bytes.NewReader(bundledVitalRanges))
+	if err != nil {
+		return fmt.Errorf("bundled vital ranges: %w", err)
+	}
+	return repository.SeedReferenceRanges(ranges)
+}
+
+// ParseReferenceRanges reads and validates a JSON array of ranges
+func ParseReferenceRanges(r io.Reader) ([]models.VitalReferenceRange, error) {
+	var ranges []models.VitalReferenceRange
+	if err := json.NewDecoder(r).Decode(&ranges); err != nil {
+		return nil, err
+	}
+	if err := ValidateReferenceRanges(ranges); err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+func ValidateReferenceRanges(ranges []models.VitalReferenceRange) error {
+	if len(ranges) == 0 {
+		return fmt.Errorf("no reference ranges")
+	}
+	for i := range ranges {
+		r := &ranges[i]
+		r.ID = 0
+		where := fmt.Sprintf("range %d (%s)", i+1, r.Measure)
+		if !models.ValidMeasure(r.Measure) {
+			return fmt.Errorf("range %d: unknown measure %q", i+1, r.Measure)
+		}
+		if r.Gender != "" && r.Gender != "male" && r.Gender != "female" && r.Gender != "other" {
+			return fmt.Errorf("%s: gender must be male, female, other or empty", where)
+		}
+		if r.MinAge < 0 || r.MaxAge < r.MinAge {
+			return fmt.Errorf("%s: min_age must be >= 0 and <= max_age", where)
+		}
+		if r.Low == nil && r.High == nil && r.CriticalLow == nil && r.CriticalHigh == nil {
+			return fmt.Errorf("%s: at least one bound is required", where)
+		}
+		if r.Low != nil && r.High != nil && *r.Low > *r.High {
+			return fmt.Errorf("%s: low must not exceed high", where)
+		}
+		if r.CriticalLow != nil && r.Low != nil && *r.CriticalLow > *r.Low {
+			return fmt.Errorf("%s: critical_low must not exceed low", where)
+		}
+		if r.CriticalHigh != nil && r.High != nil && *r.CriticalHigh < *r.High {
+			return fmt.Errorf("%s: critical_high must not be below high", where)
+		}
+	}
+	return nil
+}
+
+// FlagVitals fills in Flags on each set of vitals, using the patient's age
+// when the measurements were taken
+func FlagVitals(vitals []models.Vitals, dob models.Date, gender string) error {
+	ranges, err := repository.GetReferenceRanges()
+	if err != nil {
+		return err
+	}
+	for i := range vitals {
+		age := dob.AgeOn(vitals[i].RecordedAt)
+		vitals[i].Flags = []models.VitalFlag{}
+		values := vitals[i].Values()
+		for _, measure := range models.Measures {
+			value, ok := values[measure]
+			if !ok {
+				continue
+			}
+			r := selectRange(ranges, measure, age, gender)
+			if r == nil {
+				continue
+			}
+			if status := r.Classify(value); status != "" {
+				vitals[i].Flags = append(vitals[i].Flags, models.VitalFlag{
+					Measure: measure,
+					Value:   value,
+					Status:  status,
+					Low:     r.Low,
+					High:    r.High,
+					Unit:    r.Unit,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// BuildVitalSeries turns flagged vitals (oldest first) into one series per
+// measure. The band is the one for the patient's age today.
+func BuildVitalSeries(vitals []models.Vitals, measures []string, dob models.Date, gender string) (map[string]MeasureSeries, error) {
+	ranges, err := repository.GetReferenceRanges()
+	if err != nil {
+		return nil, err
+	}
+	age := dob.AgeOn(time.Now())
+
+	series := make(map[string]MeasureSeries, len(measures))
+	for _, measure := range measures {
+		s := MeasureSeries{Measure: measure, Points: []SeriesPoint{}}
+		if r := selectRange(ranges, measure, age, gender); r != nil {
+			s.Unit, s.Low, s.High = r.Unit, r.Low, r.High
+		}
+		for _, v := range vitals {
+			value, ok := v.Values()[measure]
+			if !ok {
+				continue
+			}
+			point := SeriesPoint{RecordedAt: v.RecordedAt, Value: value, VitalsID: v.ID}
+			for _, f := range v.Flags {
+				if f.Measure == measure {
+					point.Status = f.Status
+				}
+			}
+			s.Points = append(s.Points, point)
+		}
+		series[measure] = s
+	}
+	return series, nil
+}
+
+// selectRange picks the most specific range that applies: gender-specific
+// over general, then the narrowest age band
+func selectRange(ranges []models.VitalReferenceRange, measure string, age int, gender string) *models.VitalReferenceRange {
+	var best *models.VitalReferenceRange
+	for i := range ranges {
+		r := &ranges[i]
+		if r.Measure != measure || !r.Applies(age, gender) {
+			continue
+		}
+		specific, bestSpecific := r.Gender != "", best != nil && best.Gender != ""
+		switch {
+		case best == nil, specific && !bestSpecific:
+			best = r
+		case specific == bestSpecific && r.MaxAge-r.MinAge < best.MaxAge-best.MinAge:
+			best = r
+		}
+	}
+	return best
+}